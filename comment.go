@@ -0,0 +1,131 @@
+package sqlParser
+
+import "strings"
+
+// Comment SQL中的注释，Text是包含--、/* */在内的原文
+type Comment struct {
+	Text string
+	Pos  Pos
+}
+
+// Comments 依附在语法树节点上的注释：Leading在节点前面，Trailing在节点后面（同一行）
+type Comments struct {
+	Leading  []Comment
+	Trailing []Comment
+}
+
+// isLine 是否是--行注释，生成SQL时行注释后面必须换行
+func (c Comment) isLine() bool {
+	return strings.HasPrefix(c.Text, "--")
+}
+
+// commentInfo 语法分析器中注释的归属信息
+type commentInfo struct {
+	tok      Token
+	next     int  //注释后面第一个非注释词法单元的下标，注释前面的词法单元是next-1
+	trailing bool //前面同一行有词法单元，它跟在以该词法单元结束的节点后面
+	used     bool
+}
+
+// addComment 记录注释，它前面已经有len(p.tokens)个非注释词法单元
+func (p *parser) addComment(tok Token) {
+	info := commentInfo{tok: tok, next: len(p.tokens)}
+	if prev := len(p.tokens) - 1; prev >= 0 {
+		info.trailing = !strings.Contains(p.src[p.tokens[prev].End:tok.Start], "\n")
+	}
+	p.comments = append(p.comments, info)
+}
+
+// claimComment 将第i个注释归属到节点
+func (p *parser) claimComment(i int) Comment {
+	p.comments[i].used = true
+	p.claimed = append(p.claimed, i)
+	tok := p.comments[i].tok
+	return Comment{Text: tok.Value, Pos: p.position(tok.Start, tok.End)}
+}
+
+// leadingComments 在节点开始解析时调用，取出当前词法单元前面还没有归属的注释
+func (p *parser) leadingComments() (comments []Comment) {
+	for i := range p.comments {
+		if p.comments[i].next > p.pos {
+			break
+		}
+		if !p.comments[i].used {
+			comments = append(comments, p.claimComment(i))
+		}
+	}
+	return comments
+}
+
+// trailingComments 在节点解析完成时调用，取出节点内部还没有归属的注释，以及同一行紧跟在节点后面的注释
+func (p *parser) trailingComments() (comments []Comment) {
+	for i := range p.comments {
+		info := p.comments[i]
+		if info.next > p.pos {
+			break
+		}
+		if !info.used && (info.next < p.pos || info.trailing) {
+			comments = append(comments, p.claimComment(i))
+		}
+	}
+	return comments
+}
+
+// restComments 解析完成后还没有归属的注释
+func (p *parser) restComments() (comments []Comment) {
+	for i := range p.comments {
+		if !p.comments[i].used {
+			comments = append(comments, p.claimComment(i))
+		}
+	}
+	return comments
+}
+
+// marshalComments 将注释放到节点生成的SQL前后，行注释后面换行
+func marshalComments(c Comments, s string) string {
+	var sb strings.Builder
+	for _, item := range c.Leading {
+		sb.WriteString(item.Text)
+		if item.isLine() {
+			sb.WriteString("\n")
+		} else {
+			sb.WriteString(" ")
+		}
+	}
+	sb.WriteString(s)
+	for _, item := range c.Trailing {
+		sb.WriteString(" ")
+		sb.WriteString(item.Text)
+		if item.isLine() {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// stripComments 去掉SQL中的注释，注释和它两边的空白替换成一个空格
+func stripComments(s string) (string, error) {
+	tokens, err := Tokenize(s)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	last := 0
+	for _, tok := range tokens {
+		if tok.Type != TokenComment {
+			continue
+		}
+		sb.WriteString(strings.TrimRight(s[last:tok.Start], " \t\r\n"))
+		last = tok.End
+		for last < len(s) && strings.IndexByte(" \t\r\n", s[last]) != -1 {
+			last++
+		}
+		//括号、逗号两边不需要空格
+		prev := sb.String()
+		if prev != "" && !strings.HasSuffix(prev, "(") && last < len(s) && strings.IndexByte("),", s[last]) == -1 {
+			sb.WriteString(" ")
+		}
+	}
+	sb.WriteString(s[last:])
+	return strings.TrimSpace(sb.String()), nil
+}
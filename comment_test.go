@@ -0,0 +1,79 @@
+package sqlParser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComments(t *testing.T) {
+	tests := []struct {
+		sql    string
+		want   string
+		strip  string
+		params []string
+	}{
+		{
+			"-- header\nSELECT /*+ INDEX(T) */ A, -- first col\n  B /* b */ AS BB\nFROM T_USER T -- users\n-- filter\nWHERE T.ID = :ID /* id :X */ AND T.NAME LIKE '%--x%' -- end\nORDER BY A DESC -- sort",
+			"-- header\nSELECT /*+ INDEX(T) */ A,-- first col\nB /* b */ BB FROM T_USER T -- users\nWHERE -- filter\nT.ID=:ID /* id :X */ AND T.NAME LIKE '%--x%' -- end\nORDER BY A DESC -- sort",
+			"SELECT A, B BB FROM T_USER T WHERE T.ID=:ID AND T.NAME LIKE '%--x%' ORDER BY A DESC",
+			[]string{":ID"},
+		},
+		{
+			"SELECT A FROM T\n-- second\nUNION SELECT B FROM U /* u */",
+			"SELECT A FROM T UNION -- second\nSELECT B FROM U /* u */",
+			"SELECT A FROM T UNION SELECT B FROM U",
+			nil,
+		},
+		{
+			"UPDATE T SET A = 1, -- a\n B = :B WHERE C = 1 -- done",
+			"UPDATE T SET A=1,-- a\nB=:B WHERE C=1 -- done",
+			"UPDATE T SET A=1, B=:B WHERE C=1",
+			[]string{":B"},
+		},
+		{
+			"SELECT NVL(/*x*/A, 0) FROM T WHERE (A = 1 /* one */ OR B = 2)",
+			"SELECT NVL(/*x*/ A,0) FROM T WHERE (A=1 /* one */ OR B=2)",
+			"SELECT NVL(A,0) FROM T WHERE (A=1 OR B=2)",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		got, err := Marshal(stmt)
+		if err != nil {
+			t.Fatalf("Marshal(%q): %v", tt.sql, err)
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+		//生成的SQL可以被再次解析，注释不丢失
+		if again := remarshal(t, got); again != got {
+			t.Errorf("%q: reparsed %q, want %q", tt.sql, again, got)
+		}
+		strip, err := MarshalWithOptions(stmt, MarshalOptions{StripComments: true})
+		if err != nil || strip != tt.strip {
+			t.Errorf("%q: stripped %q, %v, want %q", tt.sql, strip, err, tt.strip)
+		}
+		if got := paramNames(stmt.Params()); !reflect.DeepEqual(got, tt.params) {
+			t.Errorf("%q: Params() = %v, want %v", tt.sql, got, tt.params)
+		}
+	}
+}
+
+func TestCommentsOnNodes(t *testing.T) {
+	stmt, err := Unmarshal("SELECT A, -- first\n B FROM T /* t */")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := stmt.Ast.(Select).Body.(SelectItem)
+	//逗号后面的注释归属到下一个字段
+	if c := item.Field[1].Comments.Leading; len(c) != 1 || c[0].Text != "-- first" || stmt.Text(c[0].Pos) != "-- first" {
+		t.Errorf("field comments = %+v", item.Field[1].Comments)
+	}
+	if c := item.Table[0].Comments.Trailing; len(c) != 1 || c[0].Text != "/* t */" {
+		t.Errorf("table comments = %+v", item.Table[0].Comments)
+	}
+}
@@ -0,0 +1,176 @@
+package sqlParser
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrStatementTooLarge 单条语句超过了Decoder允许的最大长度
+var ErrStatementTooLarge = errors.New("语句超过了允许的最大长度（statement exceeds the maximum allowed size）")
+
+const (
+	decoderReadSize          = 64 << 10 //每次至少读取的字节数
+	defaultMaxStatementSize  = 16 << 20 //默认允许的单条语句最大长度
+	maxConsecutiveEmptyReads = 100      //连续这么多次读不到数据也没有错误时，返回io.ErrNoProgress，和bufio相同
+)
+
+// Decoder 从io.Reader中逐条读取并解析语句，语句的分隔规则和UnmarshalScript相同。
+// 它只缓存还没有解析的数据，内存占用只和单条语句的长度有关，和输入的大小无关
+type Decoder struct {
+	r       io.Reader
+	buf     string //缓存的数据，buf[pos:]是还没有被消费的部分，读取更多的数据时才丢弃已经消费的部分
+	pos     int
+	chunk   []byte //读取数据用的缓冲区
+	atEOF   bool
+	offset  int //buf[pos]在输入中的字节偏移
+	line    int //buf[pos]在输入中的行号
+	column  int //buf[pos]在输入中的列号
+	maxSize int
+	//超长的语句还没有跳过，skipBlock表示它是不是PL/SQL块
+	skipping  bool
+	skipBlock bool
+	err       error //不能继续读取时的错误
+}
+
+// NewDecoder 创建一个从r中读取语句的Decoder
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, line: 1, column: 1, maxSize: defaultMaxStatementSize}
+}
+
+// SetMaxStatementSize 设置单条语句允许的最大字节数，超过时Decode返回ErrStatementTooLarge
+func (dec *Decoder) SetMaxStatementSize(n int) {
+	dec.maxSize = n
+}
+
+// InputOffset 返回已经被消费的数据在输入中的字节偏移
+func (dec *Decoder) InputOffset() int {
+	return dec.offset
+}
+
+// Decode 读取并解析下一条语句，语句的Span是它在输入中的位置。输入结束时返回io.EOF。
+// 某条语句解析失败时，和UnmarshalScript一样返回语法树是ErrorNode的语句和位置是输入中的位置的错误，之后可以继续调用Decode读取下一条语句。
+// 语句超过最大长度时返回ErrStatementTooLarge，下一次Decode会跳过这条语句剩下的部分；
+// 单个字符串或者注释就超过最大长度时无法找到语句的结尾，之后的Decode都返回ErrStatementTooLarge
+func (dec *Decoder) Decode() (Statement, error) {
+	if dec.err != nil {
+		return Statement{}, dec.err
+	}
+	for {
+		data := dec.buf[dec.pos:]
+		if dec.atEOF && len(data) == 0 {
+			return Statement{}, io.EOF
+		}
+		if dec.skipping {
+			advance, found := scanSkip(data, dec.atEOF, dec.skipBlock)
+			dec.consume(data[:advance])
+			if found {
+				dec.skipping = false
+				continue
+			}
+			if err := dec.fill(); err != nil {
+				if err == ErrStatementTooLarge {
+					dec.err = err
+				}
+				return Statement{}, err
+			}
+			continue
+		}
+		start, end, advance, block := scanStatement(data, dec.atEOF)
+		if advance == 0 {
+			if err := dec.fill(); err != nil {
+				if err == ErrStatementTooLarge {
+					dec.skipping, dec.skipBlock = true, block
+				}
+				return Statement{}, err
+			}
+			continue
+		}
+		var span Pos
+		span.Line, span.Column = lineColumn(data[:start], dec.line, dec.column)
+		span.Start, span.End = dec.offset+start, dec.offset+end
+		//先消费掉这条语句，解析出错时也能继续读取下一条
+		dec.consume(data[:advance])
+		if start < end {
+			return unmarshalSpan(data[start:end], span, block)
+		}
+	}
+}
+
+// consume 消费掉buf[pos:]开头的data
+func (dec *Decoder) consume(data string) {
+	dec.line, dec.column = lineColumn(data, dec.line, dec.column)
+	dec.offset += len(data)
+	dec.pos += len(data)
+}
+
+// scanSkip 跳过超长语句剩下的部分，找到语句的结束符时found为true，advance包含结束符；
+// 否则advance只到确定完整的词法单元为止，数据结尾的词法单元可能还没有读完，要留到读取更多的数据后再扫描
+func scanSkip(data string, atEOF bool, block bool) (advance int, found bool) {
+	lex := NewLexer(data)
+	last := 0 //最后一个词法单元的结束位置，advance是它前一个的结束位置
+	for {
+		tok, err := lex.Next()
+		if perr, ok := err.(*ParseError); ok && perr.Code == ErrIllegalCharacter {
+			tok, err = perr.Token, nil
+		}
+		if err != nil || tok.Type == TokenEOF {
+			if atEOF {
+				return len(data), true
+			}
+			if err != nil {
+				//出错的词法单元前面的都是完整的
+				return last, false
+			}
+			//最后一个词法单元后面还有空白时，它一定是完整的
+			if last < len(data) {
+				return len(data), false
+			}
+			return advance, false
+		}
+		if tok.Type == TokenOperator && tok.Value == "/" {
+			slash, more := isSlashLine(data, tok.Start, atEOF)
+			if more {
+				return last, false
+			}
+			if slash {
+				return tok.End, true
+			}
+		}
+		if tok.Type == TokenPunct && tok.Value == ";" && !block {
+			return tok.End, true
+		}
+		advance, last = last, tok.End
+	}
+}
+
+// fill 丢弃已经消费的数据并读取更多的数据，每次读取的数据量随缓存的数据翻倍，避免长语句被反复扫描太多次
+func (dec *Decoder) fill() error {
+	pending := len(dec.buf) - dec.pos
+	if pending >= dec.maxSize {
+		return ErrStatementTooLarge
+	}
+	size := pending
+	if size < decoderReadSize {
+		size = decoderReadSize
+	}
+	if size > dec.maxSize-pending {
+		size = dec.maxSize - pending
+	}
+	if cap(dec.chunk) < size {
+		dec.chunk = make([]byte, size)
+	}
+	n, err := 0, error(nil)
+	for i := 0; n == 0 && err == nil; i++ {
+		if i == maxConsecutiveEmptyReads {
+			return io.ErrNoProgress
+		}
+		n, err = dec.r.Read(dec.chunk[:size])
+	}
+	dec.buf = dec.buf[dec.pos:] + string(dec.chunk[:n])
+	dec.pos = 0
+	if err == io.EOF {
+		dec.atEOF = true
+		return nil
+	}
+	return err
+}
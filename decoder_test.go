@@ -0,0 +1,163 @@
+package sqlParser
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoder(t *testing.T) {
+	input := "select a from t;\n-- 注释\nselect b from s where x = :x;\nselect from;\nupdate t set a = 1\n/\n"
+	want := []struct {
+		sql  string
+		span Pos
+		err  bool
+	}{
+		{"SELECT a FROM t", Pos{Start: 0, End: 15, Line: 1, Column: 1}, false},
+		{"-- 注释\nSELECT b FROM s WHERE x=:x", Pos{Start: 17, End: 55, Line: 2, Column: 1}, false},
+		{"", Pos{}, true},
+		{"UPDATE t SET a=1", Pos{Start: 70, End: 88, Line: 5, Column: 1}, false},
+	}
+	readers := map[string]io.Reader{
+		"full":    strings.NewReader(input),
+		"oneByte": iotest.OneByteReader(strings.NewReader(input)),
+		"half":    iotest.HalfReader(strings.NewReader(input)),
+	}
+	for name, r := range readers {
+		dec := NewDecoder(r)
+		for i, w := range want {
+			stmt, err := dec.Decode()
+			if w.err {
+				if err == nil {
+					t.Errorf("%s: statement %d should fail", name, i)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: statement %d: %v", name, i, err)
+			}
+			got, err := Marshal(stmt)
+			if err != nil {
+				t.Fatalf("%s: Marshal statement %d: %v", name, i, err)
+			}
+			if got != w.sql {
+				t.Errorf("%s: statement %d = %q, want %q", name, i, got, w.sql)
+			}
+			if stmt.Span.Start != w.span.Start || stmt.Span.End != w.span.End || stmt.Span.Line != w.span.Line || stmt.Span.Column != w.span.Column {
+				t.Errorf("%s: statement %d span = %+v, want %+v", name, i, stmt.Span, w.span)
+			}
+		}
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("%s: got %v, want io.EOF", name, err)
+		}
+		if dec.InputOffset() != len(input) {
+			t.Errorf("%s: InputOffset() = %d, want %d", name, dec.InputOffset(), len(input))
+		}
+	}
+}
+
+func TestDecoderErrorPosition(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("select a from t;\nselect a from t where;"))
+	if _, err := dec.Decode(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := dec.Decode()
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("got %v, want *ParseError", err)
+	}
+	if perr.Pos.Line != 2 || perr.Pos.Start < 17 {
+		t.Errorf("error position = %+v, want line 2 after offset 17", perr.Pos)
+	}
+}
+
+func TestDecoderStatementTooLarge(t *testing.T) {
+	long := "select " + strings.Repeat("a,", 100) + "'x;y' /* ; */ from t"
+	tests := []struct {
+		name  string
+		input string
+		want  []string //ErrStatementTooLarge之后读到的语句
+	}{
+		//跳过超长语句后继续读取下一条，字符串、注释里的分号不结束语句
+		{"semicolon", "select a from t;\n" + long + ";\nselect b from u;\nselect c from v", []string{"SELECT b FROM u", "SELECT c FROM v"}},
+		//超长的PL/SQL块跳到单独占一行的/
+		{"block", "select a from t;\nbegin\n" + strings.Repeat("  x := 1;\n", 20) + "end;\n/\nselect b from u;", []string{"SELECT b FROM u"}},
+		//超长语句在输入的结尾
+		{"eof", "select a from t;\n" + long, nil},
+	}
+	for _, tt := range tests {
+		for _, r := range []io.Reader{strings.NewReader(tt.input), iotest.OneByteReader(strings.NewReader(tt.input))} {
+			dec := NewDecoder(r)
+			dec.SetMaxStatementSize(64)
+			if stmt, err := dec.Decode(); err != nil || stmt.Type() != "SELECT" {
+				t.Fatalf("%s: first statement: %v", tt.name, err)
+			}
+			if _, err := dec.Decode(); err != ErrStatementTooLarge {
+				t.Fatalf("%s: got %v, want ErrStatementTooLarge", tt.name, err)
+			}
+			var got []string
+			for {
+				stmt, err := dec.Decode()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("%s: %v", tt.name, err)
+				}
+				sql, _ := Marshal(stmt)
+				got = append(got, sql)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+			}
+			if dec.InputOffset() != len(tt.input) {
+				t.Errorf("%s: InputOffset() = %d, want %d", tt.name, dec.InputOffset(), len(tt.input))
+			}
+		}
+	}
+	//单个字符串超过最大长度时找不到语句的结尾，之后一直返回ErrStatementTooLarge
+	dec := NewDecoder(strings.NewReader("select '" + strings.Repeat("x", 200) + "' from t;\nselect b from u;"))
+	dec.SetMaxStatementSize(64)
+	for i := 0; i < 3; i++ {
+		if _, err := dec.Decode(); err != ErrStatementTooLarge {
+			t.Errorf("call %d: got %v, want ErrStatementTooLarge", i, err)
+		}
+	}
+	if !strings.Contains(ErrStatementTooLarge.Error(), "statement exceeds the maximum allowed size") {
+		t.Errorf("ErrStatementTooLarge = %q, want an English message", ErrStatementTooLarge)
+	}
+}
+
+// emptyReader 一直返回(0, nil)
+type emptyReader struct{}
+
+func (emptyReader) Read([]byte) (int, error) {
+	return 0, nil
+}
+
+func TestDecoderNoProgress(t *testing.T) {
+	dec := NewDecoder(io.MultiReader(strings.NewReader("select a from t;"), emptyReader{}))
+	if _, err := dec.Decode(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dec.Decode(); err != io.ErrNoProgress {
+		t.Errorf("got %v, want io.ErrNoProgress", err)
+	}
+}
+
+func TestDecoderReadError(t *testing.T) {
+	//读取出错时，已经读到的完整语句照常返回，之后一直返回读取的错误
+	readErr := errors.New("read failed")
+	dec := NewDecoder(io.MultiReader(strings.NewReader("select a from t;\nselect b fr"), iotest.ErrReader(readErr)))
+	if _, err := dec.Decode(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := dec.Decode(); err != readErr {
+			t.Errorf("got %v, want %v", err, readErr)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package sqlParser
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrorCode 解析错误的错误码，它的值不会随版本变化，可以用来判断错误的种类
+type ErrorCode string
+
+const (
+	ErrUnterminatedString  ErrorCode = "UNTERMINATED_STRING"   //字符串缺失结束的单引号
+	ErrUnterminatedIdent   ErrorCode = "UNTERMINATED_IDENT"    //标识符缺失结束的引号
+	ErrUnterminatedComment ErrorCode = "UNTERMINATED_COMMENT"  //块注释缺失结束符
+	ErrIllegalCharacter    ErrorCode = "ILLEGAL_CHARACTER"     //无法识别的字符
+	ErrUnexpectedEOF       ErrorCode = "UNEXPECTED_EOF"        //SQL不完整
+	ErrUnexpectedToken     ErrorCode = "UNEXPECTED_TOKEN"      //不能解析的词法单元
+	ErrMissingKeyword      ErrorCode = "MISSING_KEYWORD"       //缺失关键词
+	ErrMissingRightParen   ErrorCode = "MISSING_RIGHT_PAREN"   //缺失右括号
+	ErrMissingLeftParen    ErrorCode = "MISSING_LEFT_PAREN"    //缺失左括号
+	ErrMissingPunct        ErrorCode = "MISSING_PUNCT"         //缺失标点
+	ErrMissingName         ErrorCode = "MISSING_NAME"          //缺失名称
+	ErrMissingAlias        ErrorCode = "MISSING_ALIAS"         //AS后面缺失别名
+	ErrMissingTable        ErrorCode = "MISSING_TABLE"         //缺失表名
+	ErrInvalidTable        ErrorCode = "INVALID_TABLE"         //不正确的表
+	ErrInvalidOrder        ErrorCode = "INVALID_ORDER"         //不能识别的排序规则
+	ErrInvalidValue        ErrorCode = "INVALID_VALUE"         //不正确的值
+	ErrInvalidComparison   ErrorCode = "INVALID_COMPARISON"    //比较式缺少左值或右值
+	ErrCaseWithoutWhen     ErrorCode = "CASE_WITHOUT_WHEN"     //CASE表达式没有WHEN项
+	ErrInvalidInsertTarget ErrorCode = "INVALID_INSERT_TARGET" //被插入的表和字段不正确
+	ErrInvalidSetItem      ErrorCode = "INVALID_SET_ITEM"      //UPDATE设置值不是等式
+	ErrUnsupportedStmt     ErrorCode = "UNSUPPORTED_STATEMENT" //不支持的SQL类型
+)
+
+// Locale 错误信息使用的语言
+type Locale int
+
+const (
+	LocaleZh Locale = iota //中文，默认
+	LocaleEn               //英文
+)
+
+// errorMessages 错误码对应的中英文信息
+var errorMessages = map[ErrorCode][2]string{
+	ErrUnterminatedString:  {"字符串缺失结束的单引号", "unterminated string literal"},
+	ErrUnterminatedIdent:   {"标识符缺失结束的引号", "unterminated quoted identifier"},
+	ErrUnterminatedComment: {"块注释缺失结束符*/", "unterminated block comment"},
+	ErrIllegalCharacter:    {"无法识别的字符", "illegal character"},
+	ErrUnexpectedEOF:       {"SQL不完整", "unexpected end of SQL"},
+	ErrUnexpectedToken:     {"SQL存在不能解析的元素", "unexpected token"},
+	ErrMissingKeyword:      {"缺失关键词", "missing keyword"},
+	ErrMissingRightParen:   {"缺失右括号", "missing right parenthesis"},
+	ErrMissingLeftParen:    {"缺失左括号", "missing left parenthesis"},
+	ErrMissingPunct:        {"缺失标点", "missing punctuation"},
+	ErrMissingName:         {"缺失名称", "missing name"},
+	ErrMissingAlias:        {"AS后面缺失别名", "missing alias after AS"},
+	ErrMissingTable:        {"缺失表名", "missing table name"},
+	ErrInvalidTable:        {"不正确的表", "invalid table reference"},
+	ErrInvalidOrder:        {"未能识别的排序规则", "unrecognized ORDER clause"},
+	ErrInvalidValue:        {"SQL值可能有误", "invalid value"},
+	ErrInvalidComparison:   {"常规比较式需要有左值和右值", "comparison requires a left and a right operand"},
+	ErrCaseWithoutWhen:     {"CASE WHEN表达式需要有WHEN项", "CASE expression requires at least one WHEN"},
+	ErrInvalidInsertTarget: {"被插入的表和字段不正确", "invalid INSERT target table or columns"},
+	ErrInvalidSetItem:      {"UPDATE设置值必须是等式", "UPDATE SET item must be an assignment"},
+	ErrUnsupportedStmt:     {"未能适配的SQL类型", "unsupported statement type"},
+}
+
+// ParseError 解析错误，可以通过errors.As取出，例：
+//
+//	var perr *ParseError
+//	if errors.As(err, &perr) { fmt.Println(perr.Code, perr.Pos.Line, perr.Snippet) }
+type ParseError struct {
+	Code     ErrorCode
+	Pos      Pos      //出错的位置
+	Token    Token    //出错的词法单元，SQL结束时它的类型是TokenEOF
+	Expected []string //期望出现的词法单元，例：")"、"FROM"
+	Snippet  string   //出错行的原文，下一行用^标出出错的位置
+	Locale   Locale   //Error()使用的语言，由ParseOptions.Locale设置
+}
+
+// newParseError 创建解析错误，tok是出错的词法单元，src是原SQL
+func newParseError(src string, code ErrorCode, tok Token, expected ...string) *ParseError {
+	e := &ParseError{Code: code, Token: tok, Expected: expected}
+	lineStart := strings.LastIndexByte(src[:tok.Start], '\n') + 1
+	lineEnd := strings.IndexByte(src[tok.Start:], '\n')
+	if lineEnd == -1 {
+		lineEnd = len(src)
+	} else {
+		lineEnd += tok.Start
+	}
+	e.Pos = Pos{
+		Start:  tok.Start,
+		End:    tok.End,
+		Line:   strings.Count(src[:tok.Start], "\n") + 1,
+		Column: utf8.RuneCountInString(src[lineStart:tok.Start]) + 1,
+	}
+	//^的前面保留原行的制表符，使其在终端中对齐
+	line := strings.TrimRight(src[lineStart:lineEnd], "\r")
+	var caret strings.Builder
+	for _, r := range src[lineStart:tok.Start] {
+		if r == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	end := tok.End
+	if end > lineEnd {
+		end = lineEnd
+	}
+	width := utf8.RuneCountInString(src[tok.Start:end])
+	if width < 1 {
+		width = 1
+	}
+	caret.WriteString(strings.Repeat("^", width))
+	e.Snippet = line + "\n" + caret.String()
+	return e
+}
+
+// Error 按Locale返回错误信息
+func (e *ParseError) Error() string {
+	return e.Message(e.Locale)
+}
+
+// Message 按指定的语言返回错误信息，包含行列号、期望的词法单元以及实际遇到的词法单元
+func (e *ParseError) Message(locale Locale) string {
+	if locale != LocaleEn {
+		locale = LocaleZh
+	}
+	msg := errorMessages[e.Code][locale]
+	found := strconv.Quote(e.Token.Value)
+	if e.Token.Type == TokenEOF {
+		found = [2]string{"SQL结尾", "end of input"}[locale]
+	}
+	expected := make([]string, len(e.Expected))
+	for i, item := range e.Expected {
+		expected[i] = strconv.Quote(item)
+	}
+	if locale == LocaleEn {
+		msg = "line " + strconv.Itoa(e.Pos.Line) + ", column " + strconv.Itoa(e.Pos.Column) + ": " + msg
+		if len(expected) != 0 {
+			msg += ", expected " + strings.Join(expected, " or ")
+		}
+		return msg + ", found " + found
+	}
+	msg = "第" + strconv.Itoa(e.Pos.Line) + "行第" + strconv.Itoa(e.Pos.Column) + "列：" + msg
+	if len(expected) != 0 {
+		msg += "，期望" + strings.Join(expected, "或")
+	}
+	return msg + "，实际为" + found
+}
@@ -0,0 +1,83 @@
+package sqlParser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		sql      string
+		code     ErrorCode
+		line     int
+		column   int
+		expected []string
+		snippet  string
+	}{
+		{"SELECT A FROM T WHERE (A = 1", ErrMissingRightParen, 1, 29, []string{")"}, "SELECT A FROM T WHERE (A = 1\n                            ^"},
+		{"SELECT A,\n\tB FROM T WHERE A = 'x", ErrUnterminatedString, 2, 21, nil, "\tB FROM T WHERE A = 'x\n\t                   ^^"},
+		{"SELECT A FROM", ErrInvalidTable, 1, 14, nil, "SELECT A FROM\n             ^"},
+		{"SELECT A B C FROM T", ErrMissingKeyword, 1, 12, []string{"FROM"}, "SELECT A B C FROM T\n           ^"},
+		{"SELECT 中文 FROM T WHERE A ? 1", ErrIllegalCharacter, 1, 26, nil, "SELECT 中文 FROM T WHERE A ? 1\n                         ^"},
+		{"UPDATE T SET A 1", ErrInvalidSetItem, 1, 16, []string{"="}, "UPDATE T SET A 1\n               ^"},
+		{"FOO", ErrUnsupportedStmt, 1, 1, []string{"SELECT", "INSERT", "UPDATE", "DELETE"}, "FOO\n^^^"},
+		{"SELECT A FROM T ORDER X", ErrInvalidOrder, 1, 23, []string{"BY", "SIBLINGS", "DECODE"}, "SELECT A FROM T ORDER X\n                      ^"},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("Unmarshal(%q) = %v, want *ParseError", tt.sql, err)
+		}
+		if perr.Code != tt.code || perr.Pos.Line != tt.line || perr.Pos.Column != tt.column {
+			t.Errorf("%q: %s at %d:%d, want %s at %d:%d", tt.sql, perr.Code, perr.Pos.Line, perr.Pos.Column, tt.code, tt.line, tt.column)
+		}
+		if !reflect.DeepEqual(perr.Expected, tt.expected) && len(perr.Expected)+len(tt.expected) != 0 {
+			t.Errorf("%q: Expected = %q, want %q", tt.sql, perr.Expected, tt.expected)
+		}
+		if perr.Snippet != tt.snippet {
+			t.Errorf("%q: Snippet = %q, want %q", tt.sql, perr.Snippet, tt.snippet)
+		}
+	}
+}
+
+func TestParseErrorMessage(t *testing.T) {
+	_, err := Unmarshal("SELECT A B C FROM T")
+	perr := err.(*ParseError)
+	tests := []struct {
+		locale Locale
+		want   string
+	}{
+		{LocaleZh, `第1行第12列：缺失关键词，期望"FROM"，实际为"C"`},
+		{LocaleEn, `line 1, column 12: missing keyword, expected "FROM", found "C"`},
+	}
+	for _, tt := range tests {
+		if got := perr.Message(tt.locale); got != tt.want {
+			t.Errorf("Message(%d) = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+	if got := perr.Error(); got != tests[0].want {
+		t.Errorf("Error() = %q, want %q", got, tests[0].want)
+	}
+	_, err = UnmarshalWithOptions("SELECT A B C FROM T", ParseOptions{Locale: LocaleEn})
+	if got := err.Error(); got != tests[1].want {
+		t.Errorf("Error() = %q, want %q", got, tests[1].want)
+	}
+	//词法错误也使用ParseOptions.Locale
+	_, err = UnmarshalWithOptions("SELECT 'A FROM T", ParseOptions{Locale: LocaleEn})
+	if perr, ok := err.(*ParseError); !ok || err.Error() != perr.Message(LocaleEn) {
+		t.Errorf("lexer Error() = %v", err)
+	}
+	//恢复模式下的每个错误都使用ParseOptions.Locale
+	stmt, _ := UnmarshalWithOptions("SELECT A B C FROM T WHERE", ParseOptions{Recover: true, Locale: LocaleEn})
+	for _, d := range stmt.Diagnostics {
+		if d.Error() != d.Message(LocaleEn) {
+			t.Errorf("diagnostic Error() = %q", d.Error())
+		}
+	}
+	_, err = Unmarshal("SELECT A FROM")
+	if got := err.(*ParseError).Message(LocaleEn); got != "line 1, column 14: invalid table reference, found end of input" {
+		t.Errorf("EOF message = %q", got)
+	}
+}
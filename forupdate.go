@@ -0,0 +1,130 @@
+package sqlParser
+
+import (
+	"errors"
+	"strings"
+)
+
+// ForUpdate 加锁读，它是单查询的最后一个子句，例：FOR UPDATE OF T.ID NOWAIT、FOR UPDATE SKIP LOCKED、FOR SHARE、LOCK IN SHARE MODE。
+// Mode为空时没有该子句
+type ForUpdate struct {
+	Mode    string  //UPDATE、NO KEY UPDATE、SHARE、KEY SHARE，MySQL的LOCK IN SHARE MODE
+	Of      []Value //OF后面的字段或者表，没有时为空
+	Wait    string  //NOWAIT、WAIT、SKIP LOCKED，没有写明时为空
+	Timeout Value   //WAIT后面的秒数
+	Pos     Pos
+}
+
+// forUpdateModes FOR后面的加锁方式
+var forUpdateModes = [][]string{{"UPDATE"}, {"NO", "KEY", "UPDATE"}, {"SHARE"}, {"KEY", "SHARE"}}
+
+// isForUpdateStart 当前词法单元是否是加锁读子句的开始
+func (p *parser) isForUpdateStart() bool {
+	if p.isKeyword("LOCK", "IN", "SHARE", "MODE") {
+		return true
+	}
+	for _, mode := range forUpdateModes {
+		if p.isKeywordAt(1, mode...) {
+			return p.isKeyword("FOR")
+		}
+	}
+	return false
+}
+
+// getForUpdate 解析加锁读子句，调用前需用isForUpdateStart判断
+func getForUpdate(p *parser) (lock ForUpdate, err error) {
+	start := p.peek().Start
+	if p.acceptKeyword("LOCK", "IN", "SHARE", "MODE") {
+		lock.Mode = "LOCK IN SHARE MODE"
+		lock.Pos = p.posFrom(start)
+		return lock, nil
+	}
+	p.keyword()
+	for _, mode := range forUpdateModes {
+		if p.acceptKeyword(mode...) {
+			lock.Mode = strings.Join(mode, " ")
+			break
+		}
+	}
+	if p.acceptKeyword("OF") {
+		for {
+			val, err := getValue(p)
+			if err != nil {
+				return ForUpdate{}, err
+			}
+			lock.Of = append(lock.Of, val)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+	}
+	switch {
+	case p.acceptKeyword("NOWAIT"):
+		lock.Wait = "NOWAIT"
+	case p.acceptKeyword("SKIP"):
+		if err = p.expectKeyword("LOCKED"); err != nil {
+			return ForUpdate{}, err
+		}
+		lock.Wait = "SKIP LOCKED"
+	case p.acceptKeyword("WAIT"):
+		lock.Wait = "WAIT"
+		if lock.Timeout, err = getValue(p); err != nil {
+			return ForUpdate{}, err
+		}
+	}
+	lock.Pos = p.posFrom(start)
+	return lock, nil
+}
+
+// marshalForUpdate 序列化加锁读子句
+func marshalForUpdate(m *marshaler, lock ForUpdate) (retSQL string, err error) {
+	switch lock.Mode {
+	case "LOCK IN SHARE MODE":
+		return m.kw(lock.Mode), nil
+	case "UPDATE", "NO KEY UPDATE", "SHARE", "KEY SHARE":
+		retSQL = m.kw("FOR " + lock.Mode)
+	default:
+		return "", errors.New("不能识别的加锁方式" + lock.Mode)
+	}
+	if len(lock.Of) != 0 {
+		var of []string
+		for _, item := range lock.Of {
+			val, err := marshalValue(m, item, true)
+			if err != nil {
+				return "", err
+			}
+			of = append(of, val)
+		}
+		retSQL += " " + m.kw("OF") + " " + strings.Join(of, ",")
+	}
+	switch lock.Wait {
+	case "":
+	case "NOWAIT", "SKIP LOCKED":
+		retSQL += " " + m.kw(lock.Wait)
+	case "WAIT":
+		if lock.Timeout.Value == nil {
+			return "", errors.New("WAIT缺失秒数")
+		}
+		timeout, err := marshalValue(m, lock.Timeout, true)
+		if err != nil {
+			return "", err
+		}
+		retSQL += " " + m.kw("WAIT") + " " + timeout
+	default:
+		return "", errors.New("不能识别的等待方式" + lock.Wait)
+	}
+	return retSQL, nil
+}
+
+func getParamsByForUpdate(lock ForUpdate) (pars []Params) {
+	return getParamsBySelectValue(lock.Timeout)
+}
+
+// deleteParamsByForUpdate WAIT的秒数中有参数被删除时不再指定等待方式
+func deleteParamsByForUpdate(lock ForUpdate, pars []Params) ForUpdate {
+	if lock.Timeout.Value != nil && deleteParamsBySelectValue(lock.Timeout, pars).Value == nil {
+		lock.Wait = ""
+		lock.Timeout = Value{}
+	}
+	return lock
+}
@@ -0,0 +1,103 @@
+package sqlParser
+
+import "testing"
+
+func TestForUpdateModes(t *testing.T) {
+	tests := []struct {
+		sql  string
+		mode string
+		wait string
+		of   int
+		want string
+	}{
+		{"select id from jobs where state = 0 for update skip locked", "UPDATE", "SKIP LOCKED", 0, "SELECT id FROM jobs WHERE state=0 FOR UPDATE SKIP LOCKED"},
+		{"select id from jobs j for update of j.id, j.state nowait", "UPDATE", "NOWAIT", 2, "SELECT id FROM jobs j FOR UPDATE OF j.id,j.state NOWAIT"},
+		{"select id from jobs for update of jobs", "UPDATE", "", 1, "SELECT id FROM jobs FOR UPDATE OF jobs"},
+		{"select id from jobs for update wait 5", "UPDATE", "WAIT", 0, "SELECT id FROM jobs FOR UPDATE WAIT 5"},
+		{"select id from jobs for no key update", "NO KEY UPDATE", "", 0, "SELECT id FROM jobs FOR NO KEY UPDATE"},
+		{"select id from jobs for key share", "KEY SHARE", "", 0, "SELECT id FROM jobs FOR KEY SHARE"},
+		{"select id from jobs for share", "SHARE", "", 0, "SELECT id FROM jobs FOR SHARE"},
+		{"select id from jobs where a = 1 lock in share mode", "LOCK IN SHARE MODE", "", 0, "SELECT id FROM jobs WHERE a=1 LOCK IN SHARE MODE"},
+		//加锁读在限制行数的后面
+		{"select id from jobs order by id fetch first 10 rows only for update", "UPDATE", "", 0, "SELECT id FROM jobs ORDER BY id FETCH FIRST 10 ROWS ONLY FOR UPDATE"},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		lock := stmt.Ast.(Select).Body.(SelectItem).ForUpdate
+		if lock.Mode != tt.mode || lock.Wait != tt.wait || len(lock.Of) != tt.of || (lock.Timeout.Value != nil) != (tt.wait == "WAIT") {
+			t.Errorf("%q: got %+v", tt.sql, lock)
+		}
+		if got, err := Marshal(stmt); err != nil || got != tt.want {
+			t.Errorf("%q: got %q, %v, want %q", tt.sql, got, err, tt.want)
+		}
+	}
+}
+
+func TestForUpdatePlacement(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"select id from (select id from jobs for update) x", "select id from (select id from jobs for update) x"},
+		{"select id from jobs For Update Of id Skip Locked", "select id from jobs For Update Of id Skip Locked"},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: KeywordAsWritten}); err != nil || got != tt.want {
+			t.Errorf("%q: got %q, %v, want %q", tt.sql, got, err, tt.want)
+		}
+	}
+	testParams(t, []paramsCase{
+		//WAIT的秒数被删除时不再指定等待方式
+		{
+			"select id from jobs where a in (:q) for update wait :p",
+			[]string{":q", ":p"},
+			":p", "SELECT id FROM jobs WHERE a IN(:q) FOR UPDATE",
+			":q", "SELECT id FROM jobs WHERE a IN(:q0,:q1,:q2) FOR UPDATE WAIT :p",
+		},
+	})
+}
+
+func TestForUpdateSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select id from jobs for update of", ErrUnexpectedEOF, 34},
+		{"select id from jobs for update wait", ErrUnexpectedEOF, 36},
+		{"select id from jobs for update skip", ErrMissingKeyword, 36},
+		{"select id from jobs for update nowait x", ErrUnexpectedToken, 39},
+		{"select id from jobs lock in share mode nowait", ErrUnexpectedToken, 40},
+		//FOR后面不是加锁方式时不是加锁读
+		{"select for from jobs", ErrUnexpectedToken, 8},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+}
+
+func TestMarshalForUpdateErrors(t *testing.T) {
+	tests := map[string]ForUpdate{
+		"unknown mode":       {Mode: "EXCLUSIVE"},
+		"wait without value": {Mode: "UPDATE", Wait: "WAIT"},
+		"unknown wait":       {Mode: "UPDATE", Wait: "LATER"},
+		"empty of column":    {Mode: "UPDATE", Of: []Value{{Value: ColumnRef{}}}},
+	}
+	for name, lock := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalForUpdate(&marshaler{}, lock); err == nil {
+				t.Errorf("marshalForUpdate = %q, should fail", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package sqlParser
+
+import (
+	"errors"
+	"strings"
+)
+
+// GroupingElement 分组的扩展元素，它是Value，只出现在GROUP BY中，例：ROLLUP(DEPT, JOB)、CUBE(A, (B, C))、GROUPING SETS ((A, B), (A), ())。
+// Kind为空时是被括号括起的字段组合，例：(A, B)，没有字段时是空的分组()。查询字段中的GROUPING(A)、GROUPING_ID(A, B)是普通的Function
+type GroupingElement struct {
+	Kind  string  //ROLLUP、CUBE、GROUPING SETS，字段组合时为空
+	Items []Value //ROLLUP、CUBE里可以是字段组合，GROUPING SETS里还可以是ROLLUP、CUBE
+	Pos   Pos
+}
+
+// getGroupingElement 解析GROUP BY中的单个分组元素，不是扩展元素时返回普通的值
+func getGroupingElement(p *parser, nested bool) (value Value, err error) {
+	start := p.peek().Start
+	var element GroupingElement
+	switch {
+	case p.isAnyKeyword("ROLLUP", "CUBE") && p.peekN(1).Type == TokenPunct && p.peekN(1).Value == "(":
+		element.Kind = strings.ToUpper(p.keyword().Value)
+		if element.Items, err = getGroupingItems(p, false); err != nil {
+			return Value{}, err
+		}
+	case p.isKeyword("GROUPING", "SETS") && !nested:
+		p.keyword()
+		p.keyword()
+		element.Kind = "GROUPING SETS"
+		if element.Items, err = getGroupingItems(p, true); err != nil {
+			return Value{}, err
+		}
+	case p.isPunct("(") && !p.isSelectStart(0):
+		//字段组合：(A, B)、()，只有一个值时是被括号括起的表达式
+		state := p.save()
+		p.next()
+		for !p.isPunct(")") {
+			val, err := getValue(p)
+			if err != nil {
+				return Value{}, err
+			}
+			element.Items = append(element.Items, val)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+		if err = p.expectPunct(")"); err != nil {
+			return Value{}, err
+		}
+		if len(element.Items) == 1 {
+			p.restore(state)
+			return getValue(p)
+		}
+	default:
+		return getValue(p)
+	}
+	element.Pos = p.posFrom(start)
+	return Value{Value: element, Pos: element.Pos}, nil
+}
+
+// getGroupingItems 解析ROLLUP、CUBE、GROUPING SETS后面括号里的分组元素，sets为true时可以嵌套ROLLUP、CUBE
+func getGroupingItems(p *parser, sets bool) (items []Value, err error) {
+	if err = p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	for {
+		var val Value
+		if sets || p.isPunct("(") {
+			val, err = getGroupingElement(p, true)
+		} else {
+			val, err = getValue(p)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, val)
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// marshalGroupingElement 序列化分组的扩展元素
+func marshalGroupingElement(m *marshaler, element GroupingElement) (retSQL string, err error) {
+	switch element.Kind {
+	case "", "ROLLUP", "CUBE", "GROUPING SETS":
+	default:
+		return "", errors.New("不能识别的分组类型" + element.Kind)
+	}
+	if element.Kind != "" && len(element.Items) == 0 {
+		return "", errors.New(element.Kind + "不能为空")
+	}
+	for _, item := range element.Items {
+		val, err := marshalValue(m, item, true)
+		if err != nil {
+			return "", err
+		}
+		retSQL += val + ","
+	}
+	return m.kw(element.Kind) + "(" + strings.TrimRight(retSQL, ",") + ")", nil
+}
+
+func getParamsByGroupingElement(element GroupingElement) (pars []Params) {
+	for _, item := range element.Items {
+		pars = append(pars, getParamsBySelectValue(item)...)
+	}
+	return pars
+}
+
+// deleteParamsByGroupingElement 和分组一样，有参数被删除时只删除该项，全部删除时删除整个元素
+func deleteParamsByGroupingElement(element GroupingElement, pars []Params) interface{} {
+	if len(element.Items) == 0 {
+		return element
+	}
+	var items []Value
+	for _, item := range element.Items {
+		item = deleteParamsBySelectValue(item, pars)
+		if item.Value != nil {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	element.Items = items
+	return element
+}
+
+func expandParamsByGroupingElement(element GroupingElement, params Params, count int) GroupingElement {
+	items := make([]Value, len(element.Items))
+	for i, item := range element.Items {
+		items[i] = item
+		if val := expandParamsBySelectValue(item, params, count); val.Value != nil {
+			items[i] = val
+		}
+	}
+	element.Items = items
+	return element
+}
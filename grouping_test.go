@@ -0,0 +1,124 @@
+package sqlParser
+
+import "testing"
+
+func TestGroupingElements(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"select dept, job, grouping(dept), grouping_id(dept, job), sum(sal) from emp group by rollup(dept, job)", "SELECT dept,job,grouping(dept),grouping_id(dept,job),sum(sal) FROM emp GROUP BY ROLLUP(dept,job)"},
+		{"select a from t group by cube(a, (b, c)), d", "SELECT a FROM t GROUP BY CUBE(a,(b,c)),d"},
+		{"select a from t group by grouping sets ((a, b), (a), (), rollup(a, b), c)", "SELECT a FROM t GROUP BY GROUPING SETS((a,b),(a),(),ROLLUP(a,b),c)"},
+		{"select a from t group by grouping sets (rollup(a), cube(b))", "SELECT a FROM t GROUP BY GROUPING SETS(ROLLUP(a),CUBE(b))"},
+		//只有一个值的括号是表达式，不是字段组合
+		{"select a from t group by (a), (a + 1)", "SELECT a FROM t GROUP BY (a),(a+1)"},
+		{"select a from t group by (a, b)", "SELECT a FROM t GROUP BY (a,b)"},
+		//后面没有括号的ROLLUP是普通的名字
+		{"select rollup from t group by rollup", "SELECT rollup FROM t GROUP BY rollup"},
+	}
+	for _, tt := range tests {
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+	stmt, err := Unmarshal(tests[2].sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets, ok := stmt.Ast.(Select).Body.(SelectItem).Group[0].Value.(GroupingElement)
+	if !ok || sets.Kind != "GROUPING SETS" || len(sets.Items) != 5 {
+		t.Fatalf("got %#v", stmt.Ast.(Select).Body.(SelectItem).Group[0].Value)
+	}
+	if empty, ok := sets.Items[2].Value.(GroupingElement); !ok || empty.Kind != "" || len(empty.Items) != 0 {
+		t.Errorf("() = %#v, want an empty GroupingElement", sets.Items[2].Value)
+	}
+	if _, ok := sets.Items[1].Value.(ParenExpr); !ok {
+		t.Errorf("(a) = %#v, want ParenExpr", sets.Items[1].Value)
+	}
+	if rollup, ok := sets.Items[3].Value.(GroupingElement); !ok || rollup.Kind != "ROLLUP" {
+		t.Errorf("rollup = %#v", sets.Items[3].Value)
+	}
+}
+
+func TestGroupingKeywordCase(t *testing.T) {
+	stmt, err := Unmarshal("select a from t Group By Rollup(a), Grouping Sets((), a)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[KeywordCase]string{
+		KeywordUpper:     "SELECT a FROM t GROUP BY ROLLUP(a),GROUPING SETS((),a)",
+		KeywordLower:     "select a from t group by rollup(a),grouping sets((),a)",
+		KeywordAsWritten: "select a from t Group By Rollup(a),Grouping Sets((),a)",
+	}
+	for kc, want := range tests {
+		if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: kc}); err != nil || got != want {
+			t.Errorf("case %v: got %q, %v, want %q", kc, got, err, want)
+		}
+	}
+}
+
+func TestGroupingDeleteParams(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		//删除部分参数时只删除该项
+		{"select a from t group by rollup(a, :p), b having count(*) > :p", "SELECT a FROM t GROUP BY ROLLUP(a),b"},
+		{"select a from t group by grouping sets ((a, :p), (:p), b)", "SELECT a FROM t GROUP BY GROUPING SETS((a),b)"},
+		//全部删除时删除整个元素，分组为空时去掉GROUP BY
+		{"select a from t group by rollup(:p)", "SELECT a FROM t"},
+		{"select a from t group by cube((:p, :p)), a", "SELECT a FROM t GROUP BY a"},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		stmt.DeleteParams([]Params{{Name: ":p"}})
+		if got, err := Marshal(stmt); err != nil || got != tt.want {
+			t.Errorf("%q: got %q, %v, want %q", tt.sql, got, err, tt.want)
+		}
+	}
+	testParams(t, []paramsCase{{
+		"select a from t group by rollup(a, (select max(b) from u where c in (:p)))",
+		[]string{":p"},
+		"", "",
+		":p", "SELECT a FROM t GROUP BY ROLLUP(a,(SELECT max(b) FROM u WHERE c IN(:p0,:p1,:p2)))",
+	}})
+}
+
+func TestGroupingSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select a from t group by rollup()", ErrUnexpectedToken, 33},
+		{"select a from t group by cube(a, )", ErrUnexpectedToken, 34},
+		{"select a from t group by grouping sets (a", ErrMissingRightParen, 42},
+		{"select a from t group by grouping a", ErrUnexpectedToken, 35},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+}
+
+func TestMarshalGroupingElementErrors(t *testing.T) {
+	col := Value{Value: ColumnRef{Column: "a"}}
+	tests := map[string]GroupingElement{
+		"unknown kind": {Kind: "GROUPING", Items: []Value{col}},
+		"empty rollup": {Kind: "ROLLUP"},
+		"bad item":     {Kind: "CUBE", Items: []Value{{Value: ColumnRef{}}}},
+	}
+	for name, element := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalGroupingElement(&marshaler{}, element); err == nil {
+				t.Errorf("marshalGroupingElement = %q, should fail", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package sqlParser
+
+import (
+	"errors"
+	"strings"
+)
+
+// Join 表连接，例：A LEFT JOIN B ON A.ID = B.ID。多个连接从左向右组成树，A JOIN B JOIN C是(A JOIN B) JOIN C，
+// 连接的结果是SelectTable{Table: Join}，被括号括起的连接可以出现在右边，例：A JOIN (B JOIN C ON ...) ON ...
+type Join struct {
+	Left    SelectTable
+	Kind    string //连接类型：INNER、LEFT、RIGHT、FULL、CROSS，以及CROSS APPLY、OUTER APPLY
+	Natural bool   //NATURAL JOIN，没有ON、USING
+	Right   SelectTable
+	On      EquationList //ON条件，没有时Equation为空
+	Using   []string     //USING的字段
+	Pos     Pos
+}
+
+// joinKinds 可以放在JOIN前面的连接类型
+var joinKinds = []string{"INNER", "LEFT", "RIGHT", "FULL"}
+
+// isJoinStart 当前词法单元是否是连接关键词的开始。除了JOIN，其他关键词都要看到JOIN、APPLY才能确定，它们不能被当作别名
+func (p *parser) isJoinStart() bool {
+	n := 0
+	if p.isKeyword("NATURAL") {
+		n = 1
+	}
+	if p.isKeywordAt(n, "JOIN") || p.isKeywordAt(n, "CROSS", "JOIN") {
+		return true
+	}
+	for _, kind := range joinKinds {
+		if p.isKeywordAt(n, kind, "JOIN") || (kind != "INNER" && p.isKeywordAt(n, kind, "OUTER", "JOIN")) {
+			return true
+		}
+	}
+	return n == 0 && (p.isKeyword("CROSS", "APPLY") || p.isKeyword("OUTER", "APPLY"))
+}
+
+// acceptJoin 解析连接关键词，返回连接类型，不是连接关键词时返回空串
+func acceptJoin(p *parser) (kind string, natural bool) {
+	if !p.isJoinStart() {
+		return "", false
+	}
+	natural = p.acceptKeyword("NATURAL")
+	switch {
+	case p.acceptKeyword("CROSS", "APPLY"):
+		return "CROSS APPLY", false
+	case p.acceptKeyword("OUTER", "APPLY"):
+		return "OUTER APPLY", false
+	case p.acceptKeyword("CROSS", "JOIN"):
+		return "CROSS", natural
+	}
+	kind = "INNER"
+	for _, item := range joinKinds {
+		if p.acceptKeyword(item) {
+			kind = item
+			break
+		}
+	}
+	if kind != "INNER" {
+		p.acceptKeyword("OUTER")
+	}
+	p.acceptKeyword("JOIN")
+	return kind, natural
+}
+
+// getJoinedTable 解析表以及连接在它后面的表
+func getJoinedTable(p *parser) (table SelectTable, err error) {
+	start := p.peek().Start
+	if table, err = getTable(p); err != nil {
+		return SelectTable{}, err
+	}
+	for {
+		kind, natural := acceptJoin(p)
+		if kind == "" {
+			return table, nil
+		}
+		join := Join{Left: table, Kind: kind, Natural: natural}
+		if join.Right, err = getTable(p); err != nil {
+			return SelectTable{}, err
+		}
+		//CROSS JOIN、NATURAL JOIN、APPLY没有连接条件
+		if kind != "CROSS" && !strings.HasSuffix(kind, "APPLY") && !natural {
+			switch {
+			case p.acceptKeyword("ON"):
+				if join.On, err = getEquationList(p); err != nil {
+					return SelectTable{}, err
+				}
+			case p.acceptKeyword("USING"):
+				if err = p.expectPunct("("); err != nil {
+					return SelectTable{}, err
+				}
+				if join.Using, err = getNameList(p); err != nil {
+					return SelectTable{}, err
+				}
+				if err = p.expectPunct(")"); err != nil {
+					return SelectTable{}, err
+				}
+			default:
+				return SelectTable{}, p.fail(ErrMissingKeyword, "ON", "USING")
+			}
+		}
+		join.Pos = p.posFrom(start)
+		table = SelectTable{Table: join, Pos: join.Pos}
+	}
+}
+
+// marshalJoin 序列化表连接，右边的表也是连接时要用括号括起
+func marshalJoin(m *marshaler, join Join) (retSQL string, err error) {
+	left, err := marshalTableItem(m, join.Left)
+	if err != nil {
+		return "", err
+	}
+	right, err := marshalTableItem(m, join.Right)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := join.Right.Table.(Join); ok {
+		right = "(" + right + ")"
+	}
+	keyword := ""
+	switch join.Kind {
+	case "INNER", "LEFT", "RIGHT", "FULL", "CROSS":
+		keyword = join.Kind + " JOIN"
+	case "CROSS APPLY", "OUTER APPLY":
+		keyword = join.Kind
+	default:
+		return "", errors.New("存在不能识别的连表查询" + join.Kind)
+	}
+	if join.Natural {
+		keyword = "NATURAL " + keyword
+	}
+	retSQL = left + " " + m.kw(keyword) + " " + right
+	switch {
+	case len(join.On.Equation) != 0:
+		eqList, err := marshalEquationList(m, join.On)
+		if err != nil {
+			return "", err
+		}
+		retSQL += " " + m.kw("ON") + " " + eqList
+	case len(join.Using) != 0:
+		retSQL += " " + m.kw("USING") + " (" + strings.Join(join.Using, ",") + ")"
+	case join.Kind != "CROSS" && !strings.HasSuffix(join.Kind, "APPLY") && !join.Natural:
+		return "", errors.New(join.Kind + " JOIN缺失连接条件")
+	}
+	return retSQL, nil
+}
+
+func getParamsByJoin(join Join) (pars []Params) {
+	pars = append(pars, getParamsByTableItem(join.Left)...)
+	pars = append(pars, getParamsByTableItem(join.Right)...)
+	pars = append(pars, getParamsBySelectEquationList(join.On)...)
+	return pars
+}
+
+// deleteParamsByJoin 连接条件被全部删除时，内连接变成CROSS JOIN；外连接保留连接类型，连接条件变成ON 1=1，
+// 变成CROSS JOIN会丢掉外连接保留的行
+func deleteParamsByJoin(join Join, pars []Params) Join {
+	join.Left = deleteParamsByTableItem(join.Left, pars)
+	join.Right = deleteParamsByTableItem(join.Right, pars)
+	if len(join.On.Equation) != 0 {
+		if join.On = deleteParamsBySelectEquationList(join.On, pars); len(join.On.Equation) == 0 {
+			if join.Kind == "INNER" {
+				join.Kind = "CROSS"
+			} else {
+				one := Value{Value: NumberLiteral{Value: "1"}}
+				join.On.Equation = []Equation{{Equation: EquationNorm{Left: one, Right: one, Operator: "="}}}
+			}
+		}
+	}
+	return join
+}
+
+func expandParamsByJoin(join Join, params Params, count int) Join {
+	join.Left = expandParamsByTableItem(join.Left, params, count)
+	join.Right = expandParamsByTableItem(join.Right, params, count)
+	if len(join.On.Equation) != 0 {
+		join.On = expandParamsBySelectEquationList(join.On, params, count)
+	}
+	return join
+}
@@ -0,0 +1,121 @@
+package sqlParser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinKinds(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		//JOIN是INNER JOIN，OUTER省略
+		{"select a from t join s on t.id = s.id", "SELECT a FROM t INNER JOIN s ON t.id=s.id"},
+		{"select a from t left outer join s on t.id = s.id", "SELECT a FROM t LEFT JOIN s ON t.id=s.id"},
+		{"select a from t natural join s", "SELECT a FROM t NATURAL INNER JOIN s"},
+		{"select a from t natural left join s", "SELECT a FROM t NATURAL LEFT JOIN s"},
+		{"select a from t full join s using (id, name)", "SELECT a FROM t FULL JOIN s USING (id,name)"},
+		{"select a from t cross join s", "SELECT a FROM t CROSS JOIN s"},
+		{"select a from t cross apply (select b from s where s.id = t.id) x", "SELECT a FROM t CROSS APPLY (SELECT b FROM s WHERE s.id=t.id) x"},
+		{"select a from t outer apply (select b from s) x", "SELECT a FROM t OUTER APPLY (SELECT b FROM s) x"},
+		//逗号隔开的表和表连接可以混用
+		{"select a from t, s join u using (id)", "SELECT a FROM t,s INNER JOIN u USING (id)"},
+	}
+	for _, tt := range tests {
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestJoinTree(t *testing.T) {
+	//多个连接从左到右组合：(t LEFT JOIN s) INNER JOIN u
+	stmt, err := Unmarshal("select a from t x left join s y on x.id = y.id join u using (id)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer, ok := stmt.Ast.(Select).Body.(SelectItem).Table[0].Table.(Join)
+	if !ok || outer.Kind != "INNER" || outer.Right.Table != "u" || !reflect.DeepEqual(outer.Using, []string{"id"}) {
+		t.Fatalf("outer join = %+v", outer)
+	}
+	inner, ok := outer.Left.Table.(Join)
+	if !ok || inner.Kind != "LEFT" || inner.Left.Alias != "x" || inner.Right.Alias != "y" || len(inner.On.Equation) != 1 {
+		t.Errorf("inner join = %+v", inner)
+	}
+	//括号括起的连接在右边
+	stmt, err = Unmarshal("select a from t natural join (s join u on s.id = u.id)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	join := stmt.Ast.(Select).Body.(SelectItem).Table[0].Table.(Join)
+	if _, ok := join.Right.Table.(Join); !ok || !join.Natural || len(join.On.Equation) != 0 {
+		t.Errorf("join = %+v", join)
+	}
+	want := "select a from t natural inner join (s inner join u on s.id=u.id)"
+	if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: KeywordLower}); err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestJoinDeleteParams(t *testing.T) {
+	testParams(t, []paramsCase{
+		{"select a from t join s on t.id = s.id and s.k = :p where t.x = :q", []string{":p", ":q"}, ":p", "SELECT a FROM t INNER JOIN s ON t.id=s.id WHERE t.x=:q", "", ""},
+		//连接条件被全部删除时，内连接变成CROSS JOIN，外连接保留连接类型
+		{"select a from t join s on s.k = :p", []string{":p"}, ":p", "SELECT a FROM t CROSS JOIN s", "", ""},
+		{"select a from t left join s on s.k = :p", []string{":p"}, ":p", "SELECT a FROM t LEFT JOIN s ON 1=1", "", ""},
+		{"select a from t full outer join s on s.k = :p and s.j = :p", []string{":p", ":p"}, ":p", "SELECT a FROM t FULL JOIN s ON 1=1", "", ""},
+		{"select a from t right join s on (s.k = :p or s.j = :p) join u on u.id = :p", []string{":p", ":p", ":p"}, ":p", "SELECT a FROM t RIGHT JOIN s ON 1=1 CROSS JOIN u", "", ""},
+		{"select a from t join s on t.id = s.id and s.k in (:p)", []string{":p"}, ":p", "SELECT a FROM t INNER JOIN s ON t.id=s.id", ":p", "SELECT a FROM t INNER JOIN s ON t.id=s.id AND s.k IN(:p0,:p1,:p2)"},
+		{
+			"select a from t cross apply (select b from s where s.k in (:p)) x",
+			[]string{":p"},
+			":p", "SELECT a FROM t CROSS APPLY (SELECT b FROM s) x",
+			":p", "SELECT a FROM t CROSS APPLY (SELECT b FROM s WHERE s.k IN(:p0,:p1,:p2)) x",
+		},
+	})
+}
+
+func TestJoinSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select a from t join s", ErrMissingKeyword, 23},
+		{"select a from t left join s using id", ErrMissingKeyword, 35},
+		{"select a from t join s on", ErrUnexpectedEOF, 26},
+		{"select a from t full join s using ()", ErrMissingName, 36},
+		//NATURAL JOIN、CROSS JOIN不能有连接条件
+		{"select a from t natural join s on t.id = s.id", ErrUnexpectedToken, 32},
+		{"select a from t cross join s on t.id = s.id", ErrUnexpectedToken, 30},
+		{"select a from t inner s on 1=1", ErrUnexpectedToken, 17},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+}
+
+func TestMarshalJoinErrors(t *testing.T) {
+	table := SelectTable{Table: "t"}
+	bad := SelectTable{Table: Join{Left: table, Kind: "OUTER", Right: table}}
+	//表连接、子查询、嵌套的表中的错误都要返回
+	tests := map[string]interface{}{
+		"unknown kind":       Join{Left: table, Kind: "OUTER", Right: table},
+		"bad left table":     Join{Left: bad, Kind: "INNER", Right: table},
+		"bad subquery table": Select{Body: SelectItem{Field: []SelectField{{Field: Value{Value: "a"}}}, Table: []SelectTable{bad}}},
+		"bad nested right":   SelectTable{Table: Join{Left: table, Kind: "LEFT", Right: bad}},
+		"nil table":          nil,
+		"unsupported type":   1,
+	}
+	for name, table := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalSelectTable(&marshaler{}, table); err == nil {
+				t.Errorf("marshalSelectTable(%+v) = %q, should fail", table, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package sqlParser
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenType 词法单元的类型
+type TokenType int
+
+const (
+	TokenEOF         TokenType = iota //输入结束
+	TokenIdent                        //普通标识符，关键词也属于它，例：SELECT、T_USER
+	TokenQuotedIdent                  //被双引号或反单引号括起的标识符
+	TokenString                       //被单引号括起的字符串
+	TokenNumber                       //数字，例：12、1.5、1E-5
+	TokenParam                        //绑定参数，例：:RECNO
+	TokenOperator                     //运算符，例：+ - * / || = <> != >= <= :=
+	TokenPunct                        //标点：( ) , . ;
+	TokenComment                      //注释：-- 行注释、/* 块注释 */
+	TokenIllegal                      //无法识别的字符，只出现在ParseError中
+)
+
+// String 返回词法单元类型的名称
+func (t TokenType) String() string {
+	switch t {
+	case TokenEOF:
+		return "EOF"
+	case TokenIdent:
+		return "IDENT"
+	case TokenQuotedIdent:
+		return "QUOTED_IDENT"
+	case TokenString:
+		return "STRING"
+	case TokenNumber:
+		return "NUMBER"
+	case TokenParam:
+		return "PARAM"
+	case TokenOperator:
+		return "OPERATOR"
+	case TokenPunct:
+		return "PUNCT"
+	case TokenComment:
+		return "COMMENT"
+	case TokenIllegal:
+		return "ILLEGAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Token 词法单元，Start、End是它在原SQL中的字节偏移，Value是原SQL中的原文
+type Token struct {
+	Type  TokenType
+	Value string
+	Start int
+	End   int
+}
+
+// Lexer SQL词法分析器，按顺序输出带类型的词法单元
+type Lexer struct {
+	src string
+	pos int
+}
+
+// NewLexer 创建一个词法分析器
+func NewLexer(s string) *Lexer {
+	return &Lexer{src: s}
+}
+
+// Tokenize 将SQL拆分成词法单元，返回的列表包含注释，但不包含结尾的EOF
+func Tokenize(s string) (tokens []Token, err error) {
+	lex := NewLexer(s)
+	for {
+		tok, err := lex.Next()
+		if err != nil {
+			return tokens, err
+		}
+		if tok.Type == TokenEOF {
+			return tokens, nil
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+// Next 返回下一个词法单元，输入结束后一直返回TokenEOF
+func (l *Lexer) Next() (Token, error) {
+	l.skipSpaces()
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return Token{Type: TokenEOF, Start: start, End: start}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case c == '-' && l.peekByte(1) == '-':
+		//行注释，直到行尾
+		end := strings.IndexByte(l.src[l.pos:], '\n')
+		if end == -1 {
+			l.pos = len(l.src)
+		} else {
+			l.pos += end
+		}
+		return l.token(TokenComment, start), nil
+	case c == '/' && l.peekByte(1) == '*':
+		end := strings.Index(l.src[l.pos+2:], "*/")
+		if end == -1 {
+			l.pos = len(l.src)
+			return Token{}, l.fail(ErrUnterminatedComment, TokenComment, start)
+		}
+		l.pos += end + 4
+		return l.token(TokenComment, start), nil
+	case c == '\'':
+		if !l.scanQuoted('\'') {
+			return Token{}, l.fail(ErrUnterminatedString, TokenString, start)
+		}
+		return l.token(TokenString, start), nil
+	case c == '"' || c == '`':
+		if !l.scanQuoted(c) {
+			return Token{}, l.fail(ErrUnterminatedIdent, TokenQuotedIdent, start)
+		}
+		return l.token(TokenQuotedIdent, start), nil
+	case c == ':' && isIdentPart(l.peekRune(1)):
+		l.pos++
+		l.scanIdent()
+		return l.token(TokenParam, start), nil
+	case isDigit(c) || (c == '.' && isDigit(l.peekByte(1))):
+		l.scanNumber()
+		return l.token(TokenNumber, start), nil
+	case (c == 'N' || c == 'n') && l.peekByte(1) == '\'':
+		//N'...'国际字符集字符串
+		l.pos++
+		if !l.scanQuoted('\'') {
+			return Token{}, l.fail(ErrUnterminatedString, TokenString, start)
+		}
+		return l.token(TokenString, start), nil
+	case isIdentStart(l.peekRune(0)):
+		l.scanIdent()
+		return l.token(TokenIdent, start), nil
+	}
+	//运算符和标点
+	two := ""
+	if l.pos+2 <= len(l.src) {
+		two = l.src[l.pos : l.pos+2]
+	}
+	switch two {
+	case "||", "<>", "!=", ">=", "<=", "^=", "~=", "=>", ":=":
+		l.pos += 2
+		return l.token(TokenOperator, start), nil
+	}
+	switch c {
+	case '+', '-', '*', '/', '=', '<', '>', '%':
+		l.pos++
+		return l.token(TokenOperator, start), nil
+	case '(', ')', ',', '.', ';', '@':
+		l.pos++
+		return l.token(TokenPunct, start), nil
+	}
+	_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+	l.pos += size
+	return Token{}, l.fail(ErrIllegalCharacter, TokenIllegal, start)
+}
+
+func (l *Lexer) token(t TokenType, start int) Token {
+	return Token{Type: t, Value: l.src[start:l.pos], Start: start, End: l.pos}
+}
+
+// fail 返回从start开始到当前位置的词法单元不正确时的错误
+func (l *Lexer) fail(code ErrorCode, t TokenType, start int) error {
+	return newParseError(l.src, code, l.token(t, start))
+}
+
+func (l *Lexer) skipSpaces() {
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case ' ', '\t', '\n', '\r', '\f', '\v':
+			l.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (l *Lexer) peekByte(n int) byte {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+func (l *Lexer) peekRune(n int) rune {
+	if l.pos+n < len(l.src) {
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos+n:])
+		return r
+	}
+	return utf8.RuneError
+}
+
+// scanQuoted 扫描被引号括起的部分，连续的两个引号视为转义，缺失结束的引号时返回false
+func (l *Lexer) scanQuoted(quote byte) bool {
+	l.pos++
+	for l.pos < len(l.src) {
+		if l.src[l.pos] == quote {
+			if l.peekByte(1) == quote {
+				l.pos += 2
+				continue
+			}
+			l.pos++
+			return true
+		}
+		l.pos++
+	}
+	return false
+}
+
+func (l *Lexer) scanIdent() {
+	for l.pos < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+		if !isIdentPart(r) {
+			return
+		}
+		l.pos += size
+	}
+}
+
+func (l *Lexer) scanNumber() {
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' && l.peekByte(1) != '.' {
+		l.pos++
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+	}
+	//科学计数法，例：1E-5
+	if c := l.peekByte(0); c == 'E' || c == 'e' {
+		n := 1
+		if s := l.peekByte(1); s == '+' || s == '-' {
+			n++
+		}
+		if isDigit(l.peekByte(n)) {
+			l.pos += n
+			for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+				l.pos++
+			}
+		}
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= 0x80 && r != utf8.RuneError)
+}
+
+// isIdentPart Oracle的标识符允许包含$和#，例：V$SESSION
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || (r >= '0' && r <= '9') || r == '$' || r == '#'
+}
@@ -0,0 +1,103 @@
+package sqlParser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		sql    string
+		tokens []Token
+	}{
+		{
+			"select a.b, 'it''s', \"Name\" from t where x >= :p1",
+			[]Token{
+				{TokenIdent, "select", 0, 6},
+				{TokenIdent, "a", 7, 8},
+				{TokenPunct, ".", 8, 9},
+				{TokenIdent, "b", 9, 10},
+				{TokenPunct, ",", 10, 11},
+				{TokenString, "'it''s'", 12, 19},
+				{TokenPunct, ",", 19, 20},
+				{TokenQuotedIdent, "\"Name\"", 21, 27},
+				{TokenIdent, "from", 28, 32},
+				{TokenIdent, "t", 33, 34},
+				{TokenIdent, "where", 35, 40},
+				{TokenIdent, "x", 41, 42},
+				{TokenOperator, ">=", 43, 45},
+				{TokenParam, ":p1", 46, 49},
+			},
+		},
+		{
+			//字符串里的问号、冒号、注释符号不会被当作参数、注释
+			"'a?:b--c' -- 行注释\n/* 块 */1.5E-3||N'x'",
+			[]Token{
+				{TokenString, "'a?:b--c'", 0, 9},
+				{TokenComment, "-- 行注释", 10, 22},
+				{TokenComment, "/* 块 */", 23, 32},
+				{TokenNumber, "1.5E-3", 32, 38},
+				{TokenOperator, "||", 38, 40},
+				{TokenString, "N'x'", 40, 44},
+			},
+		},
+		{
+			"a<>b!=c^=d~=e;`f`",
+			[]Token{
+				{TokenIdent, "a", 0, 1},
+				{TokenOperator, "<>", 1, 3},
+				{TokenIdent, "b", 3, 4},
+				{TokenOperator, "!=", 4, 6},
+				{TokenIdent, "c", 6, 7},
+				{TokenOperator, "^=", 7, 9},
+				{TokenIdent, "d", 9, 10},
+				{TokenOperator, "~=", 10, 12},
+				{TokenIdent, "e", 12, 13},
+				{TokenPunct, ";", 13, 14},
+				{TokenQuotedIdent, "`f`", 14, 17},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := Tokenize(tt.sql)
+		if err != nil {
+			t.Fatalf("Tokenize(%q): %v", tt.sql, err)
+		}
+		if !reflect.DeepEqual(got, tt.tokens) {
+			t.Errorf("Tokenize(%q) = %v, want %v", tt.sql, got, tt.tokens)
+		}
+	}
+}
+
+func TestTokenizeError(t *testing.T) {
+	tests := []struct {
+		sql   string
+		code  ErrorCode
+		start int
+	}{
+		{"select 'abc", ErrUnterminatedString, 7},
+		{"select \"abc", ErrUnterminatedIdent, 7},
+		{"select 1 /* abc", ErrUnterminatedComment, 9},
+		{"select # from t", ErrIllegalCharacter, 7},
+	}
+	for _, tt := range tests {
+		_, err := Tokenize(tt.sql)
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("Tokenize(%q) = %v, want *ParseError", tt.sql, err)
+		}
+		if perr.Code != tt.code || perr.Pos.Start != tt.start {
+			t.Errorf("Tokenize(%q) = %s at %d, want %s at %d", tt.sql, perr.Code, perr.Pos.Start, tt.code, tt.start)
+		}
+	}
+}
+
+func TestLexerEOF(t *testing.T) {
+	lex := NewLexer("a")
+	lex.Next()
+	for i := 0; i < 2; i++ {
+		if tok, err := lex.Next(); err != nil || tok.Type != TokenEOF {
+			t.Errorf("Next() = %v, %v, want EOF", tok, err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package sqlParser
+
+import (
+	"errors"
+	"strings"
+)
+
+// Limit 限制返回的行数，它有两种形式：
+// 1。Oracle的OFFSET n ROWS FETCH FIRST|NEXT n ROWS|PERCENT ONLY|WITH TIES，Style是FETCH；
+// 2。MySQL的LIMIT n、LIMIT m, n、LIMIT n OFFSET m，PostgreSQL的OFFSET m LIMIT n，Style是LIMIT。
+// Style为空时没有该子句，行数可以是参数
+type Limit struct {
+	Style       string //FETCH、LIMIT
+	Comma       bool   //LIMIT m, n的写法，跳过的行数写在返回的行数前面
+	OffsetFirst bool   //OFFSET m LIMIT n的写法
+	Offset      Value  //跳过的行数，没有时Value为nil
+	OffsetRows  string //OFFSET n后面的ROW、ROWS，为空时生成ROWS
+	Count       Value  //返回的行数，没有时Value为nil，例：FETCH FIRST ROW ONLY
+	Fetch       string //FETCH后面的FIRST、NEXT，没有FETCH时为空
+	Percent     bool   //FETCH FIRST n PERCENT ROWS
+	Rows        string //FETCH FIRST n后面的ROW、ROWS，为空时生成ROWS
+	WithTies    bool   //WITH TIES，否则是ONLY
+	Pos         Pos
+}
+
+// isLimitStart 当前词法单元是否是限制行数子句的开始。LIMIT、OFFSET、FETCH不是保留的关键词，要看它们后面的词法单元
+func (p *parser) isLimitStart() bool {
+	next := p.peekN(1)
+	isCount := next.Type == TokenNumber || next.Type == TokenParam
+	switch {
+	case p.isKeyword("LIMIT"), p.isKeyword("OFFSET"):
+		return isCount
+	case p.isKeyword("FETCH"):
+		return p.isKeywordAt(1, "FIRST") || p.isKeywordAt(1, "NEXT")
+	}
+	return false
+}
+
+// getLimit 解析限制行数的子句，调用前需用isLimitStart判断
+func getLimit(p *parser) (limit Limit, err error) {
+	start := p.peek().Start
+	if p.acceptKeyword("LIMIT") {
+		limit.Style = "LIMIT"
+		first, err := getValue(p)
+		if err != nil {
+			return Limit{}, err
+		}
+		switch {
+		case p.acceptPunct(","):
+			limit.Comma = true
+			limit.Offset = first
+			if limit.Count, err = getValue(p); err != nil {
+				return Limit{}, err
+			}
+		case p.acceptKeyword("OFFSET"):
+			limit.Count = first
+			if limit.Offset, err = getValue(p); err != nil {
+				return Limit{}, err
+			}
+		default:
+			limit.Count = first
+		}
+		limit.Pos = p.posFrom(start)
+		return limit, nil
+	}
+	limit.Style = "FETCH"
+	if p.acceptKeyword("OFFSET") {
+		if limit.Offset, err = getValue(p); err != nil {
+			return Limit{}, err
+		}
+		limit.OffsetRows = acceptRows(p)
+		//PostgreSQL的OFFSET m LIMIT n
+		if limit.OffsetRows == "" && p.isLimitStart() && p.acceptKeyword("LIMIT") {
+			limit.Style, limit.OffsetFirst = "LIMIT", true
+			if limit.Count, err = getValue(p); err != nil {
+				return Limit{}, err
+			}
+			limit.Pos = p.posFrom(start)
+			return limit, nil
+		}
+	}
+	if p.acceptKeyword("FETCH") {
+		if !p.isAnyKeyword("FIRST", "NEXT") {
+			return Limit{}, p.fail(ErrMissingKeyword, "FIRST", "NEXT")
+		}
+		limit.Fetch = strings.ToUpper(p.keyword().Value)
+		if !p.isAnyKeyword("ROW", "ROWS") {
+			if limit.Count, err = getValue(p); err != nil {
+				return Limit{}, err
+			}
+			limit.Percent = p.acceptKeyword("PERCENT")
+		}
+		if limit.Rows = acceptRows(p); limit.Rows == "" {
+			return Limit{}, p.fail(ErrMissingKeyword, "ROW", "ROWS")
+		}
+		switch {
+		case p.acceptKeyword("ONLY"):
+		case p.acceptKeyword("WITH", "TIES"):
+			limit.WithTies = true
+		default:
+			return Limit{}, p.fail(ErrMissingKeyword, "ONLY", "WITH TIES")
+		}
+	}
+	limit.Pos = p.posFrom(start)
+	return limit, nil
+}
+
+// acceptRows 解析ROW或者ROWS，返回大写的写法，没有时返回空
+func acceptRows(p *parser) string {
+	for _, rows := range []string{"ROWS", "ROW"} {
+		if p.acceptKeyword(rows) {
+			return rows
+		}
+	}
+	return ""
+}
+
+// rowsKeyword ROW、ROWS的写法，为空时是ROWS
+func rowsKeyword(rows string) string {
+	if rows == "" {
+		return "ROWS"
+	}
+	return rows
+}
+
+// marshalLimit 序列化限制行数的子句
+func marshalLimit(m *marshaler, limit Limit) (retSQL string, err error) {
+	var offset, count string
+	if limit.Offset.Value != nil {
+		if offset, err = marshalValue(m, limit.Offset, true); err != nil {
+			return "", err
+		}
+	}
+	if limit.Count.Value != nil {
+		if count, err = marshalValue(m, limit.Count, true); err != nil {
+			return "", err
+		}
+	}
+	switch limit.Style {
+	case "LIMIT":
+		if count == "" {
+			return "", errors.New("LIMIT缺失行数")
+		}
+		if limit.Comma && offset != "" {
+			return m.kw("LIMIT") + " " + offset + "," + count, nil
+		}
+		if limit.OffsetFirst && offset != "" {
+			return m.kw("OFFSET") + " " + offset + " " + m.kw("LIMIT") + " " + count, nil
+		}
+		retSQL = m.kw("LIMIT") + " " + count
+		if offset != "" {
+			retSQL += " " + m.kw("OFFSET") + " " + offset
+		}
+		return retSQL, nil
+	case "FETCH":
+		if offset != "" {
+			retSQL = m.kw("OFFSET") + " " + offset + " " + m.kw(rowsKeyword(limit.OffsetRows)) + " "
+		}
+		if limit.Fetch != "" {
+			retSQL += m.kw("FETCH "+limit.Fetch) + " "
+			if count != "" {
+				retSQL += count + " "
+				if limit.Percent {
+					retSQL += m.kw("PERCENT") + " "
+				}
+			}
+			retSQL += m.kw(rowsKeyword(limit.Rows)) + " "
+			if limit.WithTies {
+				retSQL += m.kw("WITH TIES")
+			} else {
+				retSQL += m.kw("ONLY")
+			}
+		}
+		return strings.TrimSpace(retSQL), nil
+	}
+	return "", errors.New("不能识别的限制行数形式")
+}
+
+// getParamsByLimit 按参数在原SQL中的顺序返回，只有LIMIT n OFFSET m的返回行数写在前面
+func getParamsByLimit(limit Limit) (pars []Params) {
+	if limit.Style == "LIMIT" && !limit.Comma && !limit.OffsetFirst {
+		pars = append(pars, getParamsBySelectValue(limit.Count)...)
+		return append(pars, getParamsBySelectValue(limit.Offset)...)
+	}
+	pars = append(pars, getParamsBySelectValue(limit.Offset)...)
+	pars = append(pars, getParamsBySelectValue(limit.Count)...)
+	return pars
+}
+
+// deleteParamsByLimit 跳过的行数中有参数被删除时不再跳过，返回的行数中有参数被删除时不再限制返回的行数
+func deleteParamsByLimit(limit Limit, pars []Params) Limit {
+	if limit.Offset.Value != nil {
+		limit.Offset = deleteParamsBySelectValue(limit.Offset, pars)
+		limit.Comma = limit.Comma && limit.Offset.Value != nil
+	}
+	if limit.Count.Value != nil && deleteParamsBySelectValue(limit.Count, pars).Value == nil {
+		limit.Count = Value{}
+		if limit.Style == "LIMIT" {
+			return Limit{}
+		}
+		limit.Fetch = ""
+	}
+	if limit.Style == "FETCH" && limit.Offset.Value == nil && limit.Fetch == "" {
+		return Limit{}
+	}
+	return limit
+}
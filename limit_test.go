@@ -0,0 +1,171 @@
+package sqlParser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLimitStyles(t *testing.T) {
+	//Offset、Count只比较有没有值
+	tests := []struct {
+		sql    string
+		want   string
+		limit  Limit
+		offset bool
+		count  bool
+	}{
+		{"select a from t limit 10", "SELECT a FROM t LIMIT 10", Limit{Style: "LIMIT"}, false, true},
+		{"select a from t limit 10, 20", "SELECT a FROM t LIMIT 10,20", Limit{Style: "LIMIT", Comma: true}, true, true},
+		{"select a from t limit 20 offset 10", "SELECT a FROM t LIMIT 20 OFFSET 10", Limit{Style: "LIMIT"}, true, true},
+		//PostgreSQL的OFFSET m LIMIT n保持原来的顺序
+		{"select a from t order by a offset 10 limit 5", "SELECT a FROM t ORDER BY a OFFSET 10 LIMIT 5", Limit{Style: "LIMIT", OffsetFirst: true}, true, true},
+		{"select a from t offset 5 rows", "SELECT a FROM t OFFSET 5 ROWS", Limit{Style: "FETCH", OffsetRows: "ROWS"}, true, false},
+		{
+			"select a from t offset 5 rows fetch next 10 rows only", "SELECT a FROM t OFFSET 5 ROWS FETCH NEXT 10 ROWS ONLY",
+			Limit{Style: "FETCH", OffsetRows: "ROWS", Fetch: "NEXT", Rows: "ROWS"}, true, true,
+		},
+		{
+			"select a from t order by a fetch first 10 percent rows with ties", "SELECT a FROM t ORDER BY a FETCH FIRST 10 PERCENT ROWS WITH TIES",
+			Limit{Style: "FETCH", Fetch: "FIRST", Percent: true, Rows: "ROWS", WithTies: true}, false, true,
+		},
+		//ROW、ROWS保持原来的写法，FETCH后面可以没有行数
+		{"select a from t fetch first row only", "SELECT a FROM t FETCH FIRST ROW ONLY", Limit{Style: "FETCH", Fetch: "FIRST", Rows: "ROW"}, false, false},
+		{
+			"select a from t offset 1 row fetch next 1 row with ties", "SELECT a FROM t OFFSET 1 ROW FETCH NEXT 1 ROW WITH TIES",
+			Limit{Style: "FETCH", OffsetRows: "ROW", Fetch: "NEXT", Rows: "ROW", WithTies: true}, true, true,
+		},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		got := stmt.Ast.(Select).Body.(SelectItem).Limit
+		if (got.Offset.Value != nil) != tt.offset || (got.Count.Value != nil) != tt.count {
+			t.Errorf("%q: offset %#v count %#v", tt.sql, got.Offset.Value, got.Count.Value)
+		}
+		got.Offset, got.Count, got.Pos = Value{}, Value{}, Pos{}
+		if !reflect.DeepEqual(got, tt.limit) {
+			t.Errorf("%q: got %+v, want %+v", tt.sql, got, tt.limit)
+		}
+		if out, err := Marshal(stmt); err != nil || out != tt.want {
+			t.Errorf("%q: got %q, %v, want %q", tt.sql, out, err, tt.want)
+		}
+	}
+}
+
+func TestLimitPlacement(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		//LIMIT、OFFSET后面不是行数时是普通的名字
+		{"select limit, offset from t", "select limit,offset from t"},
+		{"select a from t offset", "select a from t offset"},
+		//子查询各自的限制行数
+		{
+			"select a from (select b from u LIMIT 3) x where a in (select c from v Fetch First 1 Row Only)",
+			"select a from (select b from u limit 3) x where a in(select c from v fetch first 1 row only)",
+		},
+		//集合运算整体的限制行数
+		{"select a from t union select b from s offset :m limit :n", "select a from t union select b from s offset :m limit :n"},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: KeywordLower}); err != nil || got != tt.want {
+			t.Errorf("%q: got %q, %v, want %q", tt.sql, got, err, tt.want)
+		}
+	}
+}
+
+func TestLimitParamsOrder(t *testing.T) {
+	tests := []struct {
+		sql    string
+		params []string
+	}{
+		{"select a from t limit :n offset :m", []string{":n", ":m"}},
+		{"select a from t limit :m, :n", []string{":m", ":n"}},
+		{"select a from t offset :m rows fetch next :n rows only", []string{":m", ":n"}},
+		{"select a from t offset :m limit :n", []string{":m", ":n"}},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		if got := paramNames(stmt.Params()); !reflect.DeepEqual(got, tt.params) {
+			t.Errorf("%q: Params() = %v, want %v", tt.sql, got, tt.params)
+		}
+	}
+}
+
+func TestLimitDeleteParams(t *testing.T) {
+	tests := []struct {
+		sql    string
+		delete string
+		want   string
+	}{
+		{"select a from t limit :m, :n", ":m", "SELECT a FROM t LIMIT :n"},
+		{"select a from t limit :m, :n", ":n", "SELECT a FROM t"},
+		{"select a from t limit :n offset :m", ":m", "SELECT a FROM t LIMIT :n"},
+		{"select a from t offset :m rows fetch next :n rows only", ":n", "SELECT a FROM t OFFSET :m ROWS"},
+		{"select a from t offset :m rows fetch next :n rows only", ":m", "SELECT a FROM t FETCH NEXT :n ROWS ONLY"},
+		{"select a from t offset :m limit :n", ":m", "SELECT a FROM t LIMIT :n"},
+		{"select a from t offset :m limit :n", ":n", "SELECT a FROM t"},
+		{"select a from t offset :m row fetch first :n row only", ":m", "SELECT a FROM t FETCH FIRST :n ROW ONLY"},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		stmt.DeleteParams([]Params{{Name: tt.delete}})
+		got, err := Marshal(stmt)
+		if err != nil {
+			t.Fatalf("Marshal(%q): %v", tt.sql, err)
+		}
+		if got != tt.want {
+			t.Errorf("%q delete %s: got %q, want %q", tt.sql, tt.delete, got, tt.want)
+		}
+	}
+}
+
+func TestLimitSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql      string
+		code     ErrorCode
+		expected []string
+		column   int
+	}{
+		{"select a from t limit 10,", ErrUnexpectedEOF, nil, 26},
+		{"select a from t fetch first 10 percent", ErrMissingKeyword, []string{"ROW", "ROWS"}, 39},
+		{"select a from t offset 5 rows fetch next 10 rows", ErrMissingKeyword, []string{"ONLY", "WITH TIES"}, 49},
+		//LIMIT n OFFSET m后面不能有ROWS，一个查询只能有一个限制行数的子句
+		{"select a from t limit 10 offset 5 rows", ErrUnexpectedToken, nil, 35},
+		{"select a from t fetch first 10 rows only limit 5", ErrUnexpectedToken, nil, 42},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		perr, ok := err.(*ParseError)
+		if !ok || perr.Code != tt.code || perr.Pos.Column != tt.column || len(perr.Expected)+len(tt.expected) != 0 && !reflect.DeepEqual(perr.Expected, tt.expected) {
+			t.Errorf("%q: got %v, want %s %q at column %d", tt.sql, err, tt.code, tt.expected, tt.column)
+		}
+	}
+}
+
+func TestMarshalLimitErrors(t *testing.T) {
+	tests := map[string]Limit{
+		"limit without count": {Style: "LIMIT"},
+		"unknown style":       {Style: "TOP", Count: Value{Value: NumberLiteral{Value: "1"}}},
+	}
+	for name, limit := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalLimit(&marshaler{}, limit); err == nil {
+				t.Errorf("marshalLimit = %q, should fail", got)
+			}
+		})
+	}
+}
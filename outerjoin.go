@@ -0,0 +1,608 @@
+package sqlParser
+
+import "strings"
+
+// OuterJoinIssue 不能安全转换成ANSI连接的(+)条件
+type OuterJoinIssue struct {
+	Pos    Pos //条件在原SQL中的位置，整个单查询的问题是WHERE的位置
+	Reason string
+}
+
+// outerJoin 一张被外连接的表：它的连接条件，以及它连接的表
+type outerJoin struct {
+	On   []Equation
+	Deps map[int]bool
+}
+
+// ConvertOuterJoins 把逗号隔开的表和带有(+)的WHERE条件转换成LEFT JOIN、RIGHT JOIN，(+)所在的表是被外连接的表，它的(+)条件移到ON里面。
+// WITH、FROM里的子查询，以及字段、条件、分组、排序里的子查询也会被转换，存在不能安全转换的条件时，该单查询保持不变，返回全部不能转换的原因
+func (stmt *Statement) ConvertOuterJoins() []OuterJoinIssue {
+	sel, ok := stmt.Ast.(Select)
+	if !ok {
+		return nil
+	}
+	var issues []OuterJoinIssue
+	stmt.Ast = convertOuterJoinsBySelect(sel, &issues)
+	return issues
+}
+
+func convertOuterJoinsBySelect(sel Select, issues *[]OuterJoinIssue) Select {
+	for i := 0; i < len(sel.With.Item); i++ {
+		sel.With.Item[i].Query = convertOuterJoinsBySelect(sel.With.Item[i].Query, issues)
+	}
+	sel.Body = convertOuterJoinsByBody(sel.Body, issues)
+	sel.Order = convertOuterJoinsByOrder(sel.Order, issues)
+	return sel
+}
+
+func convertOuterJoinsByBody(body interface{}, issues *[]OuterJoinIssue) interface{} {
+	switch v := body.(type) {
+	case SelectItem:
+		for i := 0; i < len(v.Table); i++ {
+			v.Table[i].Table = convertOuterJoinsByTable(v.Table[i].Table, issues)
+		}
+		convertOuterJoinsBySubqueries(&v, issues)
+		*issues = append(*issues, convertOuterJoinsBySelectItem(&v)...)
+		return v
+	case SetOperation:
+		v.Left = convertOuterJoinsByBody(v.Left, issues)
+		v.Right = convertOuterJoinsByBody(v.Right, issues)
+		return v
+	case Select:
+		return convertOuterJoinsBySelect(v, issues)
+	}
+	return body
+}
+
+func convertOuterJoinsByTable(table interface{}, issues *[]OuterJoinIssue) interface{} {
+	switch v := table.(type) {
+	case Select:
+		return convertOuterJoinsBySelect(v, issues)
+	case Join:
+		v.Left.Table = convertOuterJoinsByTable(v.Left.Table, issues)
+		v.Right.Table = convertOuterJoinsByTable(v.Right.Table, issues)
+		v.On = convertOuterJoinsByEquationList(v.On, issues)
+		return v
+	}
+	return table
+}
+
+// convertOuterJoinsBySubqueries 转换单查询的字段、条件、分组、排序里的子查询，单查询自己的(+)条件由convertOuterJoinsBySelectItem转换
+func convertOuterJoinsBySubqueries(sel *SelectItem, issues *[]OuterJoinIssue) {
+	for i := range sel.Field {
+		sel.Field[i].Field = convertOuterJoinsByValue(sel.Field[i].Field, issues)
+	}
+	sel.Where = convertOuterJoinsByEquationList(sel.Where, issues)
+	sel.StartWith = convertOuterJoinsByEquationList(sel.StartWith, issues)
+	sel.ConnectBy.Condition = convertOuterJoinsByEquationList(sel.ConnectBy.Condition, issues)
+	sel.Group = convertOuterJoinsByValues(sel.Group, issues)
+	sel.Having = convertOuterJoinsByEquationList(sel.Having, issues)
+	for i := range sel.Window {
+		sel.Window[i].Spec = convertOuterJoinsByWindowSpec(sel.Window[i].Spec, issues)
+	}
+	sel.Order = convertOuterJoinsByOrder(sel.Order, issues)
+}
+
+func convertOuterJoinsByOrder(order interface{}, issues *[]OuterJoinIssue) interface{} {
+	switch v := order.(type) {
+	case OrderBy:
+		v.Item = convertOuterJoinsByOrderItems(v.Item, issues)
+		return v
+	case Function:
+		return convertOuterJoinsByValue(Value{Value: v}, issues).Value
+	}
+	return order
+}
+
+func convertOuterJoinsByOrderItems(items []OrderItem, issues *[]OuterJoinIssue) []OrderItem {
+	for i := range items {
+		items[i].Expr = convertOuterJoinsByValue(items[i].Expr, issues)
+	}
+	return items
+}
+
+func convertOuterJoinsByWindowSpec(spec WindowSpec, issues *[]OuterJoinIssue) WindowSpec {
+	spec.Partition = convertOuterJoinsByValues(spec.Partition, issues)
+	spec.Order = convertOuterJoinsByOrderItems(spec.Order, issues)
+	return spec
+}
+
+// convertOuterJoinsByEquationList 转换条件中的子查询
+func convertOuterJoinsByEquationList(eqList EquationList, issues *[]OuterJoinIssue) EquationList {
+	for i := range eqList.Equation {
+		eqList.Equation[i].Equation = convertOuterJoinsByEquation(eqList.Equation[i].Equation, issues)
+	}
+	return eqList
+}
+
+func convertOuterJoinsByEquation(eq interface{}, issues *[]OuterJoinIssue) interface{} {
+	switch v := eq.(type) {
+	case EquationNorm:
+		v.Left = convertOuterJoinsByValue(v.Left, issues)
+		v.Right = convertOuterJoinsByValue(v.Right, issues)
+		return v
+	case EquationBetween:
+		v.Field = convertOuterJoinsByValue(v.Field, issues)
+		v.Left = convertOuterJoinsByValue(v.Left, issues)
+		v.Right = convertOuterJoinsByValue(v.Right, issues)
+		return v
+	case EquationOther:
+		v.Left = convertOuterJoinsByValue(v.Left, issues)
+		v.Right = convertOuterJoinsByValues(v.Right, issues)
+		return v
+	case Exists:
+		v.Query = convertOuterJoinsBySelect(v.Query, issues)
+		return v
+	case InSubquery:
+		v.Left = convertOuterJoinsByValue(v.Left, issues)
+		v.Query = convertOuterJoinsBySelect(v.Query, issues)
+		return v
+	case Quantified:
+		v.Left = convertOuterJoinsByValue(v.Left, issues)
+		if v.Query != nil {
+			query := convertOuterJoinsBySelect(*v.Query, issues)
+			v.Query = &query
+		}
+		v.List = convertOuterJoinsByValues(v.List, issues)
+		return v
+	case Not:
+		v.Equation = convertOuterJoinsByEquation(v.Equation, issues)
+		return v
+	case PredicateFunction:
+		v.Params = convertOuterJoinsByValues(v.Params, issues)
+		for i := range v.Passing {
+			v.Passing[i].Value = convertOuterJoinsByValue(v.Passing[i].Value, issues)
+		}
+		v.Condition = convertOuterJoinsByEquationList(v.Condition, issues)
+		return v
+	case EquationList:
+		return convertOuterJoinsByEquationList(v, issues)
+	}
+	return eq
+}
+
+// convertOuterJoinsByValue 转换值中的子查询
+func convertOuterJoinsByValue(value Value, issues *[]OuterJoinIssue) Value {
+	switch v := value.Value.(type) {
+	case Select:
+		value.Value = convertOuterJoinsBySelect(v, issues)
+	case Value:
+		value.Value = convertOuterJoinsByValue(v, issues)
+	case BinaryExpr:
+		v.Left = convertOuterJoinsByValue(v.Left, issues)
+		v.Right = convertOuterJoinsByValue(v.Right, issues)
+		value.Value = v
+	case UnaryExpr:
+		v.Operand = convertOuterJoinsByValue(v.Operand, issues)
+		value.Value = v
+	case ParenExpr:
+		v.Expr = convertOuterJoinsByValue(v.Expr, issues)
+		value.Value = v
+	case RowValue:
+		v.Items = convertOuterJoinsByValues(v.Items, issues)
+		value.Value = v
+	case GroupingElement:
+		v.Items = convertOuterJoinsByValues(v.Items, issues)
+		value.Value = v
+	case Function:
+		v.Params = convertOuterJoinsByValues(v.Params, issues)
+		if v.Over != nil {
+			over := convertOuterJoinsByWindowSpec(*v.Over, issues)
+			v.Over = &over
+		}
+		value.Value = v
+	case CaseWhen:
+		for i := range v.When {
+			v.When[i].Equation = convertOuterJoinsByEquationList(v.When[i].Equation, issues)
+			v.When[i].Value = convertOuterJoinsByValue(v.When[i].Value, issues)
+		}
+		v.Else = convertOuterJoinsByValue(v.Else, issues)
+		value.Value = v
+	}
+	return value
+}
+
+func convertOuterJoinsByValues(values []Value, issues *[]OuterJoinIssue) []Value {
+	for i := range values {
+		values[i] = convertOuterJoinsByValue(values[i], issues)
+	}
+	return values
+}
+
+// convertOuterJoinsBySelectItem 转换单查询，WHERE必须都是AND连接，(+)条件里的表都必须是FROM里逗号隔开的表
+func convertOuterJoinsBySelectItem(sel *SelectItem) (issues []OuterJoinIssue) {
+	var keep []Equation
+	joins := map[int]*outerJoin{}
+	hasOr := false
+	for _, eq := range sel.Where.Equation {
+		if eq.Connector == "OR" {
+			hasOr = true
+		}
+		var marked, other []ColumnRef
+		known := walkValuesColumnRefs(equationValues(eq.Equation), func(col ColumnRef) {
+			if col.OuterJoin {
+				marked = append(marked, col)
+			} else {
+				other = append(other, col)
+			}
+		})
+		if !known {
+			issues = append(issues, OuterJoinIssue{Pos: eq.Pos, Reason: "条件中存在不能识别的值，无法确定它是否有(+)"})
+			continue
+		}
+		if len(marked) == 0 {
+			keep = append(keep, eq)
+			continue
+		}
+		inner, deps, reason := outerJoinTables(sel.Table, eq.Equation, marked, other)
+		if reason != "" {
+			issues = append(issues, OuterJoinIssue{Pos: eq.Pos, Reason: reason})
+			continue
+		}
+		if joins[inner] == nil {
+			joins[inner] = &outerJoin{Deps: map[int]bool{}}
+		}
+		joins[inner].On = append(joins[inner].On, Equation{Equation: stripOuterJoin(eq.Equation), Connector: "AND", Pos: eq.Pos})
+		for _, dep := range deps {
+			joins[inner].Deps[dep] = true
+		}
+	}
+	if len(joins) == 0 && len(issues) == 0 {
+		return nil
+	}
+	if hasOr {
+		return []OuterJoinIssue{{Pos: sel.Where.Pos, Reason: "(+)条件不能和OR一起使用"}}
+	}
+	for i, table := range sel.Table {
+		if join := joins[i]; join != nil && len(join.Deps) == 0 {
+			issues = append(issues, OuterJoinIssue{Pos: sel.Where.Pos, Reason: "被外连接的表" + tableName(table) + "没有连接条件"})
+		}
+	}
+	if len(issues) != 0 {
+		return issues
+	}
+	//按依赖的顺序连接：被外连接的表所连接的表都已经连接好以后才能连接它，每组连在一起的表以其中在FROM里最靠前的表为准
+	tables := append([]SelectTable(nil), sel.Table...)
+	owner := make([]int, len(tables))
+	for i := range owner {
+		owner[i] = i
+	}
+	for len(joins) != 0 {
+		inner := -1
+		for i := range tables {
+			if join := joins[i]; join != nil && !dependsOnPending(join, joins) {
+				inner = i
+				break
+			}
+		}
+		if inner == -1 {
+			return []OuterJoinIssue{{Pos: sel.Where.Pos, Reason: "外连接的表之间存在循环"}}
+		}
+		join := joins[inner]
+		delete(joins, inner)
+		outer := -1
+		for dep := range join.Deps {
+			if outer == -1 {
+				outer = owner[dep]
+			} else if owner[dep] != outer {
+				return []OuterJoinIssue{{Pos: sel.Where.Pos, Reason: "被外连接的表" + tableName(sel.Table[inner]) + "连接的多张表之间没有连接关系"}}
+			}
+		}
+		join.On[0].Connector = ""
+		on := EquationList{Equation: join.On}
+		merged := Join{Left: tables[outer], Kind: "LEFT", Right: tables[inner], On: on}
+		first := outer
+		if inner < outer {
+			merged = Join{Left: tables[inner], Kind: "RIGHT", Right: tables[outer], On: on}
+			first = inner
+		}
+		tables[first] = SelectTable{Table: merged}
+		for i := range owner {
+			if owner[i] == inner || owner[i] == outer {
+				owner[i] = first
+			}
+		}
+	}
+	sel.Table = nil
+	for i, table := range tables {
+		if owner[i] == i {
+			sel.Table = append(sel.Table, table)
+		}
+	}
+	if len(keep) == 0 {
+		sel.Where = EquationList{}
+	} else {
+		keep[0].Connector = ""
+		sel.Where.Equation = keep
+	}
+	return nil
+}
+
+// dependsOnPending 被外连接的表是否连接了还没有完成外连接的表
+func dependsOnPending(join *outerJoin, joins map[int]*outerJoin) bool {
+	for dep := range join.Deps {
+		if joins[dep] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// outerJoinTables 找出(+)条件中被外连接的表，以及它连接的表，返回的是表在FROM中的下标，不能转换时返回原因
+func outerJoinTables(tables []SelectTable, eq interface{}, marked, other []ColumnRef) (inner int, deps []int, reason string) {
+	switch v := eq.(type) {
+	case EquationList:
+		return 0, nil, "(+)不能出现在被括号括起的条件中"
+	case EquationOther:
+		if strings.HasSuffix(v.Operator, "IN") {
+			return 0, nil, "(+)不能用于IN条件"
+		}
+	case InSubquery:
+		return 0, nil, "(+)不能用于IN条件"
+	case Quantified:
+		return 0, nil, "(+)不能用于ANY、SOME、ALL条件"
+	case Not:
+		return 0, nil, "(+)不能出现在NOT条件中"
+	case PredicateFunction:
+		return 0, nil, "(+)不能用于" + v.Name
+	}
+	inner = -1
+	for _, col := range marked {
+		if col.Table == "" {
+			return 0, nil, "(+)字段" + col.Column + "没有表名前缀，无法确定被外连接的表"
+		}
+		index, reason := findTable(tables, col.Table)
+		if reason != "" {
+			return 0, nil, reason
+		}
+		if inner != -1 && inner != index {
+			return 0, nil, "同一个条件中的(+)字段属于多张表"
+		}
+		inner = index
+	}
+	for _, col := range other {
+		if col.Table == "" {
+			return 0, nil, "字段" + col.Column + "没有表名前缀，无法确定连接的表"
+		}
+		index, reason := findTable(tables, col.Table)
+		if reason != "" {
+			return 0, nil, reason
+		}
+		if index != inner {
+			deps = append(deps, index)
+		}
+	}
+	return inner, deps, ""
+}
+
+// findTable 按表名或别名找出FROM中逗号隔开的表
+func findTable(tables []SelectTable, name string) (index int, reason string) {
+	index = -1
+	for i, table := range tables {
+		if strings.EqualFold(tableName(table), name) {
+			if index != -1 {
+				return -1, "表" + name + "不唯一"
+			}
+			index = i
+		}
+	}
+	if index == -1 {
+		return -1, "找不到表" + name + "，它可能在表连接或者外层查询中"
+	}
+	return index, ""
+}
+
+// tableName 表在查询中的名称，有别名时是别名，否则是不带前缀的表名
+func tableName(table SelectTable) string {
+	if table.Alias != "" {
+		return table.Alias
+	}
+	if name, ok := table.Table.(string); ok {
+		return name[strings.LastIndex(name, ".")+1:]
+	}
+	return ""
+}
+
+// equationValues 条件中的全部值，被括号括起的条件也包括在内
+func equationValues(eq interface{}) (values []Value) {
+	switch v := eq.(type) {
+	case EquationNorm:
+		values = append(values, v.Left, v.Right)
+	case EquationBetween:
+		values = append(values, v.Field, v.Left, v.Right)
+	case EquationOther:
+		values = append(values, v.Left)
+		values = append(values, v.Right...)
+		values = append(values, v.Escape)
+	case Quantified:
+		values = append(values, v.Left)
+		values = append(values, v.List...)
+	case InSubquery:
+		values = append(values, v.Left)
+	case Not:
+		values = append(values, equationValues(v.Equation)...)
+	case PredicateFunction:
+		values = append(values, v.Params...)
+		for _, item := range v.Passing {
+			values = append(values, item.Value)
+		}
+		values = append(values, equationValues(v.Condition)...)
+	case EquationList:
+		for _, item := range v.Equation {
+			values = append(values, equationValues(item.Equation)...)
+		}
+	}
+	return values
+}
+
+// walkColumnRefs 依次处理值中的字段，不进入子查询，子查询中的字段属于子查询自己的表。值中有不能识别的类型时返回false
+func walkColumnRefs(value Value, fn func(col ColumnRef)) bool {
+	switch v := value.Value.(type) {
+	case ColumnRef:
+		fn(v)
+	case Value:
+		return walkColumnRefs(v, fn)
+	case BinaryExpr:
+		return walkColumnRefs(v.Left, fn) && walkColumnRefs(v.Right, fn)
+	case UnaryExpr:
+		return walkColumnRefs(v.Operand, fn)
+	case ParenExpr:
+		return walkColumnRefs(v.Expr, fn)
+	case RowValue:
+		return walkValuesColumnRefs(v.Items, fn)
+	case GroupingElement:
+		return walkValuesColumnRefs(v.Items, fn)
+	case Function:
+		if !walkValuesColumnRefs(v.Params, fn) {
+			return false
+		}
+		if v.Over != nil {
+			values := append([]Value(nil), v.Over.Partition...)
+			for _, item := range v.Over.Order {
+				values = append(values, item.Expr)
+			}
+			values = append(values, v.Over.Frame.Start.Offset, v.Over.Frame.End.Offset)
+			return walkValuesColumnRefs(values, fn)
+		}
+	case CaseWhen:
+		for _, item := range v.When {
+			if !walkValuesColumnRefs(equationValues(item.Equation), fn) || !walkColumnRefs(item.Value, fn) {
+				return false
+			}
+		}
+		return walkColumnRefs(v.Else, fn)
+	case Select, string, StringLiteral, NumberLiteral, NullLiteral, PseudoColumn, Star, Params, nil:
+	default:
+		return false
+	}
+	return true
+}
+
+func walkValuesColumnRefs(values []Value, fn func(col ColumnRef)) bool {
+	for _, item := range values {
+		if !walkColumnRefs(item, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripOuterJoin 去掉条件中的(+)，被括号括起的条件、CASE WHEN中的条件也会被处理
+func stripOuterJoin(eq interface{}) interface{} {
+	switch v := eq.(type) {
+	case EquationNorm:
+		v.Left = stripOuterJoinValue(v.Left)
+		v.Right = stripOuterJoinValue(v.Right)
+		return v
+	case EquationBetween:
+		v.Field = stripOuterJoinValue(v.Field)
+		v.Left = stripOuterJoinValue(v.Left)
+		v.Right = stripOuterJoinValue(v.Right)
+		return v
+	case EquationOther:
+		v.Left = stripOuterJoinValue(v.Left)
+		v.Right = stripOuterJoinValues(v.Right)
+		v.Escape = stripOuterJoinValue(v.Escape)
+		return v
+	case Quantified:
+		v.Left = stripOuterJoinValue(v.Left)
+		v.List = stripOuterJoinValues(v.List)
+		return v
+	case InSubquery:
+		v.Left = stripOuterJoinValue(v.Left)
+		return v
+	case Not:
+		v.Equation = stripOuterJoin(v.Equation)
+		return v
+	case PredicateFunction:
+		v.Params = stripOuterJoinValues(v.Params)
+		passing := make([]PassingItem, len(v.Passing))
+		for i, item := range v.Passing {
+			passing[i] = item
+			passing[i].Value = stripOuterJoinValue(item.Value)
+		}
+		v.Passing = passing
+		v.Condition = stripOuterJoinList(v.Condition)
+		return v
+	case EquationList:
+		return stripOuterJoinList(v)
+	}
+	return eq
+}
+
+func stripOuterJoinList(eqList EquationList) EquationList {
+	if len(eqList.Equation) == 0 {
+		return eqList
+	}
+	equations := make([]Equation, len(eqList.Equation))
+	for i, item := range eqList.Equation {
+		equations[i] = item
+		equations[i].Equation = stripOuterJoin(item.Equation)
+	}
+	eqList.Equation = equations
+	return eqList
+}
+
+func stripOuterJoinValues(values []Value) []Value {
+	if len(values) == 0 {
+		return values
+	}
+	ret := make([]Value, len(values))
+	for i, item := range values {
+		ret[i] = stripOuterJoinValue(item)
+	}
+	return ret
+}
+
+func stripOuterJoinValue(value Value) Value {
+	switch v := value.Value.(type) {
+	case ColumnRef:
+		v.OuterJoin = false
+		value.Value = v
+	case Value:
+		value.Value = stripOuterJoinValue(v)
+	case BinaryExpr:
+		v.Left = stripOuterJoinValue(v.Left)
+		v.Right = stripOuterJoinValue(v.Right)
+		value.Value = v
+	case UnaryExpr:
+		v.Operand = stripOuterJoinValue(v.Operand)
+		value.Value = v
+	case ParenExpr:
+		v.Expr = stripOuterJoinValue(v.Expr)
+		value.Value = v
+	case RowValue:
+		v.Items = stripOuterJoinValues(v.Items)
+		value.Value = v
+	case GroupingElement:
+		v.Items = stripOuterJoinValues(v.Items)
+		value.Value = v
+	case Function:
+		v.Params = stripOuterJoinValues(v.Params)
+		if v.Over != nil {
+			over := *v.Over
+			over.Partition = stripOuterJoinValues(over.Partition)
+			order := make([]OrderItem, len(over.Order))
+			for i, item := range over.Order {
+				order[i] = item
+				order[i].Expr = stripOuterJoinValue(item.Expr)
+			}
+			over.Order = order
+			over.Frame.Start.Offset = stripOuterJoinValue(over.Frame.Start.Offset)
+			over.Frame.End.Offset = stripOuterJoinValue(over.Frame.End.Offset)
+			v.Over = &over
+		}
+		value.Value = v
+	case CaseWhen:
+		when := make([]CaseWhenItem, len(v.When))
+		for i, item := range v.When {
+			when[i] = item
+			when[i].Equation = stripOuterJoinList(item.Equation)
+			when[i].Value = stripOuterJoinValue(item.Value)
+		}
+		v.When = when
+		v.Else = stripOuterJoinValue(v.Else)
+		value.Value = v
+	}
+	return value
+}
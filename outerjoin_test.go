@@ -0,0 +1,185 @@
+package sqlParser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConvertOuterJoins(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"select a.x, b.y from a, b where a.id = b.id(+) and a.z = 1", "SELECT a.x,b.y FROM a LEFT JOIN b ON a.id=b.id WHERE a.z=1"},
+		{"select a.x, b.y from a, b where a.id(+) = b.id", "SELECT a.x,b.y FROM a RIGHT JOIN b ON a.id=b.id"},
+		//被外连接的表上的常量条件也放进ON
+		{"select a.x from a, b where a.id = b.id(+) and b.k(+) = 1 and a.z = 2", "SELECT a.x FROM a LEFT JOIN b ON a.id=b.id AND b.k=1 WHERE a.z=2"},
+		{"select a.x from a, b where a.id = b.id(+) and b.z(+) between 1 and 3", "SELECT a.x FROM a LEFT JOIN b ON a.id=b.id AND b.z BETWEEN 1 AND 3"},
+		//连续的外连接按连接关系排列，普通的表保留在逗号后面
+		{"select a.x from a, b, c where a.id = b.id(+) and b.id = c.id(+)", "SELECT a.x FROM a LEFT JOIN b ON a.id=b.id LEFT JOIN c ON b.id=c.id"},
+		{"select a.x from a, b, c where a.id = b.id(+) and a.k = c.k", "SELECT a.x FROM a LEFT JOIN b ON a.id=b.id,c WHERE a.k=c.k"},
+		//同一张表的不同别名按别名区分
+		{"select t1.x from tab t1, tab t2 where t1.id = t2.pid(+)", "SELECT t1.x FROM tab t1 LEFT JOIN tab t2 ON t1.id=t2.pid"},
+		//CASE WHEN、函数里的字段也要找出来，(+)被去掉
+		{
+			"select a.x from a, b where b.id(+) = case when a.t = 1 then a.id else a.pid end",
+			"SELECT a.x FROM a LEFT JOIN b ON b.id=CASE WHEN a.t=1 THEN a.id ELSE a.pid END",
+		},
+		{"select a.x from a, b where case when b.t(+) = 1 then b.id(+) end = a.id", "SELECT a.x FROM a LEFT JOIN b ON CASE WHEN b.t=1 THEN b.id END=a.id"},
+		{"select a.x from a, b where b.id(+) = nvl(a.id, a.pid)", "SELECT a.x FROM a LEFT JOIN b ON b.id=nvl(a.id,a.pid)"},
+		{"select x from (select a.x from a, b where a.id = b.id(+)) t", "SELECT x FROM (SELECT a.x FROM a LEFT JOIN b ON a.id=b.id) t"},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		if issues := stmt.ConvertOuterJoins(); len(issues) != 0 {
+			t.Errorf("%q: issues %v", tt.sql, issues)
+		}
+		if got, err := Marshal(stmt); err != nil || got != tt.want {
+			t.Errorf("%q: got %q, %v, want %q", tt.sql, got, err, tt.want)
+		}
+	}
+}
+
+// TestConvertOuterJoinsIssues 不能转换的单查询保持不变，问题的位置是条件或者整个WHERE
+func TestConvertOuterJoinsIssues(t *testing.T) {
+	tests := []struct {
+		sql    string
+		reason string
+		column int
+	}{
+		{"select a.x from a, b where a.id = b.id(+) or a.z = 1", "(+)条件不能和OR一起使用", 28},
+		{"select a.x from a, b where b.id(+) in (1, 2)", "(+)不能用于IN条件", 28},
+		{"select a.x from a, b where b.id(+) = 1", "被外连接的表b没有连接条件", 28},
+		{"select a.x from a, b where a.id(+) = b.id and b.id(+) = a.id", "外连接的表之间存在循环", 28},
+		{"select a.x from a, b, c where a.id = c.id(+) and b.id = c.id2(+)", "被外连接的表c连接的多张表之间没有连接关系", 31},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		issues := stmt.ConvertOuterJoins()
+		if len(issues) != 1 || issues[0].Reason != tt.reason || issues[0].Pos.Column != tt.column {
+			t.Errorf("%q: issues %+v, want %q at column %d", tt.sql, issues, tt.reason, tt.column)
+		}
+		if got, _ := Marshal(stmt); got != remarshal(t, tt.sql) {
+			t.Errorf("%q: changed to %q", tt.sql, got)
+		}
+	}
+}
+
+// TestConvertOuterJoinsNested 字段、条件、分组、排序里的子查询也要转换，子查询自己的问题也要返回
+func TestConvertOuterJoinsNested(t *testing.T) {
+	tests := []struct {
+		position string
+		sql      string
+		want     string
+		issues   []string
+	}{
+		{"select list", "select (select max(c.v) from b, c where b.id = c.id(+)) m from t",
+			"SELECT (SELECT max(c.v) FROM b LEFT JOIN c ON b.id=c.id) m FROM t", nil},
+		{"function param", "select a from t where nvl((select max(c.v) from b, c where b.id = c.id(+)), 0) = 1",
+			"SELECT a FROM t WHERE nvl((SELECT max(c.v) FROM b LEFT JOIN c ON b.id=c.id),0)=1", nil},
+		{"having", "select a from t group by a having count(*) > (select count(*) from b, c where b.id(+) = c.id)",
+			"SELECT a FROM t GROUP BY a HAVING count(*)>(SELECT count(*) FROM b RIGHT JOIN c ON b.id=c.id)", nil},
+		{"order by", "select a from t order by (select max(c.v) from b, c where b.id = c.id(+))",
+			"SELECT a FROM t ORDER BY (SELECT max(c.v) FROM b LEFT JOIN c ON b.id=c.id)", nil},
+		{"exists", "select a from t where exists (select 1 from b, c where b.id = c.id(+))",
+			"SELECT a FROM t WHERE EXISTS(SELECT 1 FROM b LEFT JOIN c ON b.id=c.id)", nil},
+		{"not exists", "select a from t where not exists (select 1 from b, c where b.id(+) = c.id)",
+			"SELECT a FROM t WHERE NOT EXISTS(SELECT 1 FROM b RIGHT JOIN c ON b.id=c.id)", nil},
+		{"in subquery", "select a from t where t.x in (select b.x from b, c where b.id = c.id(+))",
+			"SELECT a FROM t WHERE t.x IN(SELECT b.x FROM b LEFT JOIN c ON b.id=c.id)", nil},
+		{"quantified", "select a from t where t.x > all (select b.x from b, c where b.id = c.id(+))",
+			"SELECT a FROM t WHERE t.x>ALL(SELECT b.x FROM b LEFT JOIN c ON b.id=c.id)", nil},
+		{"join on", "select a from t join s on exists (select 1 from b, c where b.id = c.id(+))",
+			"SELECT a FROM t INNER JOIN s ON EXISTS(SELECT 1 FROM b LEFT JOIN c ON b.id=c.id)", nil},
+		{"not", "select a from t where not (t.x in (select b.x from b, c where b.id = c.id(+)))",
+			"SELECT a FROM t WHERE NOT (t.x IN(SELECT b.x FROM b LEFT JOIN c ON b.id=c.id))", nil},
+		//外层和子查询各自转换，子查询中引用外层的字段不影响外层
+		{"correlated", "select a.x from a, b where a.id = b.id(+) and exists (select 1 from c, d where c.id = d.id(+) and c.k = a.k)",
+			"SELECT a.x FROM a LEFT JOIN b ON a.id=b.id WHERE EXISTS(SELECT 1 FROM c LEFT JOIN d ON c.id=d.id WHERE c.k=a.k)", nil},
+		//不能转换的子查询保持不变，返回它的问题
+		{"nested issue", "select a from t where exists (select 1 from b, c where b.id = c.id(+) or b.z = 1)",
+			"SELECT a FROM t WHERE EXISTS(SELECT 1 FROM b,c WHERE b.id=c.id(+) OR b.z=1)", []string{"(+)条件不能和OR一起使用"}},
+		{"nested issue in select list", "select (select c.v from b, c where c.id(+) = 1) m from t",
+			"SELECT (SELECT c.v FROM b,c WHERE c.id(+)=1) m FROM t", []string{"被外连接的表c没有连接条件"}},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.position, err)
+		}
+		var reasons []string
+		for _, issue := range stmt.ConvertOuterJoins() {
+			reasons = append(reasons, issue.Reason)
+		}
+		if !reflect.DeepEqual(reasons, tt.issues) {
+			t.Errorf("%s: issues %q, want %q", tt.position, reasons, tt.issues)
+		}
+		if got, err := Marshal(stmt); err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.position, got, err, tt.want)
+		}
+	}
+}
+
+func TestConvertOuterJoinsUnknownValue(t *testing.T) {
+	stmt, err := Unmarshal("select a.x from a, b where a.id = b.id(+)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sel := stmt.Ast.(Select)
+	item := sel.Body.(SelectItem)
+	eq := item.Where.Equation[0].Equation.(EquationNorm)
+	eq.Left = Value{Value: 1}
+	item.Where.Equation[0].Equation = eq
+	sel.Body = item
+	stmt.Ast = sel
+	if issues := stmt.ConvertOuterJoins(); len(issues) != 1 {
+		t.Errorf("issues %v, want 1", issues)
+	}
+}
+
+func TestConvertedOuterJoinParams(t *testing.T) {
+	testParams(t, []paramsCase{
+		{
+			"select a.x from a, b where a.id = b.id(+) and b.k(+) in (:p) and a.z = :q",
+			[]string{":p", ":q"},
+			":p", "SELECT a.x FROM a,b WHERE a.id=b.id(+) AND a.z=:q",
+			":p", "SELECT a.x FROM a,b WHERE a.id=b.id(+) AND b.k(+) IN(:p0,:p1,:p2) AND a.z=:q",
+		},
+	})
+	//转换成ANSI连接以后，参数跟着条件进入ON
+	stmt, err := Unmarshal("select a.x from a, b where a.id = b.id(+) and b.k(+) = :p and a.z in (:q)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := stmt.ConvertOuterJoins(); len(issues) != 0 {
+		t.Fatalf("issues %v", issues)
+	}
+	stmt.DeleteParams([]Params{{Name: ":p"}})
+	stmt.ExpandParams(Params{Name: ":q"}, 3)
+	if got, err := Marshal(stmt); err != nil || got != "SELECT a.x FROM a LEFT JOIN b ON a.id=b.id WHERE a.z IN(:q0,:q1,:q2)" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func TestOuterJoinSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select a.x from a, b where b.id(+) (+) = a.id", ErrInvalidComparison, 36},
+		{"select a.x from a, b where b.id(-) = a.id", ErrUnexpectedToken, 34},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+}
@@ -2,16 +2,26 @@ package sqlParser
 
 import (
 	"errors"
-	"fmt"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // Value SQL的值，它可以是子查询、函数、CASE WHEN表达式、字符串、数字（应当包括加减乘除等运算）、字段TableField（即不被括号括起来的，包含了像SYSDATE这样的关键词）、参数、被双竖线连接的值组合；它可以出现在：查询的字段、条件语句的左右值、新增/更新语句的值
 type Value struct {
-	Value interface{} //它可以是Function、CaseWhen、String、Number、TableField、Params、ConcatValue
+	Value    interface{} //它可以是Select、Function、CaseWhen、StringLiteral、NumberLiteral、NullLiteral、ColumnRef、PseudoColumn、BinaryExpr、UnaryExpr、ParenExpr、RowValue、Params
+	Pos      Pos
+	Comments Comments
+}
+
+// Pos 语法树节点在原SQL中的位置：Start、End是字节偏移，可以用Statement.Text取出原文；Line、Column是Start所在的行和列，都从1开始，列按字符计算
+type Pos struct {
+	Start  int
+	End    int
+	Line   int
+	Column int
 }
 
 //TableField 它可以是字段(可能被双引号括起)、参数；它可以出现在新增、更新的字段上。
@@ -19,10 +29,11 @@ type Value struct {
 //	Field	interface{}		//它可以是字符串（包含了双引号的）、Params
 //}
 
-// Equation 等式，它可以是 “左值 符号（>、>=、<、<=、=） 右值”，“Between 值 and 值”，“值 IS (NOT)? NULL”，“值 (NOT)? LIKE 值”，“值 (NOT)? IN(值...)”，“值 (NOT)? EXIST(值...)”。它们之间需使用AND/OR连接
+// Equation 等式，它可以是 “左值 符号（>、>=、<、<=、=、<>、!=、^=、~=） 右值”，“值 (NOT)? BETWEEN 值 AND 值”，“值 IS (NOT)? NULL|NAN|INFINITE”，“值 (NOT)? LIKE|LIKEC|LIKE2|LIKE4 值 (ESCAPE 值)?”，“值 (NOT)? IN(值...)”，“值 (NOT)? IN(子查询)”，“(NOT)? EXISTS(子查询)”，“值 符号 ANY|SOME|ALL(子查询)”，“NOT 条件”，“REGEXP_LIKE(值...)”这样返回布尔值的函数。它们之间需使用AND/OR连接
 type Equation struct {
-	Equation  interface{} //它可以是EquationNorm、EquationOther、EquationBetween, EquationList
+	Equation  interface{} //它可以是EquationNorm、EquationOther、EquationBetween、Exists、Quantified、InSubquery、Not、PredicateFunction、EquationList
 	Connector string      //连接符只能是AND、OR两种
+	Pos       Pos         //不包含连接符
 }
 
 // EquationNorm 标准等式，即左值 符号 右值
@@ -30,31 +41,45 @@ type EquationNorm struct {
 	Left     Value
 	Right    Value
 	Operator string
+	Pos      Pos
 }
 
 type EquationBetween struct {
 	Left  Value
 	Right Value
 	Field Value
+	Not   bool //NOT BETWEEN
+	Pos   Pos
 }
 
 // EquationOther 其他等式
 type EquationOther struct {
 	Left     Value
-	Operator string  //它可以是IS (NOT)?   (NOT)? (LIKE)|(IN)|(EXIST)
+	Operator string  //它可以是IS (NOT)? (NULL)|(NAN)|(INFINITE)   (NOT)? (LIKE)|(LIKEC)|(LIKE2)|(LIKE4)|(IN)
 	Right    []Value //如果是NULL的，直接就是字符串NULL，其他的则需要括号表示
+	Escape   Value   //LIKE的ESCAPE字符，没有时Value为nil
+	Pos      Pos
 }
 
 // EquationList 多个条件可以被小括号括起
 type EquationList struct {
 	Equation  []Equation
 	Connector string
+	Pos       Pos //被括号括起时包含括号
 }
 
 // Function 函数：函数必须是一个函数名，外加参数组成的，参数里面的值可以是0个或多个
 type Function struct {
-	Name   string
-	Params []Value
+	Name       string
+	Quantifier string //参数前面的DISTINCT、UNIQUE、ALL，没有时为空，例：COUNT(DISTINCT ID)
+	Params     []Value
+	Over       *WindowSpec //分析函数的窗口，不是分析函数时为nil
+	Pos        Pos
+}
+
+// Star 函数的*参数，例：COUNT(*)
+type Star struct {
+	Pos Pos
 }
 
 /*
@@ -67,12 +92,14 @@ case when 值=值 then 值 else 值 end;
 type CaseWhen struct {
 	When []CaseWhenItem
 	Else Value
+	Pos  Pos
 }
 
 // CaseWhenItem case when表达式的单个条件项
 type CaseWhenItem struct {
 	Equation EquationList
 	Value    Value
+	Pos      Pos
 }
 
 //String 被单引号括起的部分
@@ -80,20 +107,71 @@ type CaseWhenItem struct {
 //	Value		string
 //}
 
-// Number 数字，它可以是单数字，也可以是值+-*/值
-type Number struct {
-	Number []NumberItem
+// BinaryExpr 二元运算，Op是+、-、*、/、||，例：A+B*C解析成A+(B*C)
+type BinaryExpr struct {
+	Left  Value
+	Op    string
+	Right Value
+	Pos   Pos
 }
 
-// NumberItem 数字的单个选项
-type NumberItem struct {
-	Value    Value
-	Operator string
+// UnaryExpr 一元运算，Op是+、-，以及层次查询的PRIOR、CONNECT_BY_ROOT，例：-A、PRIOR ID；紧跟在正负号后面的数字直接解析成带符号的NumberLiteral
+type UnaryExpr struct {
+	Op      string
+	Operand Value
+	Pos     Pos
+}
+
+// ParenExpr 原SQL中被括号括起的表达式，生成SQL时保留括号
+type ParenExpr struct {
+	Expr Value
+	Pos  Pos
 }
 
 // Params 参数，即冒号开头的参数占位符
 type Params struct {
 	Name string
+	Pos  Pos
+}
+
+// StringLiteral 字符串，例：'abc'、N'中文'
+type StringLiteral struct {
+	Value string //去掉引号、转义后的内容
+	Raw   string //原文，包含引号，生成SQL时优先使用它
+	Pos   Pos
+}
+
+// NumberLiteral 数字，例：12、1.5、1E-5，紧跟在正负号后面的数字包含符号，例：-5
+type NumberLiteral struct {
+	Value string //原文
+	Pos   Pos
+}
+
+// NullLiteral NULL
+type NullLiteral struct {
+	Pos Pos
+}
+
+// ColumnRef 字段，例：NAME、T.NAME、SCHEMA.T.NAME、T.*，被双引号括起的部分保留引号
+type ColumnRef struct {
+	Schema    string
+	Table     string
+	Column    string
+	OuterJoin bool //Oracle的外连接符(+)，例：B.ID(+)，Pos包含它
+	Pos       Pos
+}
+
+// PseudoColumn 伪列，例：SYSDATE、ROWNUM、LEVEL、ROWID，Name保持原SQL中的写法
+type PseudoColumn struct {
+	Name string
+	Pos  Pos
+}
+
+// pseudoColumns 被解析成PseudoColumn的单词
+var pseudoColumns = map[string]bool{
+	"SYSDATE": true, "SYSTIMESTAMP": true, "CURRENT_DATE": true, "CURRENT_TIMESTAMP": true, "LOCALTIMESTAMP": true,
+	"ROWNUM": true, "ROWID": true, "LEVEL": true, "USER": true, "UID": true,
+	"CONNECT_BY_ISLEAF": true, "CONNECT_BY_ISCYCLE": true,
 }
 
 //ConcatValue 竖线连接的值
@@ -101,176 +179,170 @@ type Params struct {
 //	Value		[]Value
 //}
 
+// OrderBy 排序，每个排序项有自己的排序方式，例：ORDER BY A DESC, 2, NAME NULLS FIRST
 type OrderBy struct {
-	Value     []Value
-	Collation string
+	Item     []OrderItem
+	Siblings bool //层次查询的ORDER SIBLINGS BY
+	Pos      Pos
 }
 
 type Statement struct {
-	Ast interface{}
+	Ast         interface{}
+	Source      string            //被解析的原SQL，语法树各节点的Pos都是相对它的
+	Diagnostics []*ParseError     //恢复模式下解析时遇到的全部错误
+	Span        Pos               //语句在脚本中的位置，单独解析时是整个SQL
+	keywords    map[string]string //原SQL中关键词的写法，大写的关键词->原写法，用于KeywordAsWritten
 }
 
-// Select 一个完整的SQL语句应该可由多个单查询组合而成，加上像UNION等关键词进行合并
+// Select 完整的查询：WITH子句加上查询体，查询体可以是单查询，也可以是用UNION等集合运算组合的多个查询
 type Select struct {
-	Select []SelectItem
+	With  With        //WITH子句，没有时Item为空
+	Body  interface{} //它可以是SelectItem、SetOperation，以及被括号括起的查询Select
+	Order interface{} //集合运算整体的排序，它可以是OrderBy、Function(Order Decode)；单查询的排序在SelectItem上
+	Limit Limit       //集合运算整体的限制行数，Style为空时没有
+	Pos   Pos
+}
+
+// With WITH子句，即公用表表达式，例：WITH T(ID, PID) AS (SELECT ...) SELECT * FROM T
+type With struct {
+	Item     []WithItem
+	Pos      Pos
+	Comments Comments //WITH关键词前面的注释
+}
+
+// WithItem WITH子句中的单个命名子查询，递归查询可以带有SEARCH、CYCLE子句
+type WithItem struct {
+	Name     string
+	Field    []string //字段列表，可以省略，递归查询必须有
+	Query    Select
+	Search   WithSearch
+	Cycle    WithCycle
+	Pos      Pos
+	Comments Comments
+}
+
+// WithSearch 递归查询的SEARCH子句，例：SEARCH DEPTH FIRST BY ID SET SEQ，Mode为空时没有该子句
+type WithSearch struct {
+	Mode string //DEPTH、BREADTH
+	By   OrderBy
+	Set  string
+	Pos  Pos
+}
+
+// WithCycle 递归查询的CYCLE子句，例：CYCLE ID SET IS_CYCLE TO 'Y' DEFAULT 'N'，Field为空时没有该子句
+type WithCycle struct {
+	Field   []string
+	Set     string
+	To      Value
+	Default Value
+	Pos     Pos
 }
 
 type SelectItem struct {
+	Distinct  string //SELECT后面的DISTINCT、UNIQUE、ALL，没有时为空
 	Field     []SelectField
 	Table     []SelectTable
 	Where     EquationList
+	StartWith EquationList //层次查询的START WITH条件
+	ConnectBy ConnectBy    //层次查询的CONNECT BY子句
 	Group     []Value
 	Having    EquationList
-	Order     interface{} //它可以是[]Value(Order By)、Function(Order Decode)
-	Aggregate string      //集合关键词：union、union all、minus、intersect
+	Window    []NamedWindow //WINDOW子句的命名窗口
+	Order     interface{}   //它可以是OrderBy{Item []OrderItem}(Order By、Order Siblings By)、Function(Order Decode)
+	Limit     Limit         //限制返回的行数，Style为空时没有
+	ForUpdate ForUpdate     //加锁读，Mode为空时没有
+	Pos       Pos
+	Comments  Comments //SQL开头、集合关键词后面的注释是单查询的Leading
 }
 
 type SelectField struct {
-	Field Value
-	Alias string
+	Field    Value
+	Alias    string
+	Pos      Pos
+	Comments Comments
 }
 
 type SelectTable struct {
-	Table   interface{}  //它可以是子查询，也可以是字符串
-	Alias   string       //别名
-	JoinKey string       //如果这张表是join前面的表，则会有关键词，它可以是JOIN、LEFT JOIN、RIGHT JOIN、INNER JOIN
-	JoinOn  EquationList //一个条件列，它可以被括号括起来
-}
-
-type Placeholder struct {
-	Value string
-	Name  string //所有占位符的名称都应该是$序号，例$000001
+	Table    interface{} //它可以是字符串、子查询，也可以是表连接Join
+	Alias    string      //别名
+	Lateral  bool        //LATERAL子查询
+	Pivot    *Pivot      //跟在表后面的PIVOT、UNPIVOT子句，没有时为nil
+	Pos      Pos
+	Comments Comments //表连接的SelectTable没有注释，注释在连接两边的表上
 }
 
 type Insert struct {
-	Table  string
-	Field  []string
-	Values interface{} //它可以时[]Value，或者Select。
+	Table    string
+	Field    []string
+	Values   interface{} //它可以时[]Value，或者Select。
+	Pos      Pos
+	Comments Comments
 }
 
 type UpdateValueItem struct {
-	Field string
-	Value Value
+	Field    string
+	Value    Value
+	Pos      Pos
+	Comments Comments
 }
 
 type Update struct {
-	Table string
-	Value []UpdateValueItem
-	Where EquationList
+	Table    string
+	Value    []UpdateValueItem
+	Where    EquationList
+	Pos      Pos
+	Comments Comments
+}
+
+// ConnectBy 层次查询的CONNECT BY子句，例：CONNECT BY NOCYCLE PRIOR ID = PID，Condition为空时没有该子句
+type ConnectBy struct {
+	NoCycle   bool
+	Condition EquationList
+	Pos       Pos
+}
+
+// ErrorNode 恢复模式下不能解析的区域，它会出现在字段、表、条件、分组、排序、SET值的位置上，生成SQL时原样输出Text；
+// 脚本中解析失败的语句，语法树也是覆盖整条语句的ErrorNode
+type ErrorNode struct {
+	Text string
+	Err  *ParseError
+	Pos  Pos
 }
 
 // Delete 删除数据的时候，可能会有FROM关键词，为了兼容以前的ORACLE，生成SQL的时候带上FROM
 type Delete struct {
-	Table string
-	Where EquationList
+	Table    string
+	Where    EquationList
+	Pos      Pos
+	Comments Comments
 }
 
 // removeExtraSpaces 清除多余的空格
 func removeExtraSpaces(s string) string {
-	//将多个空格、制表符、换行符替换成一个空格
-	reg, _ := regexp.Compile("[ \t\n]+")
-	str := reg.ReplaceAllString(s, " ")
-	return str
-}
-
-// placeholderByString 将一段SQL中可以替换成占位符的字符串替换成占位符
-func placeholderByString(s string, placeholder *[]Placeholder, placeholderPos *int) (string, error) {
-	s = strings.TrimSpace(s)
-	//替换单引号括起的内容
-	s = replacePlaceholder(s, `'(?:[^']|'')*'`, placeholder, placeholderPos)
-	//替换双引号
-	s = replacePlaceholder(s, "\".*?\"", placeholder, placeholderPos)
-	//替换反单引号
-	s = replacePlaceholder(s, "`.*?`", placeholder, placeholderPos)
-	//替换参数
-	s = replacePlaceholder(s, `:\w*`, placeholder, placeholderPos)
-	//转大写
-	s = strings.ToUpper(s)
-	//左括号左侧要空格，右侧去空格
-	s = strings.ReplaceAll(s, "(", " (")
-	s = strings.ReplaceAll(s, "( ", "(")
-	//右括号左侧去空格，右侧要空格
-	s = strings.ReplaceAll(s, ")", ") ")
-	s = strings.ReplaceAll(s, " )", ")")
-	//删除多余的空格
-	s = removeExtraSpaces(s)
-	//从里到外，把括号的内容替换成占位符
-	var err error
-	s, err = replaceParenthesis(s, placeholder, placeholderPos)
-	return s, err
-}
-
-// replacePlaceholder 用正则表达式替换占位符
-func replacePlaceholder(s string, regStr string, placeholder *[]Placeholder, placeholderPos *int) string {
-	re := regexp.MustCompile(regStr)
-	matches := re.FindAllString(s, -1)
-	strs := re.Split(s, -1)
-	var ret string
-	for i := 0; i < len(strs); i++ {
-		ret += strs[i]
-		if len(matches) > i {
-			name := fmt.Sprintf("$%06d", *placeholderPos)
-			*placeholderPos++
-			*placeholder = append(*placeholder, Placeholder{Name: name, Value: matches[i]})
-			ret += name
-		}
-	}
-	return ret
-}
-
-// replaceParenthesis 把有小括号的替换成占位符，要求从里到外所有的括号依次替换
-func replaceParenthesis(s string, placeholder *[]Placeholder, placeholderPos *int) (string, error) {
-	//利用栈的原理去处理
-	var leftStack []int
-	for index, c := range s {
-		if c == '(' {
-			leftStack = append(leftStack, index)
-		} else if c == ')' {
-			//需要替换这一段的数据
-			if len(leftStack) > 0 {
-				name := fmt.Sprintf("$%06d", *placeholderPos)
-				*placeholderPos++
-				*placeholder = append(*placeholder, Placeholder{Name: name, Value: s[leftStack[len(leftStack)-1] : index+1]})
-				s = s[:leftStack[len(leftStack)-1]] + name + s[index+1:]
-				return replaceParenthesis(s, placeholder, placeholderPos)
+	tokens, err := Tokenize(s)
+	if err != nil {
+		//将多个空格、制表符、换行符替换成一个空格
+		reg, _ := regexp.Compile("[ \t\n]+")
+		return reg.ReplaceAllString(s, " ")
+	}
+	//按词法单元处理，字符串、注释里面的空格保持不变，行注释后面保留换行
+	var sb strings.Builder
+	last := 0
+	for i, tok := range tokens {
+		if tok.Start > last {
+			if i > 0 && tokens[i-1].Type == TokenComment && strings.HasPrefix(tokens[i-1].Value, "--") {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString(" ")
 			}
-			return "", errors.New("缺失左括号")
-		}
-	}
-	if len(leftStack) > 0 {
-		return "", errors.New("缺失右括号")
-	}
-	return s, nil
-}
-
-// getPlaceholder 传入一个字符串，返回被占位符替代还原后的字符串和占位符列表
-func getPlaceholder(s string, placeholder *[]Placeholder, placeholderPos *int) (retStr string, retPlace []Placeholder, err error) {
-	//按$取出所有参数
-	re := regexp.MustCompile(`\$[0-9]+`)
-	matches := re.FindAllString(s, -1)
-	for _, item := range matches {
-		idx, err := strconv.Atoi(item[1:])
-		if err != nil {
-			return "", nil, errors.New("获取占位符失败")
 		}
-		s = strings.ReplaceAll(s, item, (*placeholder)[idx].Value)
-		retPlace = append(retPlace, Placeholder{Name: item, Value: (*placeholder)[idx].Value})
+		sb.WriteString(tok.Value)
+		last = tok.End
 	}
-	return s, retPlace, err
-}
-
-// getSqlType 获取SQL的语法类型
-func getSqlType(s string, placeholder *[]Placeholder, placeholderPos *int) (string, error) {
-	strs := strings.Split(s, " ")
-	if strs[0][0] == '$' {
-		//占位符，说明多有是被括号括起的
-		newStr, _, err := getPlaceholder(s, placeholder, placeholderPos)
-		if err != nil {
-			return "", err
-		}
-		return getSqlType(newStr, placeholder, placeholderPos)
+	if last < len(s) {
+		sb.WriteString(" ")
 	}
-	return strs[0], nil
+	return sb.String()
 }
 
 // trimLR 同时满足左右两边都有的情况下才会去掉
@@ -291,960 +363,1600 @@ func trimLR(s, l, r string) string {
 	return s
 }
 
-// splitSQLAggregate 如果一个SQL中存在集合关键词(union、union all、minus、intersect)，则需要拆分成多个单条SQL
-func splitSQLAggregate(s string) ([]string, []string) {
-	re := regexp.MustCompile(`( UNION ALL )|( UNION )|( MINUS )|( INTERSECT )`)
-	return re.Split(s, -1), re.FindAllString(s, -1)
-}
-
-// parserSelect 对一个完整的查询SQL进行解析，返回Select的语法树
-func parserSelect(s string, placeholder *[]Placeholder, placeholderPos *int) (sel Select, err error) {
-	//先把集合分开，然后在处理单个查询
-	sqls, keys := splitSQLAggregate(s)
-	for idx, item := range sqls {
-		selItem, err := parserSelectItem(item, placeholder, placeholderPos)
+// reservedWords 保留的关键词，它们不能作为别名，也不能作为值出现
+var reservedWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "BY": true,
+	"UNION": true, "MINUS": true, "INTERSECT": true, "JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true,
+	"ON": true, "AND": true, "OR": true, "NOT": true, "IS": true, "IN": true, "LIKE": true, "BETWEEN": true,
+	"CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true, "AS": true, "SET": true,
+	"VALUES": true, "INTO": true, "ASC": true, "DESC": true, "WITH": true, "WINDOW": true, "FOR": true, "EXCEPT": true,
+}
+
+// parser 语法分析器，它按顺序消费词法分析器输出的词法单元
+type parser struct {
+	src    string
+	tokens []Token //不包含注释，最后一个一定是TokenEOF
+	pos    int
+	lines  []int //每一行开始的字节偏移
+	parens []int //每个词法单元前面还没有闭合的左括号数量，多余的右括号不计入
+	//最远的解析错误，回溯尝试失败时真正的错误通常在更远处
+	furthest *ParseError
+	//恢复模式，出错后记录错误并跳到下一个子句继续解析
+	recover     bool
+	diagnostics []*ParseError
+	//错误信息使用的语言
+	locale Locale
+	//注释，以及按归属顺序记录的已归属注释的下标，用于回溯
+	comments []commentInfo
+	claimed  []int
+	//被当作关键词消费的单词在原SQL中的写法，大写的关键词->原写法，按KeywordAsWritten生成SQL时使用
+	keywords map[string]string
+}
+
+// parserState 回溯时需要恢复的语法分析器状态
+type parserState struct {
+	pos         int
+	diagnostics int
+	claimed     int
+}
+
+// save 保存语法分析器状态，用于回溯
+func (p *parser) save() parserState {
+	return parserState{pos: p.pos, diagnostics: len(p.diagnostics), claimed: len(p.claimed)}
+}
+
+// restore 回溯到保存的状态，回溯期间记录的错误、归属的注释都被撤销
+func (p *parser) restore(state parserState) {
+	p.pos = state.pos
+	p.diagnostics = p.diagnostics[:state.diagnostics]
+	for _, i := range p.claimed[state.claimed:] {
+		p.comments[i].used = false
+	}
+	p.claimed = p.claimed[:state.claimed]
+}
+
+// newParser 对SQL进行词法分析，返回语法分析器。恢复模式下，词法错误会被记录，出错的部分作为TokenIllegal交给语法分析
+func newParser(s string, opts ParseOptions) (*parser, error) {
+	p := &parser{src: s, recover: opts.Recover, locale: opts.Locale, keywords: make(map[string]string)}
+	lex := NewLexer(s)
+	for {
+		tok, err := lex.Next()
 		if err != nil {
-			return Select{}, err
+			perr, ok := err.(*ParseError)
+			if ok {
+				perr.Locale = opts.Locale
+			}
+			if !opts.Recover || !ok {
+				return nil, err
+			}
+			p.diagnostics = append(p.diagnostics, perr)
+			tok = perr.Token
+			tok.Type = TokenIllegal
+		}
+		if tok.Type == TokenEOF {
+			break
+		}
+		if tok.Type == TokenComment {
+			p.addComment(tok)
+		} else {
+			p.tokens = append(p.tokens, tok)
 		}
-		if idx > 0 {
-			selItem.Aggregate = keys[idx-1]
+	}
+	p.tokens = append(p.tokens, Token{Type: TokenEOF, Start: len(s), End: len(s)})
+	depth := 0
+	for _, tok := range p.tokens {
+		p.parens = append(p.parens, depth)
+		if tok.Type == TokenPunct && tok.Value == "(" {
+			depth++
+		} else if tok.Type == TokenPunct && tok.Value == ")" && depth > 0 {
+			depth--
 		}
-		sel.Select = append(sel.Select, selItem)
 	}
-	return sel, nil
+	p.lines = append(p.lines, 0)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\n' {
+			p.lines = append(p.lines, i+1)
+		}
+	}
+	return p, nil
 }
 
-// parserSelectItem 对单查询的SQL进行解析，返回单查询的语法树
-func parserSelectItem(s string, placeholder *[]Placeholder, placeholderPos *int) (sel SelectItem, err error) {
-	//如果查询是被括号包裹的，先去掉外层括号
-	if s[0] == '$' {
-		s, _, err = getPlaceholder(s, placeholder, placeholderPos)
-		if err != nil {
-			return SelectItem{}, err
+// position 计算一段原SQL的位置
+func (p *parser) position(start, end int) Pos {
+	line := sort.Search(len(p.lines), func(i int) bool {
+		return p.lines[i] > start
+	})
+	column := utf8.RuneCountInString(p.src[p.lines[line-1]:start]) + 1
+	return Pos{Start: start, End: end, Line: line, Column: column}
+}
+
+// posFrom 返回从字节偏移start开始，到上一个被消费的词法单元结束的位置
+func (p *parser) posFrom(start int) Pos {
+	end := start
+	if p.pos > 0 && p.tokens[p.pos-1].End > start {
+		end = p.tokens[p.pos-1].End
+	}
+	return p.position(start, end)
+}
+
+func (p *parser) peek() Token {
+	return p.peekN(0)
+}
+
+func (p *parser) peekN(n int) Token {
+	if p.pos+n < len(p.tokens) {
+		return p.tokens[p.pos+n]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() Token {
+	tok := p.peek()
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+	return tok
+}
+
+// isKeywordAt 判断从第n个词法单元开始，是否依次是这些关键词
+func (p *parser) isKeywordAt(n int, words ...string) bool {
+	for i, w := range words {
+		tok := p.peekN(n + i)
+		if tok.Type != TokenIdent || !strings.EqualFold(tok.Value, w) {
+			return false
 		}
-		s = strings.TrimSpace(s)
-		s = strings.TrimLeft(s, "(")
-		s = strings.TrimRight(s, ")")
-		return parserSelectItem(s, placeholder, placeholderPos)
 	}
-	s = strings.TrimSpace(s)
-	s = trimLR(s, "(", ")")
-	s = strings.TrimSpace(s)
-	//按关键词分割语句
-	selKeyword, err := splitSqlByKeywordForSelect(s)
-	if err != nil {
-		return SelectItem{}, err
+	return true
+}
+
+func (p *parser) isKeyword(words ...string) bool {
+	return p.isKeywordAt(0, words...)
+}
+
+// isAnyKeyword 当前词法单元是否是words中的任意一个关键词
+func (p *parser) isAnyKeyword(words ...string) bool {
+	for _, w := range words {
+		if p.isKeyword(w) {
+			return true
+		}
 	}
-	//解析字段
-	sel.Field, err = getSelectField(selKeyword.Select, placeholder, placeholderPos)
-	if err != nil {
-		return SelectItem{}, err
+	return false
+}
+
+func (p *parser) acceptKeyword(words ...string) bool {
+	if !p.isKeyword(words...) {
+		return false
 	}
-	sel.Table, err = getSelectTable(selKeyword.From, placeholder, placeholderPos)
-	if err != nil {
-		return SelectItem{}, err
+	for range words {
+		p.keyword()
 	}
-	sel.Where, err = getEquationList(selKeyword.Where, placeholder, placeholderPos)
-	if err != nil {
-		return SelectItem{}, err
+	return true
+}
+
+// keyword 把当前词法单元当作关键词消费，并记录它在原SQL中的写法
+func (p *parser) keyword() Token {
+	tok := p.next()
+	word := strings.ToUpper(tok.Value)
+	if _, ok := p.keywords[word]; !ok {
+		p.keywords[word] = tok.Value
 	}
-	sel.Group, err = getSelectGroup(selKeyword.Group, placeholder, placeholderPos)
-	if err != nil {
-		return SelectItem{}, err
+	return tok
+}
+
+func (p *parser) expectKeyword(words ...string) error {
+	if !p.acceptKeyword(words...) {
+		return p.fail(ErrMissingKeyword, strings.Join(words, " "))
 	}
-	sel.Having, err = getEquationList(selKeyword.Having, placeholder, placeholderPos)
-	if err != nil {
-		return SelectItem{}, err
+	return nil
+}
+
+// isReserved 当前词法单元是否是保留的关键词
+func (p *parser) isReserved() bool {
+	tok := p.peek()
+	return tok.Type == TokenIdent && reservedWords[strings.ToUpper(tok.Value)]
+}
+
+func (p *parser) isPunct(s string) bool {
+	tok := p.peek()
+	return tok.Type == TokenPunct && tok.Value == s
+}
+
+func (p *parser) acceptPunct(s string) bool {
+	if p.isPunct(s) {
+		p.next()
+		return true
 	}
-	sel.Order, err = getSelectOrder(selKeyword.Order, placeholder, placeholderPos)
-	if err != nil {
-		return SelectItem{}, err
+	return false
+}
+
+func (p *parser) expectPunct(s string) error {
+	if p.acceptPunct(s) {
+		return nil
+	}
+	switch s {
+	case ")":
+		return p.fail(ErrMissingRightParen, s)
+	case "(":
+		return p.fail(ErrMissingLeftParen, s)
+	default:
+		return p.fail(ErrMissingPunct, s)
 	}
-	return sel, nil
 }
 
-// getTable 传入被查询的表，返回表的结构体，即：表 别名
-func getTable(s string, placeholder *[]Placeholder, placeholderPos *int) (table SelectTable, err error) {
-	strs := strings.Split(s, " ")
-	if len(strs) == 2 {
-		retStr, retPlace, err := getPlaceholder(strs[1], placeholder, placeholderPos)
-		if err != nil {
-			return SelectTable{}, err
-		}
-		table.Alias = retStr
-		retStr, retPlace, err = getPlaceholder(strs[0], placeholder, placeholderPos)
-		if len(retPlace) > 0 && retStr[:1] != "\"" && retStr[:1] != "`" {
-			table.Table, err = parserSelect(retStr, placeholder, placeholderPos)
-			if err != nil {
-				return SelectTable{}, err
-			}
-		} else {
-			table.Table = retStr
-		}
-	} else if len(strs) == 1 {
-		retStr, retPlace, err := getPlaceholder(strs[0], placeholder, placeholderPos)
-		if len(retPlace) > 0 && retStr[:1] != "\"" && retStr[:1] != "`" {
-			table.Table, err = parserSelect(strs[0], placeholder, placeholderPos)
-			if err != nil {
-				return SelectTable{}, err
-			}
-		} else {
-			table.Table = retStr
+func (p *parser) isOperator(ops ...string) bool {
+	tok := p.peek()
+	if tok.Type != TokenOperator {
+		return false
+	}
+	for _, op := range ops {
+		if tok.Value == op {
+			return true
 		}
-	} else {
-		return SelectTable{}, errors.New("不正确的表")
 	}
-	return table, nil
+	return false
 }
 
-type JoinString struct {
-	Table   string
-	Keyword string
-	On      string
-}
-
-// splitJoin 提取出join的部分，传入的字符串应确认有JOIN的存在。返回的应该是JOIN前边的表、JOIN本身、ON后面的部分
-func splitJoin(s string) (rets []JoinString) {
-	strs := strings.Split(s, " ")
-	var tab, word, on string
-	var bOn, bJoin bool
-	for _, item := range strs {
-		if item == "LEFT" || item == "RIGHT" || item == "INNER" {
-			bJoin = true
-			bOn = false
-			//保存上一次的
-			if tab != "" || word != "" || on != "" {
-				rets = append(rets, JoinString{Table: strings.TrimSpace(tab), Keyword: strings.TrimSpace(word), On: strings.TrimSpace(on)})
-			}
-			word = ""
-			tab = ""
-			word += item + " "
-		} else if item == "JOIN" {
-			bOn = false
-			//保存上一次的
-			if !bJoin && (tab != "" || word != "" || on != "") {
-				rets = append(rets, JoinString{Table: strings.TrimSpace(tab), Keyword: strings.TrimSpace(word), On: strings.TrimSpace(on)})
-			}
-			tab = ""
-			word += "JOIN"
-		} else if item == "ON" {
-			bOn = true
-			on = ""
-		} else {
-			if bOn {
-				on += item + " "
-			} else {
-				tab += item + " "
-			}
-		}
+// isSelectStart 判断从第n个词法单元开始是否是一个查询，查询可能被多层括号括起，也可能以WITH开头
+func (p *parser) isSelectStart(n int) bool {
+	for p.peekN(n).Type == TokenPunct && p.peekN(n).Value == "(" {
+		n++
 	}
-	if tab != "" || word != "" || on != "" {
-		rets = append(rets, JoinString{Table: strings.TrimSpace(tab), Keyword: strings.TrimSpace(word), On: strings.TrimSpace(on)})
+	return p.isKeywordAt(n, "SELECT") || p.isKeywordAt(n, "WITH")
+}
+
+// fail 返回当前词法单元处的解析错误，expected是期望出现的词法单元
+func (p *parser) fail(code ErrorCode, expected ...string) error {
+	err := newParseError(p.src, code, p.peek(), expected...)
+	err.Locale = p.locale
+	if p.furthest == nil || err.Pos.Start > p.furthest.Pos.Start {
+		p.furthest = err
 	}
-	return rets
+	return err
 }
 
-// getSelectOrder 解析Order排序
-func getSelectOrder(s string, placeholder *[]Placeholder, placeholderPos *int) (order interface{}, err error) {
-	//它可能是ORDER BY 各值；DECODE函数自定义排序
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return nil, nil
+// furthestError 在错误之外，返回回溯时记录的更远的解析错误
+func (p *parser) furthestError(err error) error {
+	if perr, ok := err.(*ParseError); ok && p.furthest != nil && p.furthest.Pos.Start > perr.Pos.Start {
+		return p.furthest
 	}
-	strType, err := getSqlType(s, placeholder, placeholderPos)
-	if err != nil {
-		return nil, err
+	return err
+}
+
+// clauseWords 子句边界的关键词，恢复模式下出错后跳到这些关键词处继续解析
+var clauseWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true,
+	"UNION": true, "MINUS": true, "INTERSECT": true, "EXCEPT": true, "SET": true, "VALUES": true, "WINDOW": true,
+}
+
+// isClauseEnd 当前词法单元是否是子句的边界：子句关键词、分号、闭合前面左括号的右括号或者SQL结尾。
+// 多余的右括号不是边界，恢复模式下它被当作错误的内容跳过
+func (p *parser) isClauseEnd() bool {
+	tok := p.peek()
+	switch tok.Type {
+	case TokenEOF:
+		return true
+	case TokenPunct:
+		return tok.Value == ";" || tok.Value == ")" && p.parens[p.pos] > 0
+	case TokenIdent:
+		return clauseWords[strings.ToUpper(tok.Value)] || p.isClauseStart()
 	}
-	if strType == "BY" {
-		var orderBy OrderBy
-		s = strings.Replace(s, "BY ", "", 1)
-		nPos := strings.LastIndex(s, " DESC")
-		if nPos != -1 {
-			//说明排序是倒序
-			orderBy.Collation = "DESC"
-			s = strings.TrimRight(s, "DESC")
-		} else {
-			nPos = strings.LastIndex(s, "ASC")
-			orderBy.Collation = "ASC"
-			s = strings.TrimRight(s, "ASC")
+	return false
+}
+
+// isClauseStart 当前词法单元是否是由非保留关键词开始的子句：START WITH、CONNECT BY、限制行数子句、加锁读子句，这些关键词不能被当作别名
+func (p *parser) isClauseStart() bool {
+	return p.isKeyword("START", "WITH") || p.isKeyword("CONNECT", "BY") || p.isLimitStart() || p.isForUpdateStart()
+}
+
+// diagnose 恢复模式下记录错误，同一位置只记录一次
+func (p *parser) diagnose(perr *ParseError) {
+	p.furthest = nil
+	for _, item := range p.diagnostics {
+		if item.Pos.Start == perr.Pos.Start {
+			return
 		}
-		orderBy.Value, err = getSelectGroup(s, placeholder, placeholderPos)
-		return orderBy, err
-	} else if strType == "DECODE" {
-		return getValue(s, placeholder, placeholderPos)
-	} else {
-		return nil, errors.New("未能识别的排序规则" + strType)
 	}
+	p.diagnostics = append(p.diagnostics, perr)
 }
 
-// getSelectGroup 解析分组
-func getSelectGroup(s string, placeholder *[]Placeholder, placeholderPos *int) (groups []Value, err error) {
-	s = strings.TrimSpace(s)
-	if s == "" {
+// endClause 结束一个从第start个词法单元开始的子句。
+// 非恢复模式下原样返回err；恢复模式下子句出错或没有停在子句边界时，记录错误并跳到下一个子句边界，返回标记被跳过区域的错误节点
+func (p *parser) endClause(err error, start int) (*ErrorNode, error) {
+	if err == nil && (!p.recover || p.isClauseEnd()) {
 		return nil, nil
 	}
-	items := strings.Split(s, ",")
-	for _, item := range items {
-		val, err := getValue(item, placeholder, placeholderPos)
-		if err != nil {
-			return nil, err
+	if err == nil {
+		err = p.unexpected()
+	}
+	perr, ok := p.furthestError(err).(*ParseError)
+	if !p.recover || !ok {
+		return nil, err
+	}
+	p.diagnose(perr)
+	//从子句开头重新扫描，跳过括号里的内容，停在错误位置之后的第一个子句边界
+	p.pos = start
+	depth := 0
+	for p.peek().Type != TokenEOF {
+		if depth == 0 && p.peek().Start >= perr.Pos.Start && p.isClauseEnd() {
+			break
 		}
-		groups = append(groups, val)
+		if p.isPunct("(") {
+			depth++
+		} else if p.isPunct(")") && depth > 0 {
+			depth--
+		}
+		p.next()
 	}
-	return groups, nil
+	node := &ErrorNode{Err: perr, Pos: p.posFrom(p.tokens[start].Start)}
+	node.Text = p.src[node.Pos.Start:node.Pos.End]
+	return node, nil
 }
 
-// getSelectTable 解析查询语句的表，里面包含了JOIN部分
-func getSelectTable(s string, placeholder *[]Placeholder, placeholderPos *int) (tables []SelectTable, err error) {
-	//查询语句的表以逗号隔开
-	items := strings.Split(s, ",")
-	for _, item := range items {
-		var table SelectTable
-		item = strings.TrimSpace(item)
-		//判断表是不是存在JOIN
-		joinRe := regexp.MustCompile(`( LEFT )|( RIGHT )|( INNER )|( JOIN )|( ON )`)
-		joinFind := joinRe.FindAllString(item, -1)
-		if len(joinFind) == 0 {
-			//没有join的时候
-			table, err = getTable(item, placeholder, placeholderPos)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			var tabs []SelectTable
-			//有join的时候
-			joins := splitJoin(item)
-			for _, join := range joins {
-				var tab SelectTable
-				tab.Table, err = getTable(join.Table, placeholder, placeholderPos)
-				if err != nil {
-					return nil, err
-				}
-				//以上取得表名
-				if join.Keyword != "" {
-					tab.JoinKey = join.Keyword
-					tab.JoinOn, err = getEquationList(join.On, placeholder, placeholderPos)
-				}
-				tabs = append(tabs, tab)
-			}
-			table.Table = tabs
-		}
-		tables = append(tables, table)
+// unexpected 返回遇到不能解析的词法单元时的错误
+func (p *parser) unexpected(expected ...string) error {
+	if p.peek().Type == TokenEOF {
+		return p.fail(ErrUnexpectedEOF, expected...)
 	}
-	return tables, nil
+	return p.fail(ErrUnexpectedToken, expected...)
 }
 
-// getSelectField 解析查询语句的字段部分
-func getSelectField(s string, placeholder *[]Placeholder, placeholderPos *int) (fields []SelectField, err error) {
-	items := strings.Split(s, ",")
-	for _, item := range items {
-		//按空格拆分
-		item = strings.TrimSpace(item)
-		fs := strings.Split(item, " ")
-		var field SelectField
-		switch len(fs) {
-		case 1:
-			//一定是字段本身
-			field.Field, err = getValue(fs[0], placeholder, placeholderPos)
-			if err != nil {
-				return nil, err
-			}
-		default:
-			//看看最后一个是不是别名
-			var alias string
-			alias, _, err = getPlaceholder(fs[len(fs)-1], placeholder, placeholderPos)
-			if alias[0] == '\'' || alias[0] == '`' || fs[len(fs)-1][0] != '$' {
-				//是别名
-				field.Alias = alias
-				pos := strings.LastIndex(item, fs[len(fs)-1])
-				field.Field, err = getValue(item[:pos], placeholder, placeholderPos)
-				if err != nil {
-					return nil, err
-				}
-			} else {
-				field.Field, err = getValue(item, placeholder, placeholderPos)
-			}
-		}
-		fields = append(fields, field)
+// getSqlType 获取SQL的语法类型，即第一个关键词，查询可能会被括号括起
+func getSqlType(p *parser) string {
+	n := 0
+	for p.peekN(n).Type == TokenPunct && p.peekN(n).Value == "(" {
+		n++
 	}
-	return fields, nil
-}
-
-// getValue 解析成Value SQL的值，它可以是子查询、函数、CASE WHEN表达式、字符串、数字（应当包括加减乘除等运算）、字段TableField（即不被括号括起来的，包含了像SYSDATE这样的关键词）、参数、被双竖线连接的值组合；它可以出现在：查询的字段、条件语句的左右值、新增/更新语句的值
-func getValue(s string, placeholder *[]Placeholder, placeholderPos *int) (value Value, err error) {
-	s = strings.TrimSpace(s)
-	//如果不是子查询，才会生效这个连接符，因为当整个值是一个子查询的话，那么里面的双竖线就是子查询里面的
-	if strings.Index(s, "SELECT ") != 0 {
-		//首先需要用||分割开
-		items := strings.Split(s, "||")
-		if len(items) > 1 {
-			//说明它是用竖线连接的字符串
-			var retVal []Value
-			for _, item := range items {
-				item = strings.TrimSpace(item)
-				val, err := getValue(item, placeholder, placeholderPos)
-				if err != nil {
-					return Value{}, err
-				}
-				retVal = append(retVal, val)
-			}
-			value.Value = retVal
-			return value, nil
-		}
+	tok := p.peekN(n)
+	if tok.Type != TokenIdent {
+		return ""
 	}
-	//只有一项，则看是什么东西
-	numItems := getNumberItemKeyword(s)
-	if len(numItems) > 1 {
-		//说明是有加减乘除运算的
-		var retVal Number
-		for _, item := range numItems {
-			item.Value = strings.TrimSpace(item.Value)
-			val, err := getValue(item.Value, placeholder, placeholderPos)
-			if err != nil {
-				return Value{}, err
-			}
-			retVal.Number = append(retVal.Number, NumberItem{Value: val, Operator: item.Operator})
+	return strings.ToUpper(tok.Value)
+}
+
+// getObjectName 解析对象名称，例：表名、字段名，它可能带有模式名和DBLINK，例：SCHEMA.TABLE@LINK
+func getObjectName(p *parser) (name string, err error) {
+	for {
+		tok := p.peek()
+		if (tok.Type != TokenIdent || p.isReserved()) && tok.Type != TokenQuotedIdent {
+			return "", p.fail(ErrMissingName)
 		}
-		value.Value = retVal
-		return value, nil
+		name += p.next().Value
+		if !p.acceptPunct(".") {
+			break
+		}
+		name += "."
 	}
-	//如果该项是单值，则可能是子查询、函数、CASE WHEN表达式、参数、普通字符串
-	strs := strings.Split(s, " ")
-	switch len(strs) {
-	case 1:
-		//只有一项的时候，它可能是普通字符串、子查询、参数
-		retStr, retPlace, err := getPlaceholder(strs[0], placeholder, placeholderPos)
+	if p.acceptPunct("@") {
+		link, err := getObjectName(p)
 		if err != nil {
-			return Value{}, err
-		}
-		if len(retPlace) == 0 {
-			//说明是普通字符串
-			value.Value = retStr
-		} else if len(retPlace) == 1 {
-			if retStr[0] == ':' {
-				//说明是参数
-				value.Value = Params{Name: retStr}
-			} else if retStr[0] == '\'' || retStr[0] == '"' || retStr[0] == '`' {
-				value.Value = retStr
-			} else {
-				//说明是子查询，或者是被括号括起的表达式
-				strs[0] = trimLR(retPlace[0].Value, "(", ")")
-				strType, err := getSqlType(strs[0], placeholder, placeholderPos)
-				if err != nil {
-					return Value{}, err
-				}
-				if strType == "SELECT" {
-					value.Value, err = parserSelect(strs[0], placeholder, placeholderPos)
-					if err != nil {
-						return Value{}, err
-					}
-				} else {
-					value.Value, err = getValue(strs[0], placeholder, placeholderPos)
-					if err != nil {
-						return Value{}, err
-					}
-				}
-			}
-		} else {
-			return Value{}, errors.New("SQL值可能有误")
+			return "", err
 		}
-	case 2:
-		//两项的时候，那他一定是函数
-		value.Value, err = getFunction(strs[0], strs[1], placeholder, placeholderPos)
+		name += "@" + link
+	}
+	return name, nil
+}
+
+// getName 解析不带前缀的名称，例：WITH子查询名、字段名
+func getName(p *parser) (string, error) {
+	tok := p.peek()
+	if (tok.Type != TokenIdent || p.isReserved()) && tok.Type != TokenQuotedIdent {
+		return "", p.fail(ErrMissingName)
+	}
+	return p.next().Value, nil
+}
+
+// getNameList 解析逗号隔开的名称列表
+func getNameList(p *parser) (names []string, err error) {
+	for {
+		name, err := getName(p)
 		if err != nil {
-			return Value{}, err
+			return nil, err
 		}
-	default:
-		//其他情况应该看看是不是case when
-		if strs[0] == "CASE" {
-			value.Value, err = getCaseWhen(s, placeholder, placeholderPos)
-			if err != nil {
-				return Value{}, err
-			}
-		} else if strs[0] == "SELECT" {
-			value.Value, err = parserSelect(s, placeholder, placeholderPos)
-			if err != nil {
-				return Value{}, err
-			}
-		} else {
-			return Value{}, errors.New("SQL值存在不能解析的元素")
+		names = append(names, name)
+		if !p.acceptPunct(",") {
+			return names, nil
 		}
 	}
-	return value, nil
 }
 
-// getFunction 解析函数的部分
-func getFunction(name, params string, placeholder *[]Placeholder, placeholderPos *int) (f Function, err error) {
-	nameStr, _, err := getPlaceholder(name, placeholder, placeholderPos)
-	if err != nil {
-		return Function{}, err
-	}
-	f.Name = nameStr
-	paramsStr, _, err := getPlaceholder(params, placeholder, placeholderPos)
-	//去掉首尾括号
-	paramsStr = strings.TrimSpace(paramsStr)
-	paramsStr = strings.TrimLeft(paramsStr, "(")
-	paramsStr = strings.TrimRight(paramsStr, ")")
-	paramsStr = strings.TrimSpace(paramsStr)
-	//按逗号取出里面的参数值
-	strs := strings.Split(paramsStr, ",")
-	for _, item := range strs {
-		val, err := getValue(item, placeholder, placeholderPos)
-		if err != nil {
-			return Function{}, err
+// getAlias 解析别名，别名前面的AS可以省略，没有别名时返回空串
+func getAlias(p *parser) (string, error) {
+	if p.acceptKeyword("AS") {
+		tok := p.peek()
+		if (tok.Type != TokenIdent || p.isReserved()) && tok.Type != TokenQuotedIdent && tok.Type != TokenString {
+			return "", p.fail(ErrMissingAlias)
 		}
-		f.Params = append(f.Params, val)
+		return p.next().Value, nil
 	}
-	return f, nil
+	tok := p.peek()
+	if (tok.Type == TokenIdent && p.isAliasWord()) || tok.Type == TokenQuotedIdent || tok.Type == TokenString {
+		return p.next().Value, nil
+	}
+	return "", nil
 }
 
-// replaceBetweenItem 替换between and表达式为$BETWEEN
-func replaceBetweenItem(s string) (newStr string, betStr string, err error) {
-	newStr = ""
-	betStr = ""
-	err = errors.New("未知错误")
-	nBet := strings.Index(s, " BETWEEN ")
-	if nBet == -1 {
-		return s, betStr, nil
+// isAliasWord 省略AS时，当前的单词是否可以是别名：它不能是保留的关键词，也不能是子句、表连接的开始
+func (p *parser) isAliasWord() bool {
+	if p.isReserved() || p.isClauseStart() || p.isJoinStart() || p.isPivotStart() {
+		return false
 	}
-	//存在between
-	nStart := strings.LastIndex(s[:nBet], " AND ")
-	if nStart == -1 {
-		nStart = strings.LastIndex(s[:nBet], " OR ")
-		if nStart != -1 {
-			nStart += 4
+	next := p.peekN(1)
+	return !p.isKeyword("USING") || next.Type != TokenPunct || next.Value != "("
+}
+
+// parserSelect 对一个完整的查询SQL进行解析，返回Select的语法树
+func parserSelect(p *parser) (sel Select, err error) {
+	start := p.peek().Start
+	if p.isKeyword("WITH") {
+		leading := p.leadingComments()
+		p.keyword()
+		if sel.With, err = getWith(p); err != nil {
+			return Select{}, err
 		}
-	} else {
-		nStart += 5
+		sel.With.Comments.Leading = leading
 	}
-	if nStart == -1 {
-		nStart = 0
+	if sel.Body, err = getSetOperation(p); err != nil {
+		return Select{}, err
 	}
-	nAnd := strings.Index(s[nBet:], " AND ")
-	if nAnd == -1 {
-		err = errors.New("BETWEEN表达式缺失AND关键词")
-		return
-	} else {
-		nAnd += nBet
+	if op, ok := sel.Body.(SetOperation); ok {
+		//最后一个单查询后面的排序、限制行数属于集合运算整体
+		if item, ok := op.Right.(SelectItem); ok {
+			sel.Order, sel.Limit = item.Order, item.Limit
+			item.Order, item.Limit = nil, Limit{}
+			op.Right = item
+			sel.Body = op
+		}
 	}
-	nEnd := strings.Index(s[nAnd+4:], " AND ")
-	if nEnd == -1 {
-		nEnd = strings.Index(s[nAnd+4:], " OR ")
-	} else {
-		nEnd += nAnd + 4
+	//被括号括起的查询后面的排序、限制行数
+	if _, ok := sel.Body.(SelectItem); !ok {
+		if p.acceptKeyword("ORDER") {
+			if sel.Order, err = getSelectOrder(p); err != nil {
+				return Select{}, err
+			}
+		}
+		if p.isLimitStart() {
+			if sel.Limit, err = getLimit(p); err != nil {
+				return Select{}, err
+			}
+		}
 	}
-	if nEnd == -1 {
-		nEnd = len(s)
+	if inner, ok := sel.Body.(Select); ok && sel.Order == nil && sel.Limit.Style == "" && (len(sel.With.Item) == 0 || len(inner.With.Item) == 0) {
+		//整个查询被括号括起时去掉括号
+		if len(inner.With.Item) == 0 {
+			inner.With = sel.With
+		}
+		sel = inner
 	}
-	betStr = s[nStart:nEnd]
-	newStr = s[:nStart] + "$BETWEEN" + s[nEnd:]
-	err = nil
-	return
+	sel.Pos = p.posFrom(start)
+	return sel, nil
 }
 
-func replaceBetween(s string) (newStr string, betStrs []string, err error) {
-	newStr = s
+// getWith 解析WITH子句，WITH关键词已被解析
+func getWith(p *parser) (with With, err error) {
+	start := p.tokens[p.pos-1].Start
 	for {
-		ns, bs, err := replaceBetweenItem(newStr)
+		item, err := getWithItem(p)
 		if err != nil {
-			return "", nil, err
+			return With{}, err
 		}
-		newStr = ns
-		if bs == "" {
-			break
+		with.Item = append(with.Item, item)
+		if !p.acceptPunct(",") {
+			with.Pos = p.posFrom(start)
+			return with, nil
 		}
-		betStrs = append(betStrs, bs)
 	}
-	return newStr, betStrs, nil
 }
 
-// getEquationList 解析条件部分
-func getEquationList(s string, placeholder *[]Placeholder, placeholderPos *int) (list EquationList, err error) {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return EquationList{}, nil
-	}
-	//BETWEEN AND先用占位符替换
-	//re := regexp.MustCompile(`\$?\w*((\|\|\$?\w*)+)? BETWEEN \$?\w*((\|\|\$?\w*)+)? AND \$?\w*((\|\|\$?\w*)+)?`)
-	//ph := re.FindAllString(s, -1)
-	//var phNum int
-	//s = re.ReplaceAllString(s, "$BETWEEN")
-	var phNum int
-	var ph []string
-	s, ph, err = replaceBetween(s)
-	if err != nil {
-		return EquationList{}, err
+// getWithItem 解析WITH子句中的单个命名子查询：名称 [(字段列表)] AS (查询) [SEARCH子句] [CYCLE子句]
+func getWithItem(p *parser) (item WithItem, err error) {
+	start := p.peek().Start
+	item.Comments.Leading = p.leadingComments()
+	if item.Name, err = getName(p); err != nil {
+		return WithItem{}, err
 	}
-	//用AND OR分割
-	resp := regexp.MustCompile(`( AND )|( OR )`)
-	matches := resp.FindAllString(s, -1)
-	strs := resp.Split(s, -1)
-	for idx, item := range strs {
-		item = strings.TrimSpace(item)
-		var equation Equation
-		if idx > 0 {
-			equation.Connector = strings.TrimSpace(matches[idx-1])
-		}
-		//item就是一个条件/一组条件。它可能有$BETWEEN，和括号括起来的条件组
-		if item == "$BETWEEN" {
-			//BETWEEN AND表达式
-			reBetween := regexp.MustCompile(`(BETWEEN)|(AND)`)
-			betVals := reBetween.Split(ph[phNum], -1)
-			phNum++
-			var eqBet EquationBetween
-			eqBet.Field, err = getValue(betVals[0], placeholder, placeholderPos)
-			if err != nil {
-				return EquationList{}, err
-			}
-			eqBet.Left, err = getValue(betVals[1], placeholder, placeholderPos)
-			if err != nil {
-				return EquationList{}, err
-			}
-			eqBet.Right, err = getValue(betVals[2], placeholder, placeholderPos)
-			if err != nil {
-				return EquationList{}, err
+	if p.acceptPunct("(") {
+		if item.Field, err = getNameList(p); err != nil {
+			return WithItem{}, err
+		}
+		if err = p.expectPunct(")"); err != nil {
+			return WithItem{}, err
+		}
+	}
+	if err = p.expectKeyword("AS"); err != nil {
+		return WithItem{}, err
+	}
+	if err = p.expectPunct("("); err != nil {
+		return WithItem{}, err
+	}
+	if item.Query, err = parserSelect(p); err != nil {
+		return WithItem{}, err
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return WithItem{}, err
+	}
+	if p.isKeyword("SEARCH") {
+		if item.Search, err = getWithSearch(p); err != nil {
+			return WithItem{}, err
+		}
+	}
+	if p.isKeyword("CYCLE") {
+		if item.Cycle, err = getWithCycle(p); err != nil {
+			return WithItem{}, err
+		}
+	}
+	item.Pos = p.posFrom(start)
+	item.Comments.Trailing = p.trailingComments()
+	return item, nil
+}
+
+// getWithSearch 解析SEARCH DEPTH|BREADTH FIRST BY 排序 SET 字段
+func getWithSearch(p *parser) (search WithSearch, err error) {
+	start := p.keyword().Start
+	switch {
+	case p.acceptKeyword("DEPTH"):
+		search.Mode = "DEPTH"
+	case p.acceptKeyword("BREADTH"):
+		search.Mode = "BREADTH"
+	default:
+		return WithSearch{}, p.fail(ErrMissingKeyword, "DEPTH", "BREADTH")
+	}
+	if err = p.expectKeyword("FIRST", "BY"); err != nil {
+		return WithSearch{}, err
+	}
+	if search.By, err = getOrderBy(p, p.tokens[p.pos-1].Start); err != nil {
+		return WithSearch{}, err
+	}
+	if err = p.expectKeyword("SET"); err != nil {
+		return WithSearch{}, err
+	}
+	if search.Set, err = getName(p); err != nil {
+		return WithSearch{}, err
+	}
+	search.Pos = p.posFrom(start)
+	return search, nil
+}
+
+// getWithCycle 解析CYCLE 字段列表 SET 字段 TO 值 DEFAULT 值
+func getWithCycle(p *parser) (cycle WithCycle, err error) {
+	start := p.keyword().Start
+	if cycle.Field, err = getNameList(p); err != nil {
+		return WithCycle{}, err
+	}
+	if err = p.expectKeyword("SET"); err != nil {
+		return WithCycle{}, err
+	}
+	if cycle.Set, err = getName(p); err != nil {
+		return WithCycle{}, err
+	}
+	if err = p.expectKeyword("TO"); err != nil {
+		return WithCycle{}, err
+	}
+	if cycle.To, err = getPrimary(p); err != nil {
+		return WithCycle{}, err
+	}
+	if err = p.expectKeyword("DEFAULT"); err != nil {
+		return WithCycle{}, err
+	}
+	if cycle.Default, err = getPrimary(p); err != nil {
+		return WithCycle{}, err
+	}
+	cycle.Pos = p.posFrom(start)
+	return cycle, nil
+}
+
+// parserSelectItem 对单查询的SQL进行解析，返回单查询的语法树
+func parserSelectItem(p *parser) (sel SelectItem, err error) {
+	start := p.peek().Start
+	sel.Comments.Leading = p.leadingComments()
+	if err = p.expectKeyword("SELECT"); err != nil {
+		return SelectItem{}, err
+	}
+	if p.isAnyKeyword("DISTINCT", "UNIQUE", "ALL") {
+		sel.Distinct = strings.ToUpper(p.keyword().Value)
+	}
+	//解析字段，每个子句都是恢复模式下的恢复点
+	clause := p.pos
+	sel.Field, err = getSelectField(p)
+	node, err := p.endClause(err, clause)
+	if err != nil {
+		return SelectItem{}, err
+	}
+	if node != nil {
+		sel.Field = []SelectField{{Field: Value{Value: *node, Pos: node.Pos}, Pos: node.Pos}}
+	}
+	if p.acceptKeyword("FROM") {
+		clause = p.pos
+		sel.Table, err = getSelectTable(p)
+		if node, err = p.endClause(err, clause); err != nil {
+			return SelectItem{}, err
+		}
+		if node != nil {
+			sel.Table = []SelectTable{{Table: *node, Pos: node.Pos}}
+		}
+	} else {
+		err = p.fail(ErrMissingKeyword, "FROM")
+		if !p.recover {
+			return SelectItem{}, err
+		}
+		p.diagnose(err.(*ParseError))
+	}
+	if p.acceptKeyword("WHERE") {
+		if sel.Where, err = getClauseEquationList(p); err != nil {
+			return SelectItem{}, err
+		}
+	}
+	//层次查询，START WITH可以在CONNECT BY的前面，也可以在后面，各自只能出现一次
+	hasStartWith, hasConnectBy := false, false
+	for !hasStartWith && p.isKeyword("START", "WITH") || !hasConnectBy && p.isKeyword("CONNECT", "BY") {
+		if p.acceptKeyword("START", "WITH") {
+			hasStartWith = true
+			if sel.StartWith, err = getClauseEquationList(p); err != nil {
+				return SelectItem{}, err
 			}
-			equation.Equation = eqBet
-			list.Equation = append(list.Equation, equation)
 			continue
 		}
-		newSql, placeList, err := getPlaceholder(item, placeholder, placeholderPos)
-		if err != nil {
-			return EquationList{}, err
+		hasConnectBy = true
+		start := p.keyword().Start
+		p.keyword()
+		sel.ConnectBy.NoCycle = p.acceptKeyword("NOCYCLE")
+		if sel.ConnectBy.Condition, err = getClauseEquationList(p); err != nil {
+			return SelectItem{}, err
 		}
-		if len(placeList) == 1 && placeList[0].Name == item {
-			equation.Equation, err = getEquationList(newSql, placeholder, placeholderPos)
-			if err != nil {
-				return EquationList{}, err
-			}
-		} else {
-			//正常的条件，有可能是各种符号，和IN、NOT IN、EXIST、NOT EXIST、LIKE、NOT LIKE等
-			//先处理常规比较符的
-			reNorm := regexp.MustCompile(`>|=|<|!`)
-			normOperators := reNorm.FindAllString(item, -1)
-			normStrs := reNorm.Split(item, -1)
-			if len(normOperators) > 0 {
-				if len(normStrs) != 2 {
-					return EquationList{}, errors.New("常规比较式需要有左值和右值")
-				}
-				//说明是常规的比较式
-				var eqNorm EquationNorm
-				eqNorm.Left, err = getValue(normStrs[0], placeholder, placeholderPos)
-				if err != nil {
-					return EquationList{}, err
-				}
-				eqNorm.Right, err = getValue(normStrs[1], placeholder, placeholderPos)
-				if err != nil {
-					return EquationList{}, err
-				}
-				for _, o := range normOperators {
-					eqNorm.Operator += o
-				}
-				equation.Equation = eqNorm
-			} else {
-				//可能是IN、NOT IN、EXIST、NOT EXIST、LIKE、NOT LIKE等
-				var eqOther EquationOther
-				reOther := regexp.MustCompile(`\bIN\b|\bNOT IN\b|\bLIKE\b|\bNOT LIKE\b|\bEXIST\b|\bNOT EXIST\b|\bIS NULL\b|\bIS NOT NULL\b`)
-				otherKeys := reOther.FindAllString(item, -1)
-				otherVals := reOther.Split(item, -1)
-				for _, o := range otherKeys {
-					eqOther.Operator += o + " "
-				}
-				eqOther.Operator = strings.TrimSpace(eqOther.Operator)
-				eqOther.Left, err = getValue(otherVals[0], placeholder, placeholderPos)
-				if err != nil {
-					return EquationList{}, err
-				}
-				//如果是IN/EXIST带括号的，可能有多个值，如果是LIKE，就一个值，NULL没有值
-				if strings.Index(eqOther.Operator, "IN") > -1 || strings.Index(eqOther.Operator, "EXIST") > -1 {
-					otherVals[1], _, _ = getPlaceholder(otherVals[1], placeholder, placeholderPos)
-					otherVals[1] = strings.TrimSpace(otherVals[1])
-					otherVals[1] = trimLR(otherVals[1], "(", ")")
-					wheres := strings.Split(otherVals[1], ",")
-					for _, whe := range wheres {
-						var itemVal Value
-						itemVal, err = getValue(whe, placeholder, placeholderPos)
-						if err != nil {
-							return EquationList{}, err
-						}
-						eqOther.Right = append(eqOther.Right, itemVal)
-					}
-				} else if strings.Index(eqOther.Operator, "LIKE") > -1 {
-					var itemVal Value
-					itemVal, err = getValue(otherVals[1], placeholder, placeholderPos)
-					eqOther.Right = append(eqOther.Right, itemVal)
-				}
-				equation.Equation = eqOther
-			}
+		sel.ConnectBy.Pos = p.posFrom(start)
+	}
+	if p.acceptKeyword("GROUP", "BY") {
+		clause = p.pos
+		sel.Group, err = getSelectGroup(p)
+		if node, err = p.endClause(err, clause); err != nil {
+			return SelectItem{}, err
+		}
+		if node != nil {
+			sel.Group = []Value{{Value: *node, Pos: node.Pos}}
+		}
+	}
+	if p.acceptKeyword("HAVING") {
+		if sel.Having, err = getClauseEquationList(p); err != nil {
+			return SelectItem{}, err
 		}
-		list.Equation = append(list.Equation, equation)
+	}
+	if p.acceptKeyword("WINDOW") {
+		if sel.Window, err = getNamedWindows(p); err != nil {
+			return SelectItem{}, err
+		}
+	}
+	if p.acceptKeyword("ORDER") {
+		clause = p.pos
+		sel.Order, err = getSelectOrder(p)
+		if node, err = p.endClause(err, clause); err != nil {
+			return SelectItem{}, err
+		}
+		if node != nil {
+			sel.Order = *node
+		}
+	}
+	if p.isLimitStart() {
+		if sel.Limit, err = getLimit(p); err != nil {
+			return SelectItem{}, err
+		}
+	}
+	if p.isForUpdateStart() {
+		if sel.ForUpdate, err = getForUpdate(p); err != nil {
+			return SelectItem{}, err
+		}
+	}
+	sel.Pos = p.posFrom(start)
+	sel.Comments.Trailing = p.trailingComments()
+	return sel, nil
+}
+
+// getClauseEquationList 解析WHERE、HAVING子句的条件，恢复模式下出错时条件列表只包含一个错误节点
+func getClauseEquationList(p *parser) (list EquationList, err error) {
+	clause := p.pos
+	list, err = getEquationList(p)
+	node, err := p.endClause(err, clause)
+	if err != nil {
+		return EquationList{}, err
+	}
+	if node != nil {
+		list = EquationList{Equation: []Equation{{Equation: *node, Pos: node.Pos}}, Pos: node.Pos}
 	}
 	return list, nil
 }
 
-// getCaseWhen 解析Case when表达式
-func getCaseWhen(s string, placeholder *[]Placeholder, placeholderPos *int) (cas CaseWhen, err error) {
-	s = strings.TrimSpace(s)
-	re := regexp.MustCompile(`\bCASE\b|\bWHEN\b|\bTHEN\b|\bELSE\b|\bEND\b`)
-	matches := re.FindAllString(s, -1)
-	strs := re.Split(s, -1)
-	//去掉strs的空串
-	var newStrs []string
-	for _, item := range strs {
-		if strings.TrimSpace(item) != "" {
-			newStrs = append(newStrs, strings.TrimSpace(item))
-		}
-	}
-	strs = newStrs
-	bCaseWhen := false
-	if strings.Index(s, "CASE WHEN") > -1 {
-		bCaseWhen = true
+// getTable 解析被查询的表，返回表的结构体，即：表 别名
+func getTable(p *parser) (table SelectTable, err error) {
+	start := p.peek().Start
+	table.Comments.Leading = p.leadingComments()
+	if p.isKeyword("LATERAL") && p.isSelectStart(1) {
+		p.keyword()
+		table.Lateral = true
 	}
-	var caseVal Value
-	var caseItem CaseWhenItem
-	var equationNorm EquationNorm
-	var equationList EquationList
-	bEnd := false
-	var idx int
-	for _, key := range matches {
-		switch key {
-		case "CASE":
-			if !bCaseWhen {
-				caseVal, err = getValue(strs[idx], placeholder, placeholderPos)
-				idx++
-				if err != nil {
-					return CaseWhen{}, err
-				}
+	if p.isPunct("(") && !p.isSelectStart(0) {
+		//被括号括起的表连接
+		p.next()
+		inner, err := getJoinedTable(p)
+		if err != nil {
+			return SelectTable{}, err
+		}
+		if err = p.expectPunct(")"); err != nil {
+			return SelectTable{}, err
+		}
+		table.Table = inner.Table
+		if _, ok := inner.Table.(Join); !ok {
+			//括号里只有一张表
+			table = inner
+		}
+	} else if p.acceptPunct("(") {
+		//子查询
+		table.Table, err = parserSelect(p)
+		if err != nil {
+			return SelectTable{}, err
+		}
+		if err = p.expectPunct(")"); err != nil {
+			return SelectTable{}, err
+		}
+	} else {
+		table.Table, err = getObjectName(p)
+		if err != nil {
+			return SelectTable{}, p.fail(ErrInvalidTable)
+		}
+	}
+	if p.isPivotStart() {
+		if table.Pivot, err = getPivot(p); err != nil {
+			return SelectTable{}, err
+		}
+	}
+	table.Alias, err = getAlias(p)
+	if err != nil {
+		return SelectTable{}, err
+	}
+	table.Pos = p.posFrom(start)
+	table.Comments.Trailing = p.trailingComments()
+	return table, nil
+}
+
+// getSelectOrder 解析Order排序，ORDER关键词已被解析
+func getSelectOrder(p *parser) (order interface{}, err error) {
+	//它可能是ORDER BY 各值；DECODE函数自定义排序
+	start := p.tokens[p.pos-1].Start
+	if p.acceptKeyword("BY") {
+		return getOrderBy(p, start)
+	}
+	if p.acceptKeyword("SIBLINGS", "BY") {
+		orderBy, err := getOrderBy(p, start)
+		orderBy.Siblings = true
+		return orderBy, err
+	}
+	if p.isKeyword("DECODE") {
+		val, err := getValue(p)
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := val.Value.(Function); ok {
+			return f, nil
+		}
+	}
+	return nil, p.fail(ErrInvalidOrder, "BY", "SIBLINGS", "DECODE")
+}
+
+// getOrderBy 解析BY后面的排序项列表，start是排序子句开始的位置
+func getOrderBy(p *parser, start int) (orderBy OrderBy, err error) {
+	if orderBy.Item, err = getOrderItems(p); err != nil {
+		return OrderBy{}, err
+	}
+	orderBy.Pos = p.posFrom(start)
+	return orderBy, nil
+}
+
+// getSelectGroup 解析分组
+func getSelectGroup(p *parser) (groups []Value, err error) {
+	for {
+		val, err := getGroupingElement(p, false)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, val)
+		if !p.acceptPunct(",") {
+			return groups, nil
+		}
+	}
+}
+
+// getSelectTable 解析查询语句的表，里面包含了JOIN部分
+func getSelectTable(p *parser) (tables []SelectTable, err error) {
+	//查询语句的表以逗号隔开
+	for {
+		table, err := getJoinedTable(p)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+		if !p.acceptPunct(",") {
+			return tables, nil
+		}
+	}
+}
+
+// getSelectField 解析查询语句的字段部分
+func getSelectField(p *parser) (fields []SelectField, err error) {
+	for {
+		var field SelectField
+		start := p.peek().Start
+		field.Comments.Leading = p.leadingComments()
+		field.Field, err = getValue(p)
+		if err != nil {
+			return nil, err
+		}
+		field.Alias, err = getAlias(p)
+		if err != nil {
+			return nil, err
+		}
+		field.Pos = p.posFrom(start)
+		field.Comments.Trailing = p.trailingComments()
+		fields = append(fields, field)
+		if !p.acceptPunct(",") {
+			return fields, nil
+		}
+	}
+}
+
+// getValue 解析成Value SQL的值，它可以是子查询、函数、CASE WHEN表达式、字符串、数字、字段、伪列、参数，以及它们的加减乘除、双竖线连接运算；它可以出现在：查询的字段、条件语句的左右值、新增/更新语句的值
+func getValue(p *parser) (value Value, err error) {
+	return getBinaryExpr(p, 1)
+}
+
+// binaryPrecedence 二元运算符的优先级，不是二元运算符时返回0。和Oracle一致，||与+、-的优先级相同
+func binaryPrecedence(op string) int {
+	switch op {
+	case "*", "/":
+		return 2
+	case "+", "-", "||":
+		return 1
+	}
+	return 0
+}
+
+// getBinaryExpr 按优先级解析二元运算，只解析优先级不低于minPrec的运算符，同一优先级从左向右结合
+func getBinaryExpr(p *parser, minPrec int) (value Value, err error) {
+	start := p.peek().Start
+	value, err = getPrimary(p)
+	if err != nil {
+		return Value{}, err
+	}
+	for {
+		tok := p.peek()
+		prec := 0
+		if tok.Type == TokenOperator {
+			prec = binaryPrecedence(tok.Value)
+		}
+		if prec == 0 || prec < minPrec {
+			return value, nil
+		}
+		p.next()
+		right, err := getBinaryExpr(p, prec+1)
+		if err != nil {
+			return Value{}, err
+		}
+		pos := p.posFrom(start)
+		value = Value{Value: BinaryExpr{Left: value, Op: tok.Value, Right: right, Pos: pos}, Pos: pos}
+	}
+}
+
+// getPrimary 解析不含运算符的单个值：子查询、函数、CASE WHEN表达式、字符串、数字、字段、参数
+func getPrimary(p *parser) (value Value, err error) {
+	tok := p.peek()
+	leading := p.leadingComments()
+	defer func() {
+		if err == nil {
+			value.Pos = p.posFrom(tok.Start)
+			value.Comments = Comments{Leading: leading, Trailing: p.trailingComments()}
+		}
+	}()
+	switch tok.Type {
+	case TokenString:
+		p.next()
+		value.Value = StringLiteral{Value: unquoteString(tok.Value), Raw: tok.Value, Pos: p.position(tok.Start, tok.End)}
+	case TokenNumber:
+		p.next()
+		value.Value = NumberLiteral{Value: tok.Value, Pos: p.position(tok.Start, tok.End)}
+	case TokenParam:
+		value.Value = Params{Name: p.next().Value, Pos: p.position(tok.Start, tok.End)}
+	case TokenOperator:
+		switch tok.Value {
+		case "*":
+			p.next()
+			value.Value = ColumnRef{Column: tok.Value, Pos: p.position(tok.Start, tok.End)}
+		case "+", "-":
+			//正负号
+			p.next()
+			if p.peek().Type == TokenNumber {
+				num := p.next()
+				value.Value = NumberLiteral{Value: tok.Value + num.Value, Pos: p.position(tok.Start, num.End)}
+				return value, nil
 			}
-		case "WHEN":
-			equationNorm = EquationNorm{}
-			equationList = EquationList{}
-			caseItem = CaseWhenItem{}
-			if !bCaseWhen {
-				equationNorm.Left = caseVal
-				equationNorm.Operator = "="
-				equationNorm.Right, err = getValue(strs[idx], placeholder, placeholderPos)
-				idx++
-				if err != nil {
-					return CaseWhen{}, err
-				}
-			} else {
-				equationList, err = getEquationList(strs[idx], placeholder, placeholderPos)
-				idx++
-				if err != nil {
-					return CaseWhen{}, err
-				}
+			val, err := getPrimary(p)
+			if err != nil {
+				return Value{}, err
 			}
-		case "THEN":
-			caseItem.Value, err = getValue(strs[idx], placeholder, placeholderPos)
-			idx++
+			value.Value = UnaryExpr{Op: tok.Value, Operand: val, Pos: p.posFrom(tok.Start)}
+		default:
+			return Value{}, p.unexpected()
+		}
+	case TokenPunct:
+		if tok.Value != "(" {
+			return Value{}, p.unexpected()
+		}
+		if sel, ok := getSubquery(p); ok {
+			value.Value = sel
+			return value, nil
+		}
+		//被括号括起的表达式，有逗号时是行值构造器
+		p.next()
+		val, err := getValue(p)
+		if err != nil {
+			return Value{}, err
+		}
+		if p.isPunct(",") {
+			value.Value, err = getRowValue(p, val, tok.Start)
 			if err != nil {
-				return CaseWhen{}, err
+				return Value{}, err
 			}
-			if !bCaseWhen {
-				caseItem.Equation.Equation = append(caseItem.Equation.Equation, Equation{Equation: equationNorm})
-			} else {
-				caseItem.Equation = equationList
+			return value, nil
+		}
+		if err = p.expectPunct(")"); err != nil {
+			return Value{}, err
+		}
+		value.Value = ParenExpr{Expr: val, Pos: p.posFrom(tok.Start)}
+	case TokenIdent, TokenQuotedIdent:
+		if p.isKeyword("CASE") {
+			value.Value, err = getCaseWhen(p)
+			if err != nil {
+				return Value{}, err
 			}
-			cas.When = append(cas.When, caseItem)
-		case "ELSE":
-			cas.Else, err = getValue(strs[idx], placeholder, placeholderPos)
-			idx++
+			return value, nil
+		}
+		if p.isAnyKeyword("PRIOR", "CONNECT_BY_ROOT") && isOperandStart(p.peekN(1)) {
+			//层次查询的一元运算符
+			op := strings.ToUpper(p.keyword().Value)
+			val, err := getPrimary(p)
 			if err != nil {
-				return CaseWhen{}, err
+				return Value{}, err
 			}
-		case "END":
-			bEnd = true
-		default:
-			return CaseWhen{}, errors.New("CASE WHEN表达式出现未知关键词")
+			value.Value = UnaryExpr{Op: op, Operand: val, Pos: p.posFrom(tok.Start)}
+			return value, nil
+		}
+		if p.isReserved() {
+			return Value{}, p.unexpected()
 		}
+		//字段、伪列或者函数名，它们可能带有前缀，例：T.NAME、T.*、PKG.FUNC
+		parts := []string{p.next().Value}
+		for p.isPunct(".") {
+			p.next()
+			if p.isOperator("*") {
+				parts = append(parts, p.next().Value)
+				break
+			}
+			part := p.peek()
+			if part.Type != TokenIdent && part.Type != TokenQuotedIdent {
+				return Value{}, p.fail(ErrInvalidValue)
+			}
+			parts = append(parts, p.next().Value)
+		}
+		if p.isPunct("(") && !p.isOuterJoinMark() {
+			value.Value, err = getFunction(p, strings.Join(parts, "."), tok.Start)
+			if err != nil {
+				return Value{}, err
+			}
+			return value, nil
+		}
+		value.Value = getColumnRef(parts, p.posFrom(tok.Start))
+		if col, ok := value.Value.(ColumnRef); ok && p.isOuterJoinMark() {
+			p.next()
+			p.next()
+			p.next()
+			col.OuterJoin = true
+			col.Pos = p.posFrom(tok.Start)
+			value.Value = col
+		}
+	default:
+		return Value{}, p.unexpected()
 	}
-	if !bEnd {
-		return CaseWhen{}, errors.New("CASE WHEN表达式缺少END")
+	return value, nil
+}
+
+// isOperandStart 词法单元是否可以是运算项的开始，用来区分PRIOR等运算符和同名的字段
+func isOperandStart(tok Token) bool {
+	switch tok.Type {
+	case TokenIdent, TokenQuotedIdent, TokenNumber, TokenString, TokenParam:
+		return true
+	case TokenPunct:
+		return tok.Value == "("
 	}
-	return cas, nil
+	return false
 }
 
-type NumberItemKeyword struct {
-	Value    string
-	Operator string
+// isOuterJoinMark 当前是否是Oracle的外连接符(+)
+func (p *parser) isOuterJoinMark() bool {
+	plus, right := p.peekN(1), p.peekN(2)
+	return p.isPunct("(") && plus.Type == TokenOperator && plus.Value == "+" && right.Type == TokenPunct && right.Value == ")"
 }
 
-// getNumberItemKeyword 将字符串按照+-*/分割
-func getNumberItemKeyword(s string) (items []NumberItemKeyword) {
-	re := regexp.MustCompile(`\+|-|\*|/`)
-	matches := re.FindAllString(s, -1)
-	strs := re.Split(s, -1)
-	if len(matches) == 1 && matches[0] == "*" {
-		if strings.Index(s, ".*") > -1 {
-			return nil
+// getColumnRef 按名称的各部分返回字段、伪列或者NULL
+func getColumnRef(parts []string, pos Pos) interface{} {
+	if len(parts) == 1 && tokenIsUnquoted(parts[0]) {
+		word := strings.ToUpper(parts[0])
+		if word == "NULL" {
+			return NullLiteral{Pos: pos}
 		}
-		if len(strs) != 2 {
-			return nil
+		if pseudoColumns[word] {
+			return PseudoColumn{Name: parts[0], Pos: pos}
 		}
-		if strs[0] == "" || strs[1] == "" {
-			return nil
+	}
+	col := ColumnRef{Column: parts[len(parts)-1], Pos: pos}
+	if len(parts) >= 2 {
+		col.Table = parts[len(parts)-2]
+	}
+	if len(parts) >= 3 {
+		col.Schema = strings.Join(parts[:len(parts)-2], ".")
+	}
+	return col
+}
+
+// tokenIsUnquoted 标识符是否没有被引号括起
+func tokenIsUnquoted(name string) bool {
+	return name != "" && name[0] != '"' && name[0] != '`'
+}
+
+// unquoteString 去掉字符串的引号、N前缀，两个连续的单引号还原成一个
+func unquoteString(s string) string {
+	if len(s) > 0 && (s[0] == 'N' || s[0] == 'n') {
+		s = s[1:]
+	}
+	if len(s) >= 2 {
+		s = s[1 : len(s)-1]
+	}
+	return strings.ReplaceAll(s, "''", "'")
+}
+
+// getFunction 解析函数的参数部分，函数名已被解析，start是函数名开始的位置
+func getFunction(p *parser, name string, start int) (f Function, err error) {
+	f.Name = name
+	if err = p.expectPunct("("); err != nil {
+		return Function{}, err
+	}
+	if p.isAnyKeyword("DISTINCT", "UNIQUE", "ALL") {
+		f.Quantifier = strings.ToUpper(p.keyword().Value)
+	}
+	if p.isOperator("*") && p.peekN(1).Type == TokenPunct && p.peekN(1).Value == ")" {
+		//COUNT(*)
+		tok := p.next()
+		pos := p.position(tok.Start, tok.End)
+		f.Params = []Value{{Value: Star{Pos: pos}, Pos: pos}}
+	} else {
+		//按逗号取出里面的参数值
+		for !p.isPunct(")") {
+			val, err := getValue(p)
+			if err != nil {
+				return Function{}, err
+			}
+			f.Params = append(f.Params, val)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+	}
+	if f.Quantifier != "" && len(f.Params) == 0 {
+		return Function{}, p.fail(ErrInvalidValue)
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return Function{}, err
+	}
+	if p.acceptKeyword("OVER") {
+		over, err := getOver(p)
+		if err != nil {
+			return Function{}, err
+		}
+		f.Over = &over
+	}
+	f.Pos = p.posFrom(start)
+	return f, nil
+}
+
+// getEquationList 解析条件部分，条件之间用AND、OR连接
+func getEquationList(p *parser) (list EquationList, err error) {
+	start := p.peek().Start
+	connector := ""
+	for {
+		var equation Equation
+		equation.Connector = connector
+		eqStart := p.peek().Start
+		equation.Equation, err = getEquation(p)
+		if err != nil {
+			return EquationList{}, err
+		}
+		equation.Pos = p.posFrom(eqStart)
+		list.Equation = append(list.Equation, equation)
+		if p.acceptKeyword("AND") {
+			connector = "AND"
+		} else if p.acceptKeyword("OR") {
+			connector = "OR"
+		} else {
+			list.Pos = p.posFrom(start)
+			return list, nil
 		}
 	}
-	for idx, item := range strs {
-		var numItem NumberItemKeyword
-		numItem.Value = item
-		if idx > 0 {
-			numItem.Operator = matches[idx-1]
+}
+
+// isCompareOperator 是否是常规比较式的符号
+func isCompareOperator(op string) bool {
+	switch op {
+	case "=", "<>", "!=", "^=", "~=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// likeOperators LIKE以及Oracle按字符集比较的LIKEC、LIKE2、LIKE4
+var likeOperators = []string{"LIKE", "LIKEC", "LIKE2", "LIKE4"}
+
+// isLikeOperator 是否是LIKE、NOT LIKE这一类条件
+func isLikeOperator(op string) bool {
+	for _, item := range likeOperators {
+		if op == item || op == "NOT "+item {
+			return true
 		}
-		items = append(items, numItem)
 	}
-	return items
+	return false
 }
 
-type SelectKeyword struct {
-	Select string
-	From   string
-	Where  string
-	Group  string
-	Having string
-	Order  string
+// isOtherOperator 是否是EquationOther的条件
+func isOtherOperator(op string) bool {
+	switch op {
+	case "IS NULL", "IS NOT NULL", "IS NAN", "IS NOT NAN", "IS INFINITE", "IS NOT INFINITE", "IN", "NOT IN":
+		return true
+	}
+	return isLikeOperator(op)
+}
+
+// acceptLikeOperator 解析LIKE、NOT LIKE这一类条件的关键词，没有则返回空串
+func acceptLikeOperator(p *parser) string {
+	for _, op := range likeOperators {
+		if p.acceptKeyword(op) {
+			return op
+		}
+		if p.acceptKeyword("NOT", op) {
+			return "NOT " + op
+		}
+	}
+	return ""
 }
 
-type KeyPos struct {
-	Key   string
-	Start int
-	End   int
+// isEquationEnd 判断一个被括号括起的部分后面是否结束了条件，如果后面还有比较符号、运算符，说明括号括起的是值
+func isEquationEnd(p *parser) bool {
+	if p.peek().Type == TokenOperator {
+		return false
+	}
+	return !p.isKeyword("IS") && !p.isKeyword("IN") && !p.isKeyword("NOT") && !p.isAnyKeyword(likeOperators...) && !p.isKeyword("BETWEEN")
 }
 
-// FindKeyPos 查询所有key的位置
-func FindKeyPos(s string, keys []KeyPos) []KeyPos {
-	for i := 0; i < len(keys); i++ {
-		keys[i].Start = strings.Index(s, keys[i].Key)
-		if keys[i].Start != -1 {
-			keys[i].End = keys[i].Start + len(keys[i].Key)
+// getEquation 解析单个条件，它可能是被括号括起的条件组
+func getEquation(p *parser) (eq interface{}, err error) {
+	if p.isPunct("(") {
+		//可能是被括号括起的条件组，也可能是被括号括起的值，先按条件组解析，不符合再按值解析
+		state := p.save()
+		p.next()
+		list, err := getEquationList(p)
+		if err == nil {
+			err = p.expectPunct(")")
 		}
+		if err == nil && isEquationEnd(p) {
+			list.Pos = p.posFrom(p.tokens[state.pos].Start)
+			return list, nil
+		}
+		p.restore(state)
+	}
+	start := p.peek().Start
+	if p.isKeyword("EXISTS") || p.isKeyword("NOT", "EXISTS") {
+		//EXISTS没有左值
+		return getExists(p)
 	}
-	return keys
+	if p.acceptKeyword("NOT") {
+		eq, err := getEquation(p)
+		if err != nil {
+			return nil, err
+		}
+		return Not{Equation: eq, Pos: p.posFrom(start)}, nil
+	}
+	if p.isPredicateFunctionStart() {
+		//返回布尔值的函数可以单独作为条件，后面还有比较符号或者参数不符合谓词函数时按普通的函数解析，
+		//例：MySQL的REGEXP_LIKE(A, 'X') = 1、LNNVL(A) = 1；按普通的函数也解析不了时返回谓词函数的错误
+		state := p.save()
+		fn, err := getPredicateFunction(p)
+		if err == nil && isEquationEnd(p) {
+			return fn, nil
+		}
+		p.restore(state)
+		furthest := p.furthest
+		if eq, valErr := getCompareEquation(p, start); valErr == nil || err == nil {
+			return eq, valErr
+		}
+		//按普通函数解析时的错误不再参与比较，只报告谓词函数的错误
+		p.furthest = furthest
+		return nil, err
+	}
+	return getCompareEquation(p, start)
 }
 
-// splitSqlByKeywordForSelect 按查询的关键词(SELECT FROM WHERE GROUP ORDER)分割SQL
-func splitSqlByKeywordForSelect(s string) (sel SelectKeyword, err error) {
-	var selectKey, fromKey, whereKey, groupKey, orderKey, havingKey KeyPos
-	selectKey.Key = "SELECT "
-	fromKey.Key = " FROM "
-	whereKey.Key = " WHERE "
-	groupKey.Key = " GROUP BY "
-	havingKey.Key = " HAVING "
-	orderKey.Key = " ORDER "
-	keys := []KeyPos{selectKey, fromKey, whereKey, groupKey, havingKey, orderKey}
-	keys = FindKeyPos(s, keys)
-	sort.SliceStable(keys, func(i, j int) bool {
-		return keys[i].Start < keys[j].Start
-	})
-	//排序后，按非-1开始的关键词开始
-	for i := 0; i < len(keys); i++ {
-		if keys[i].Start == -1 {
-			continue
+// getCompareEquation 解析有左值的条件：比较式、BETWEEN、IN、LIKE、IS NULL等，start是条件开始的位置
+func getCompareEquation(p *parser, start int) (eq interface{}, err error) {
+	left, err := getValue(p)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.Type == TokenOperator && isCompareOperator(tok.Value) {
+		//常规的比较式
+		p.next()
+		if p.isQuantifierStart() {
+			return getQuantified(p, left, tok.Value, start)
 		}
-		//查询的关键词部分，一定是从SELECT ... FROM ... 从这以后就是不一定有的，如果有，顺序一定是WHERE ... GROUP/HAVING ... ORDER ...
-		if keys[i].Key == "SELECT " {
-			if i == len(keys)-1 {
-				return sel, errors.New("未找到FROM关键词")
-			}
-			sel.Select = s[keys[i].End:keys[i+1].Start]
-		} else if keys[i].Key == " FROM " {
-			if i == len(keys)-1 {
-				sel.From = s[keys[i].End:]
-			} else {
-				sel.From = s[keys[i].End:keys[i+1].Start]
-			}
-		} else if keys[i].Key == " WHERE " {
-			if i == len(keys)-1 {
-				sel.Where = s[keys[i].End:]
-			} else {
-				sel.Where = s[keys[i].End:keys[i+1].Start]
-			}
-		} else if keys[i].Key == " GROUP BY " {
-			if i == len(keys)-1 {
-				sel.Group = s[keys[i].End:]
-			} else {
-				sel.Group = s[keys[i].End:keys[i+1].Start]
-			}
-		} else if keys[i].Key == " HAVING " {
-			if i == len(keys)-1 {
-				sel.Having = s[keys[i].End:]
-			} else {
-				sel.Having = s[keys[i].End:keys[i+1].Start]
+		var eqNorm EquationNorm
+		eqNorm.Left = left
+		eqNorm.Operator = tok.Value
+		eqNorm.Right, err = getValue(p)
+		if err != nil {
+			return nil, err
+		}
+		eqNorm.Pos = p.posFrom(start)
+		return eqNorm, nil
+	}
+	if p.isKeyword("BETWEEN") || p.isKeyword("NOT", "BETWEEN") {
+		var eqBet EquationBetween
+		eqBet.Not = p.acceptKeyword("NOT")
+		p.keyword()
+		eqBet.Field = left
+		eqBet.Left, err = getValue(p)
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword("AND") {
+			return nil, p.fail(ErrMissingKeyword, "AND")
+		}
+		eqBet.Right, err = getValue(p)
+		if err != nil {
+			return nil, err
+		}
+		eqBet.Pos = p.posFrom(start)
+		return eqBet, nil
+	}
+	//可能是IN、NOT IN、LIKE、NOT LIKE、IS NULL、IS NOT NULL
+	var eqOther EquationOther
+	eqOther.Left = left
+	switch {
+	case p.isKeyword("IS"):
+		p.keyword()
+		eqOther.Operator = "IS "
+		if p.acceptKeyword("NOT") {
+			eqOther.Operator += "NOT "
+		}
+		if !p.isAnyKeyword("NULL", "NAN", "INFINITE") {
+			return nil, p.fail(ErrMissingKeyword, "NULL", "NAN", "INFINITE")
+		}
+		eqOther.Operator += strings.ToUpper(p.keyword().Value)
+	case p.isKeyword("IN"), p.isKeyword("NOT", "IN"):
+		if p.acceptKeyword("NOT") {
+			eqOther.Operator = "NOT "
+		}
+		p.keyword()
+		if query, ok := getSubquery(p); ok {
+			return InSubquery{Left: left, Not: eqOther.Operator != "", Query: query, Pos: p.posFrom(start)}, nil
+		}
+		eqOther.Operator += "IN"
+		eqOther.Right, err = getValueList(p)
+	default:
+		if eqOther.Operator = acceptLikeOperator(p); eqOther.Operator == "" {
+			return nil, p.fail(ErrInvalidComparison)
+		}
+		var itemVal Value
+		if itemVal, err = getValue(p); err != nil {
+			return nil, err
+		}
+		eqOther.Right = append(eqOther.Right, itemVal)
+		if p.acceptKeyword("ESCAPE") {
+			eqOther.Escape, err = getValue(p)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	eqOther.Pos = p.posFrom(start)
+	return eqOther, nil
+}
+
+// getValueList 解析IN、ANY、SOME、ALL后面被括号括起的值列表，没有括号时是单个值
+func getValueList(p *parser) (values []Value, err error) {
+	if !p.isPunct("(") {
+		val, err := getValue(p)
+		if err != nil {
+			return nil, err
+		}
+		return []Value{val}, nil
+	}
+	p.next()
+	for {
+		val, err := getValue(p)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, val)
+		if !p.acceptPunct(",") {
+			break
+		}
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// getCaseWhen 解析Case when表达式
+func getCaseWhen(p *parser) (cas CaseWhen, err error) {
+	start := p.peek().Start
+	if err = p.expectKeyword("CASE"); err != nil {
+		return CaseWhen{}, err
+	}
+	//CASE后面直接跟WHEN的，WHEN后面是条件，否则WHEN后面是与CASE后面的值相比较的值
+	bCaseWhen := p.isKeyword("WHEN")
+	var caseVal Value
+	if !bCaseWhen {
+		caseVal, err = getValue(p)
+		if err != nil {
+			return CaseWhen{}, err
+		}
+	}
+	for p.isKeyword("WHEN") {
+		var caseItem CaseWhenItem
+		itemStart := p.keyword().Start
+		if !bCaseWhen {
+			var equationNorm EquationNorm
+			equationNorm.Left = caseVal
+			equationNorm.Operator = "="
+			equationNorm.Right, err = getValue(p)
+			if err != nil {
+				return CaseWhen{}, err
 			}
-		} else if keys[i].Key == " ORDER " {
-			if i == len(keys)-1 {
-				sel.Order = s[keys[i].End:]
-			} else {
-				sel.Order = s[keys[i].End:keys[i+1].Start]
+			equationNorm.Pos = equationNorm.Right.Pos
+			caseItem.Equation.Equation = append(caseItem.Equation.Equation, Equation{Equation: equationNorm, Pos: equationNorm.Pos})
+			caseItem.Equation.Pos = equationNorm.Pos
+		} else {
+			caseItem.Equation, err = getEquationList(p)
+			if err != nil {
+				return CaseWhen{}, err
 			}
 		}
+		if err = p.expectKeyword("THEN"); err != nil {
+			return CaseWhen{}, err
+		}
+		caseItem.Value, err = getValue(p)
+		if err != nil {
+			return CaseWhen{}, err
+		}
+		caseItem.Pos = p.posFrom(itemStart)
+		cas.When = append(cas.When, caseItem)
 	}
-	if sel.Select == "" {
-		return sel, errors.New("字段缺失")
+	if len(cas.When) == 0 {
+		return CaseWhen{}, p.fail(ErrCaseWithoutWhen, "WHEN")
 	}
-	if sel.From == "" {
-		return sel, errors.New("表缺失")
+	if p.acceptKeyword("ELSE") {
+		cas.Else, err = getValue(p)
+		if err != nil {
+			return CaseWhen{}, err
+		}
 	}
-	return sel, nil
+	if !p.acceptKeyword("END") {
+		return CaseWhen{}, p.fail(ErrMissingKeyword, "END")
+	}
+	cas.Pos = p.posFrom(start)
+	return cas, nil
 }
 
 // parserInsert 解析插入语句
-func parserInsert(s string, placeholder *[]Placeholder, placeholderPos *int) (insert Insert, err error) {
-	intoPos := strings.Index(s, "INSERT INTO ")
-	if intoPos == -1 {
-		return Insert{}, errors.New("缺失INTO关键词")
-	}
-	valuesPos := strings.Index(s, "VALUES ")
-	selectPos := -1
-	if valuesPos == -1 {
-		//看看是否存在select
-		selectPos = strings.Index(s, "SELECT ")
-		if selectPos == -1 {
-			return Insert{}, errors.New("缺失VALUES关键词")
-		}
-	}
-	tabEnd := -1
-	if valuesPos != -1 {
-		tabEnd = valuesPos
-	} else {
-		tabEnd = selectPos
+func parserInsert(p *parser) (insert Insert, err error) {
+	start := p.peek().Start
+	insert.Comments.Leading = p.leadingComments()
+	if !p.acceptKeyword("INSERT", "INTO") {
+		return Insert{}, p.fail(ErrMissingKeyword, "INSERT INTO")
 	}
-	tabStrs := strings.Split(strings.TrimSpace(s[intoPos+len("INSERT INTO "):tabEnd]), " ")
-	if len(tabStrs) == 0 || tabStrs[0] == "" {
-		return Insert{}, errors.New("缺失表名")
-	}
-	retStr, _, err := getPlaceholder(tabStrs[0], placeholder, placeholderPos)
+	insert.Table, err = getObjectName(p)
 	if err != nil {
-		return Insert{}, err
+		return Insert{}, p.fail(ErrMissingTable)
 	}
-	insert.Table = retStr
-	if len(tabStrs) == 2 {
+	if p.isPunct("(") && !p.isSelectStart(0) {
 		//说明含有字段
-		fieldStr, _, err := getPlaceholder(tabStrs[1], placeholder, placeholderPos)
-		if err != nil {
-			return Insert{}, err
+		p.next()
+		for {
+			field, err := getObjectName(p)
+			if err != nil {
+				return Insert{}, p.fail(ErrInvalidInsertTarget)
+			}
+			insert.Field = append(insert.Field, field)
+			if !p.acceptPunct(",") {
+				break
+			}
 		}
-		fieldStr = trimLR(strings.TrimSpace(fieldStr), "(", ")")
-		fields := strings.Split(fieldStr, ",")
-		for _, item := range fields {
-			insert.Field = append(insert.Field, strings.TrimSpace(item))
+		if err = p.expectPunct(")"); err != nil {
+			return Insert{}, err
 		}
-	} else if len(tabStrs) > 2 {
-		return Insert{}, errors.New("被插入的表和字段不正确")
 	}
-	vals := ""
-	if valuesPos != -1 {
+	if p.acceptKeyword("VALUES") {
 		//values的形式
-		vals, _, err = getPlaceholder(strings.TrimSpace(s[valuesPos+len("VALUES "):]), placeholder, placeholderPos)
-		vals = trimLR(vals, "(", ")")
-		valItems := strings.Split(vals, ",")
+		if err = p.expectPunct("("); err != nil {
+			return Insert{}, err
+		}
 		var values []Value
-		for _, item := range valItems {
-			val, err := getValue(strings.TrimSpace(item), placeholder, placeholderPos)
+		for {
+			val, err := getValue(p)
 			if err != nil {
 				return Insert{}, err
 			}
 			values = append(values, val)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+		if err = p.expectPunct(")"); err != nil {
+			return Insert{}, err
 		}
 		insert.Values = values
-	}
-	if selectPos != -1 {
+	} else if p.isSelectStart(0) {
 		//select的形式
-		insert.Values, err = parserSelect(s[selectPos:], placeholder, placeholderPos)
+		insert.Values, err = parserSelect(p)
+		if err != nil {
+			return Insert{}, err
+		}
+	} else {
+		return Insert{}, p.fail(ErrMissingKeyword, "VALUES", "SELECT")
 	}
+	insert.Pos = p.posFrom(start)
+	insert.Comments.Trailing = p.trailingComments()
 	return insert, nil
 }
 
+// getTableWithAlias 解析更新、删除语句的表，表名和别名一起保存
+func getTableWithAlias(p *parser) (string, error) {
+	table, err := getObjectName(p)
+	if err != nil {
+		return "", p.fail(ErrMissingTable)
+	}
+	alias, err := getAlias(p)
+	if err != nil {
+		return "", err
+	}
+	if alias != "" {
+		table += " " + alias
+	}
+	return table, nil
+}
+
 // parserUpdate 解析更新语句
-func parserUpdate(s string, placeholder *[]Placeholder, placeholderPos *int) (update Update, err error) {
-	setPos := strings.Index(s, " SET ")
-	if setPos == -1 {
-		return Update{}, errors.New("缺失SET关键词")
+func parserUpdate(p *parser) (update Update, err error) {
+	start := p.peek().Start
+	update.Comments.Leading = p.leadingComments()
+	if err = p.expectKeyword("UPDATE"); err != nil {
+		return Update{}, err
+	}
+	update.Table, err = getTableWithAlias(p)
+	if err != nil {
+		return Update{}, err
+	}
+	if !p.acceptKeyword("SET") {
+		return Update{}, p.fail(ErrMissingKeyword, "SET")
+	}
+	clause := p.pos
+	update.Value, err = getUpdateValues(p)
+	node, err := p.endClause(err, clause)
+	if err != nil {
+		return Update{}, err
+	}
+	if node != nil {
+		update.Value = []UpdateValueItem{{Value: Value{Value: *node, Pos: node.Pos}, Pos: node.Pos}}
 	}
 	//where不一定有
-	wherePos := strings.Index(s, " WHERE ")
-	update.Table, _, err = getPlaceholder(strings.TrimSpace(s[len("UPDATE"):setPos]), placeholder, placeholderPos)
-	nSetEnd := wherePos
-	if wherePos == -1 {
-		nSetEnd = len(s)
-	}
-	setItems := strings.Split(s[setPos+len(" SET"):nSetEnd], ",")
-	for _, item := range setItems {
-		eqStrs := strings.Split(item, "=")
-		if len(eqStrs) != 2 {
-			return Update{}, errors.New("UPDATE设置值必须是等式")
+	if p.acceptKeyword("WHERE") {
+		if update.Where, err = getClauseEquationList(p); err != nil {
+			return Update{}, err
 		}
+	}
+	update.Pos = p.posFrom(start)
+	update.Comments.Trailing = p.trailingComments()
+	return update, nil
+}
+
+// getUpdateValues 解析更新语句SET后面的等式列表
+func getUpdateValues(p *parser) (values []UpdateValueItem, err error) {
+	for {
 		var setItem UpdateValueItem
-		setItem.Field, _, err = getPlaceholder(strings.TrimSpace(eqStrs[0]), placeholder, placeholderPos)
+		itemStart := p.peek().Start
+		setItem.Comments.Leading = p.leadingComments()
+		setItem.Field, err = getObjectName(p)
 		if err != nil {
-			return Update{}, err
+			return nil, err
 		}
-		setItem.Value, err = getValue(strings.TrimSpace(eqStrs[1]), placeholder, placeholderPos)
-		if err != nil {
-			return Update{}, err
+		if !p.isOperator("=") {
+			return nil, p.fail(ErrInvalidSetItem, "=")
 		}
-		update.Value = append(update.Value, setItem)
-	}
-	if wherePos != -1 {
-		update.Where, err = getEquationList(strings.TrimSpace(s[wherePos+len(" WHERE"):]), placeholder, placeholderPos)
+		p.next()
+		setItem.Value, err = getValue(p)
 		if err != nil {
-			return Update{}, err
+			return nil, err
+		}
+		setItem.Pos = p.posFrom(itemStart)
+		setItem.Comments.Trailing = p.trailingComments()
+		values = append(values, setItem)
+		if !p.acceptPunct(",") {
+			return values, nil
 		}
 	}
-	return update, nil
 }
 
-func parserDelete(s string, placeholder *[]Placeholder, placeholderPos *int) (delete Delete, err error) {
-	fromPos := strings.Index(s, " FROM ")
-	wherePos := strings.Index(s, " WHERE ")
-	nTabStart := fromPos
-	if nTabStart == -1 {
-		nTabStart = len("DELETE ")
-	} else {
-		nTabStart += len(" FROM")
-	}
-	nTabEnd := wherePos
-	if nTabEnd == -1 {
-		nTabEnd = len(s)
+// parserDelete 解析删除语句
+func parserDelete(p *parser) (delete Delete, err error) {
+	start := p.peek().Start
+	delete.Comments.Leading = p.leadingComments()
+	if err = p.expectKeyword("DELETE"); err != nil {
+		return Delete{}, err
 	}
-	delete.Table, _, err = getPlaceholder(strings.TrimSpace(s[nTabStart:nTabEnd]), placeholder, placeholderPos)
+	p.acceptKeyword("FROM")
+	delete.Table, err = getTableWithAlias(p)
 	if err != nil {
 		return Delete{}, err
 	}
-	if wherePos != -1 {
-		delete.Where, err = getEquationList(strings.TrimSpace(s[wherePos+len(" WHERE"):]), placeholder, placeholderPos)
-		if err != nil {
+	if p.acceptKeyword("WHERE") {
+		if delete.Where, err = getClauseEquationList(p); err != nil {
 			return Delete{}, err
 		}
 	}
+	delete.Pos = p.posFrom(start)
+	delete.Comments.Trailing = p.trailingComments()
 	return delete, nil
 }
 
 // marshalFunction 序列化函数
-func marshalFunction(function Function) (retSQL string, err error) {
+func marshalFunction(m *marshaler, function Function) (retSQL string, err error) {
 	retSQL += function.Name + "("
+	if function.Quantifier != "" {
+		if len(function.Params) == 0 {
+			return "", errors.New("函数" + function.Name + "缺失" + function.Quantifier + "后面的参数")
+		}
+		retSQL += m.kw(function.Quantifier) + " "
+	}
 	for _, item := range function.Params {
-		val, err := marshalValue(item, true)
+		val, err := marshalValue(m, item, true)
 		if err != nil {
 			return "", err
 		}
 		retSQL += val + ","
 	}
 	retSQL = strings.TrimRight(retSQL, ",") + ")"
+	if function.Over != nil {
+		over, err := marshalOver(m, *function.Over)
+		if err != nil {
+			return "", err
+		}
+		retSQL += " " + over
+	}
 	return retSQL, nil
 }
 
 // marshalEquationNorm 序列化常态的条件
-func marshalEquationNorm(eq EquationNorm) (retSQL string, err error) {
-	if eq.Operator != "<" && eq.Operator != "<=" && eq.Operator != ">" && eq.Operator != ">=" && eq.Operator != "=" && eq.Operator != "!=" && eq.Operator != "<>" {
+func marshalEquationNorm(m *marshaler, eq EquationNorm) (retSQL string, err error) {
+	if !isCompareOperator(eq.Operator) {
 		return "", errors.New("比较式的符合" + eq.Operator + "不符合规则")
 	}
-	lv, err := marshalValue(eq.Left, true)
+	lv, err := marshalValue(m, eq.Left, true)
 	if err != nil {
 		return "", err
 	}
-	rv, err := marshalValue(eq.Right, true)
+	rv, err := marshalValue(m, eq.Right, true)
 	if err != nil {
 		return "", err
 	}
@@ -1253,17 +1965,23 @@ func marshalEquationNorm(eq EquationNorm) (retSQL string, err error) {
 }
 
 // marshalEquationOther 序列化其他条件
-func marshalEquationOther(eq EquationOther) (retSQL string, err error) {
-	if eq.Operator != "IS NULL" && eq.Operator != "IS NOT NULL" && eq.Operator != "IN" && eq.Operator != "NOT IN" && eq.Operator != "EXIST" && eq.Operator != "NOT EXIST" && eq.Operator != "LIKE" && eq.Operator != "NOT LIKE" {
+func marshalEquationOther(m *marshaler, eq EquationOther) (retSQL string, err error) {
+	if !isOtherOperator(eq.Operator) {
 		return "", errors.New("条件" + eq.Operator + "无效")
 	}
-	lv, err := marshalValue(eq.Left, true)
-	if err != nil {
-		return "", err
+	if eq.Escape.Value != nil && !isLikeOperator(eq.Operator) {
+		return "", errors.New("条件" + eq.Operator + "不能有ESCAPE")
+	}
+	lv := ""
+	if eq.Left.Value != nil {
+		lv, err = marshalValue(m, eq.Left, true)
+		if err != nil {
+			return "", err
+		}
 	}
 	lrStr := "("
 	for _, item := range eq.Right {
-		val, err := marshalValue(item, true)
+		val, err := marshalValue(m, item, true)
 		if err != nil {
 			return "", err
 		}
@@ -1272,71 +1990,99 @@ func marshalEquationOther(eq EquationOther) (retSQL string, err error) {
 	lrStr = strings.TrimRight(lrStr, ",")
 	lrStr += ")"
 	if lrStr != "()" {
-		if eq.Operator == "LIKE" || eq.Operator == "NOT LIKE" {
+		if isLikeOperator(eq.Operator) {
 			lrStr = trimLR(lrStr, "(", ")")
-			retSQL = lv + " " + eq.Operator + " " + lrStr
+			retSQL = lv + " " + m.kw(eq.Operator) + " " + lrStr
 		} else {
-			retSQL = lv + " " + eq.Operator + lrStr
+			retSQL = lv + " " + m.kw(eq.Operator) + lrStr
 		}
 	} else {
-		retSQL = lv + " " + eq.Operator
+		retSQL = lv + " " + m.kw(eq.Operator)
+	}
+	if eq.Escape.Value != nil {
+		escape, err := marshalValue(m, eq.Escape, true)
+		if err != nil {
+			return "", err
+		}
+		retSQL += " " + m.kw("ESCAPE") + " " + escape
 	}
 	return retSQL, nil
 }
 
-func marshalEquationBetween(eq EquationBetween) (retSQL string, err error) {
-	fld, err := marshalValue(eq.Field, true)
+func marshalEquationBetween(m *marshaler, eq EquationBetween) (retSQL string, err error) {
+	fld, err := marshalValue(m, eq.Field, true)
 	if err != nil {
 		return "", err
 	}
-	lv, err := marshalValue(eq.Left, true)
+	lv, err := marshalValue(m, eq.Left, true)
 	if err != nil {
 		return "", err
 	}
-	rv, err := marshalValue(eq.Right, true)
+	rv, err := marshalValue(m, eq.Right, true)
 	if err != nil {
 		return "", err
 	}
-	return fld + " BETWEEN " + lv + " AND " + rv, nil
+	between := "BETWEEN"
+	if eq.Not {
+		between = "NOT BETWEEN"
+	}
+	return fld + " " + m.kw(between) + " " + lv + " " + m.kw("AND") + " " + rv, nil
 }
 
-func marshalEquationList(eqList EquationList) (retSQL string, err error) {
+func marshalEquationList(m *marshaler, eqList EquationList) (retSQL string, err error) {
 	if len(eqList.Equation) == 0 {
 		return "", nil
 	}
 	for _, item := range eqList.Equation {
-		//外层的不带括号，如果遇上了EquationList，需带上括号
-		eqStr := ""
-		switch v := item.Equation.(type) {
-		case EquationNorm:
-			eqStr, err = marshalEquationNorm(v)
-		case EquationOther:
-			eqStr, err = marshalEquationOther(v)
-		case EquationBetween:
-			eqStr, err = marshalEquationBetween(v)
-		case EquationList:
-			eqStr, err = marshalEquationList(v)
-			eqStr = "(" + eqStr + ")"
-		default:
-			return "", errors.New("条件列表存在未能识别的类型")
-		}
+		eqStr, err := marshalEquation(m, item.Equation)
 		if err != nil {
 			return "", err
 		}
-		retSQL += " " + item.Connector + " " + eqStr
+		retSQL += " " + m.kw(item.Connector) + " " + eqStr
+	}
+	//只去掉左边的空格，右边可能是行注释后面的换行
+	return strings.TrimLeft(retSQL, " "), nil
+}
+
+// marshalEquation 序列化单个条件，被括号括起的条件组需带上括号
+func marshalEquation(m *marshaler, eq interface{}) (retSQL string, err error) {
+	switch v := eq.(type) {
+	case EquationNorm:
+		return marshalEquationNorm(m, v)
+	case EquationOther:
+		return marshalEquationOther(m, v)
+	case EquationBetween:
+		return marshalEquationBetween(m, v)
+	case Exists:
+		return marshalExists(m, v)
+	case Quantified:
+		return marshalQuantified(m, v)
+	case InSubquery:
+		return marshalInSubquery(m, v)
+	case Not:
+		return marshalNot(m, v)
+	case PredicateFunction:
+		return marshalPredicateFunction(m, v)
+	case EquationList:
+		if retSQL, err = marshalEquationList(m, v); err != nil {
+			return "", err
+		}
+		return "(" + retSQL + ")", nil
+	case ErrorNode:
+		return v.Text, nil
 	}
-	return strings.TrimSpace(retSQL), nil
+	return "", errors.New("条件列表存在未能识别的类型")
 }
 
 // marshalCaseWhenItem 序列化case when表达式的when项
-func marshalCaseWhenItem(whenItem CaseWhenItem) (retSQL string, err error) {
-	retSQL += "WHEN "
-	eqList, err := marshalEquationList(whenItem.Equation)
+func marshalCaseWhenItem(m *marshaler, whenItem CaseWhenItem) (retSQL string, err error) {
+	retSQL += m.kw("WHEN") + " "
+	eqList, err := marshalEquationList(m, whenItem.Equation)
 	if err != nil {
 		return "", err
 	}
-	retSQL += eqList + " THEN "
-	val, err := marshalValue(whenItem.Value, true)
+	retSQL += eqList + " " + m.kw("THEN") + " "
+	val, err := marshalValue(m, whenItem.Value, true)
 	if err != nil {
 		return "", err
 	}
@@ -1345,45 +2091,91 @@ func marshalCaseWhenItem(whenItem CaseWhenItem) (retSQL string, err error) {
 }
 
 // marshalCaseWhen 序列化case when表达式
-func marshalCaseWhen(caseWhen CaseWhen) (retSQL string, err error) {
+func marshalCaseWhen(m *marshaler, caseWhen CaseWhen) (retSQL string, err error) {
 	if len(caseWhen.When) == 0 {
 		return "", errors.New("CASE WHEN表达式需要有WHEN项")
 	}
-	retSQL = "CASE "
+	retSQL = m.kw("CASE") + " "
 	for _, item := range caseWhen.When {
-		itemStr, err := marshalCaseWhenItem(item)
+		itemStr, err := marshalCaseWhenItem(m, item)
 		if err != nil {
 			return "", err
 		}
 		retSQL += itemStr + " "
 	}
 	if caseWhen.Else.Value != nil {
-		elseVal, err := marshalValue(caseWhen.Else, true)
+		elseVal, err := marshalValue(m, caseWhen.Else, true)
 		if err != nil {
 			return "", err
 		}
-		retSQL += "ELSE " + elseVal + " "
+		retSQL += m.kw("ELSE") + " " + elseVal + " "
 	}
-	retSQL += "END"
+	retSQL += m.kw("END")
 	return retSQL, nil
 }
 
-// marshalNumber 序列化数字
-func marshalNumber(num Number) (retSQL string, err error) {
-	if len(num.Number) == 0 {
-		return "", errors.New("数字值不能为空")
+// marshalBinaryExpr 序列化二元运算，只在优先级需要时给运算项加上括号
+func marshalBinaryExpr(m *marshaler, expr BinaryExpr) (retSQL string, err error) {
+	prec := binaryPrecedence(expr.Op)
+	if prec == 0 {
+		return "", errors.New("不能识别的运算符：" + expr.Op)
 	}
-	for _, item := range num.Number {
-		retNum, err := marshalValue(item.Value, true)
-		if err != nil {
-			return "", err
+	left, err := marshalOperand(m, expr.Left, prec, false)
+	if err != nil {
+		return "", err
+	}
+	right, err := marshalOperand(m, expr.Right, prec, true)
+	if err != nil {
+		return "", err
+	}
+	return left + joinOperator(expr.Op, right), nil
+}
+
+// marshalUnaryExpr 序列化一元运算
+func marshalUnaryExpr(m *marshaler, expr UnaryExpr) (retSQL string, err error) {
+	operand, err := marshalOperand(m, expr.Operand, 3, false)
+	if err != nil {
+		return "", err
+	}
+	switch expr.Op {
+	case "+", "-":
+		return joinOperator(expr.Op, operand), nil
+	case "PRIOR", "CONNECT_BY_ROOT":
+		return m.kw(expr.Op) + " " + operand, nil
+	}
+	return "", errors.New("不能识别的运算符：" + expr.Op)
+}
+
+// marshalOperand 序列化运算项，prec是所在运算的优先级；运算项是优先级更低的运算，或者是右边同一优先级的运算时要加上括号
+func marshalOperand(m *marshaler, value Value, prec int, right bool) (retSQL string, err error) {
+	retSQL, err = marshalValue(m, value, true)
+	if err != nil {
+		return "", err
+	}
+	if v, ok := value.Value.(BinaryExpr); ok {
+		if sub := binaryPrecedence(v.Op); sub < prec || right && sub == prec {
+			retSQL = "(" + retSQL + ")"
 		}
-		retSQL += item.Operator + retNum
 	}
-	if len(num.Number) > 1 {
-		retSQL = "(" + retSQL + ")"
+	return retSQL, nil
+}
+
+// joinOperator 将运算符和它右边的SQL连在一起，避免连成--、/*注释
+func joinOperator(op string, right string) string {
+	if op == "-" && strings.HasPrefix(right, "-") || op == "/" && strings.HasPrefix(right, "*") {
+		return op + " " + right
 	}
-	return retSQL, err
+	return op + right
+}
+
+// marshalParenExpr 序列化被括号括起的表达式
+func marshalParenExpr(m *marshaler, expr ParenExpr) (retSQL string, err error) {
+	//子查询自己会加上括号
+	retSQL, err = marshalValue(m, expr.Expr, false)
+	if err != nil {
+		return "", err
+	}
+	return "(" + retSQL + ")", nil
 }
 
 // marshalParams 序列换参数
@@ -1397,222 +2189,394 @@ func marshalParams(par Params) (retSQL string, err error) {
 	return par.Name, nil
 }
 
+// marshalStringLiteral 序列化字符串，没有原文时按内容加上引号
+func marshalStringLiteral(str StringLiteral) string {
+	if str.Raw != "" {
+		return str.Raw
+	}
+	return "'" + strings.ReplaceAll(str.Value, "'", "''") + "'"
+}
+
+// marshalColumnRef 序列化字段
+func marshalColumnRef(col ColumnRef) (retSQL string, err error) {
+	if col.Column == "" {
+		return "", errors.New("字段名不能为空")
+	}
+	for _, part := range []string{col.Schema, col.Table} {
+		if part != "" {
+			retSQL += part + "."
+		}
+	}
+	retSQL += col.Column
+	if col.OuterJoin {
+		retSQL += "(+)"
+	}
+	return retSQL, nil
+}
+
 // marshalValue 序列化值，top顶层值，非双竖线连接的字符串，都应该是顶层值，true
-func marshalValue(value Value, top bool) (retSQL string, err error) {
+func marshalValue(m *marshaler, value Value, top bool) (retSQL string, err error) {
+	retSQL, err = marshalValueContent(m, value, top)
+	if err != nil {
+		return "", err
+	}
+	return marshalComments(value.Comments, retSQL), nil
+}
+
+// marshalValueContent 序列化值本身，不包含依附在值上的注释
+func marshalValueContent(m *marshaler, value Value, top bool) (retSQL string, err error) {
 	switch v := value.Value.(type) {
 	case Select:
-		retSQL, err = marshalSelect(v)
+		retSQL, err = marshalSelect(m, v)
 		if top {
 			retSQL = "(" + retSQL + ")" //在in、function等里面，涉及到多个值的情况下，SQL需要用括号括起，只有最顶层的值才需要被括号括起
 		}
 		return retSQL, err
 	case Function:
-		return marshalFunction(v)
+		return marshalFunction(m, v)
+	case GroupingElement:
+		return marshalGroupingElement(m, v)
 	case CaseWhen:
-		return marshalCaseWhen(v)
+		return marshalCaseWhen(m, v)
 	case string:
 		return v, nil
-	case Number:
-		return marshalNumber(v)
+	case StringLiteral:
+		return marshalStringLiteral(v), nil
+	case NumberLiteral:
+		return v.Value, nil
+	case NullLiteral:
+		return m.kw("NULL"), nil
+	case ColumnRef:
+		return marshalColumnRef(v)
+	case PseudoColumn:
+		return v.Name, nil
+	case Star:
+		return "*", nil
+	case BinaryExpr:
+		return marshalBinaryExpr(m, v)
+	case UnaryExpr:
+		return marshalUnaryExpr(m, v)
+	case ParenExpr:
+		return marshalParenExpr(m, v)
+	case RowValue:
+		return marshalRowValue(m, v)
 	case Params:
 		return marshalParams(v)
 	case Value:
-		return marshalValue(v, true)
+		return marshalValue(m, v, true)
+	case ErrorNode:
+		return v.Text, nil
 	case nil:
-		return "NULL", nil
+		return m.kw("NULL"), nil
 		//return "", errors.New("值不能为空")
-	case []Value:
-		for _, item := range v {
-			val, err := marshalValue(item, true)
+	default:
+		return "", errors.New("值存在不能识别的类型")
+	}
+}
+
+// marshalSelectFieldList 序列化查询的字段
+func marshalSelectFieldList(m *marshaler, fields []SelectField) (retSQL string, err error) {
+	if len(fields) == 0 {
+		return "", errors.New("缺失字段")
+	}
+	//用Join拼接，不能去掉末尾的逗号，恢复模式下ErrorNode的原文可能以逗号结尾
+	fldList := make([]string, 0, len(fields))
+	for _, item := range fields {
+		fldStr, err := marshalValue(m, item.Field, true)
+		if err != nil {
+			return "", err
+		}
+		if item.Alias != "" {
+			fldStr += " " + item.Alias
+		}
+		fldList = append(fldList, marshalComments(item.Comments, fldStr))
+	}
+	return strings.Join(fldList, ","), nil
+}
+
+// marshalSelectTable 解析表
+func marshalSelectTable(m *marshaler, tables interface{}) (retSQL string, err error) {
+	//表可能是字符串，也可能是子查询，子查询需要用括号括起
+	switch v := tables.(type) {
+	case string:
+		retSQL = v
+	case Select:
+		if retSQL, err = marshalSelect(m, v); err != nil {
+			return "", err
+		}
+		retSQL = "(" + retSQL + ")"
+	case Join:
+		retSQL, err = marshalJoin(m, v)
+	case SelectTable:
+		retSQL, err = marshalSelectTable(m, v.Table)
+	case ErrorNode:
+		retSQL = v.Text
+	case nil:
+		return "", errors.New("表不能为空")
+	default:
+		return "", errors.New("存在未知类型的表")
+	}
+	return retSQL, err
+}
+
+// marshalSelectTableList 序列化表列表
+func marshalSelectTableList(m *marshaler, tables []SelectTable) (retSQL string, err error) {
+	if len(tables) == 0 {
+		return "", errors.New("缺失要查询的表")
+	}
+	//每张表用逗号隔开
+	for _, item := range tables {
+		tabStr, err := marshalTableItem(m, item)
+		if err != nil {
+			return "", err
+		}
+		retSQL += tabStr + ","
+	}
+	retSQL = strings.TrimRight(retSQL, ",")
+	return retSQL, nil
+}
+
+// marshalTableItem 序列化单个表，包括它的别名和注释
+func marshalTableItem(m *marshaler, table SelectTable) (retSQL string, err error) {
+	retSQL, err = marshalSelectTable(m, table.Table)
+	if err != nil {
+		return "", err
+	}
+	if table.Lateral {
+		retSQL = m.kw("LATERAL") + " " + retSQL
+	}
+	if table.Pivot != nil {
+		pivot, err := marshalPivot(m, *table.Pivot)
+		if err != nil {
+			return "", err
+		}
+		retSQL += " " + pivot
+	}
+	if table.Alias != "" {
+		retSQL += " " + table.Alias
+	}
+	return marshalComments(table.Comments, retSQL), nil
+}
+
+// marshalSelectItem 序列化单查询SQL
+func marshalSelectItem(m *marshaler, sel SelectItem) (retSQL string, err error) {
+	retSQL += m.kw("SELECT") + " "
+	if sel.Distinct != "" {
+		retSQL += m.kw(sel.Distinct) + " "
+	}
+	fieldStr, err := marshalSelectFieldList(m, sel.Field)
+	if err != nil {
+		return "", err
+	}
+	retSQL += fieldStr + " " + m.kw("FROM") + " "
+	tableStr, err := marshalSelectTableList(m, sel.Table)
+	if err != nil {
+		return "", err
+	}
+	retSQL += tableStr + " "
+	//看有没有where
+	if sel.Where.Equation != nil {
+		whereStr, err := marshalEquationList(m, sel.Where)
+		if err != nil {
+			return "", err
+		}
+		retSQL += m.kw("WHERE") + " " + whereStr + " "
+	}
+	//层次查询
+	if len(sel.StartWith.Equation) > 0 {
+		startStr, err := marshalEquationList(m, sel.StartWith)
+		if err != nil {
+			return "", err
+		}
+		retSQL += m.kw("START WITH") + " " + startStr + " "
+	}
+	if len(sel.ConnectBy.Condition.Equation) > 0 {
+		connectStr, err := marshalEquationList(m, sel.ConnectBy.Condition)
+		if err != nil {
+			return "", err
+		}
+		retSQL += m.kw("CONNECT BY") + " "
+		if sel.ConnectBy.NoCycle {
+			retSQL += m.kw("NOCYCLE") + " "
+		}
+		retSQL += connectStr + " "
+	}
+	//看有没有group
+	if len(sel.Group) > 0 {
+		groupList := make([]string, 0, len(sel.Group))
+		for _, item := range sel.Group {
+			val, err := marshalValue(m, item, true)
 			if err != nil {
 				return "", err
 			}
-			//if strings.Index(val, "SELECT ") == 0{
-			//	val = "(" + val + ")"
-			//}
-			retSQL += val + "||"
+			groupList = append(groupList, val)
 		}
-		retSQL = strings.TrimRight(retSQL, "||")
-	default:
-		return "", errors.New("值存在不能识别的类型")
+		retSQL += m.kw("GROUP BY") + " " + strings.Join(groupList, ",") + " "
+	}
+	//看有没有having
+	if len(sel.Having.Equation) > 0 {
+		havingStr, err := marshalEquationList(m, sel.Having)
+		if err != nil {
+			return "", err
+		}
+		retSQL += m.kw("HAVING") + " " + havingStr + " "
+	}
+	if len(sel.Window) > 0 {
+		windowStr, err := marshalNamedWindows(m, sel.Window)
+		if err != nil {
+			return "", err
+		}
+		retSQL += windowStr + " "
+	}
+	//看有没有order
+	if sel.Order != nil {
+		orderStr, err := marshalSelectOrder(m, sel.Order)
+		if err != nil {
+			return "", err
+		}
+		retSQL += orderStr + " "
+	}
+	if sel.Limit.Style != "" {
+		limitStr, err := marshalLimit(m, sel.Limit)
+		if err != nil {
+			return "", err
+		}
+		retSQL += limitStr
+	}
+	if sel.ForUpdate.Mode != "" {
+		lockStr, err := marshalForUpdate(m, sel.ForUpdate)
+		if err != nil {
+			return "", err
+		}
+		retSQL += " " + lockStr
 	}
-	return retSQL, err
+	return retSQL, nil
 }
 
-// marshalSelectFieldList 序列化查询的字段
-func marshalSelectFieldList(fields []SelectField) (retSQL string, err error) {
-	if len(fields) == 0 {
-		return "", errors.New("缺失字段")
-	}
-	for _, item := range fields {
-		fldStr, err := marshalValue(item.Field, true)
+// marshalSelectOrder 序列化排序子句，它可以是ORDER BY、ORDER SIBLINGS BY、ORDER DECODE(...)
+func marshalSelectOrder(m *marshaler, order interface{}) (retSQL string, err error) {
+	switch v := order.(type) {
+	case OrderBy:
+		retSQL, err = marshalOrderBy(m, v)
 		if err != nil {
 			return "", err
 		}
-		if item.Alias != "" {
-			retSQL += fldStr + " " + item.Alias + ","
-		} else {
-			retSQL += fldStr + ","
+		if v.Siblings {
+			return m.kw("ORDER SIBLINGS BY") + " " + retSQL, nil
+		}
+		return m.kw("ORDER BY") + " " + retSQL, nil
+	case Function:
+		retSQL, err = marshalFunction(m, v)
+		if err != nil {
+			return "", err
 		}
+		return m.kw("ORDER") + " " + retSQL, nil
+	case ErrorNode:
+		return m.kw("ORDER") + " " + v.Text, nil
 	}
-	//去除最后一个逗号
-	return strings.TrimRight(retSQL, ","), nil
+	return "", errors.New("排序类型不正确")
 }
 
-// marshalSelectTable 解析表
-func marshalSelectTable(tables interface{}) (retSQL string, err error) {
-	//表可能是字符串，也可能是子查询，子查询需要用括号括起
-	switch v := tables.(type) {
-	case string:
-		retSQL = v
-	case Select:
-		retSQL, err = marshalSelect(v)
-		retSQL = "(" + retSQL + ")"
-	case []SelectTable:
-		retSQL, err = marshalSelectTableList(v)
-	case SelectTable:
-		retSQL, err = marshalSelectTable(v.Table)
-	case nil:
-		return "", errors.New("表不能为空")
-	default:
-		return "", errors.New("存在未知类型的表")
+// marshalOrderBy 序列化BY后面的排序项列表
+func marshalOrderBy(m *marshaler, orderBy OrderBy) (retSQL string, err error) {
+	if len(orderBy.Item) == 0 {
+		return "", errors.New("order by字段不能为空")
 	}
-	return retSQL, nil
+	return marshalOrderItems(m, orderBy.Item)
 }
 
-// marshalSelectTableList 序列化表列表
-func marshalSelectTableList(tables []SelectTable) (retSQL string, err error) {
-	if len(tables) == 0 {
-		return "", errors.New("缺失要查询的表")
-	}
-	//每张表用逗号隔开
-	for _, item := range tables {
-		if item.JoinKey == "JOIN" || item.JoinKey == "INNER JOIN" || item.JoinKey == "LEFT JOIN" || item.JoinKey == "RIGHT JOIN" {
-			//存在正常的JOIN关系
-			retSQL = strings.TrimRight(retSQL, ",")
-			retSQL += " " + item.JoinKey + " "
-		} else if item.JoinKey != "" {
-			return "", errors.New("存在不能识别的连表查询" + item.JoinKey)
-		}
-		tabStr, err := marshalSelectTable(item.Table)
+// marshalWith 序列化WITH子句
+func marshalWith(m *marshaler, with With) (retSQL string, err error) {
+	for _, item := range with.Item {
+		itemSQL, err := marshalWithItem(m, item)
 		if err != nil {
 			return "", err
 		}
-		if item.Alias != "" || item.JoinKey != "" {
-			retSQL += tabStr + " "
-		} else {
-			retSQL += tabStr
-		}
-		retSQL += item.Alias
-		if item.JoinKey != "" {
-			eqList, err := marshalEquationList(item.JoinOn)
-			if err != nil {
-				return "", err
-			}
-			retSQL += "ON " + eqList
-		}
-		retSQL += ","
+		retSQL += marshalComments(item.Comments, itemSQL) + ","
 	}
-	retSQL = strings.TrimRight(retSQL, ",")
-	return retSQL, nil
+	return marshalComments(with.Comments, m.kw("WITH")+" "+strings.TrimRight(retSQL, ",")) + " ", nil
 }
 
-// marshalSelectItem 序列化单查询SQL
-func marshalSelectItem(sel SelectItem) (retSQL string, err error) {
-	retSQL += "SELECT "
-	fieldStr, err := marshalSelectFieldList(sel.Field)
-	if err != nil {
-		return "", err
+// marshalWithItem 序列化WITH子句中的单个命名子查询
+func marshalWithItem(m *marshaler, item WithItem) (retSQL string, err error) {
+	if item.Name == "" {
+		return "", errors.New("WITH子查询名称不能为空")
+	}
+	retSQL = item.Name
+	if len(item.Field) != 0 {
+		retSQL += "(" + strings.Join(item.Field, ",") + ")"
 	}
-	retSQL += fieldStr + " FROM "
-	tableStr, err := marshalSelectTableList(sel.Table)
+	query, err := marshalSelect(m, item.Query)
 	if err != nil {
 		return "", err
 	}
-	retSQL += tableStr + " "
-	//看有没有where
-	if sel.Where.Equation != nil {
-		whereStr, err := marshalEquationList(sel.Where)
+	retSQL += " " + m.kw("AS") + " (" + query + ")"
+	if item.Search.Mode != "" {
+		by, err := marshalOrderBy(m, item.Search.By)
 		if err != nil {
 			return "", err
 		}
-		retSQL += "WHERE " + whereStr + " "
-	}
-	//看有没有group
-	if len(sel.Group) > 0 {
-		groupStr := ""
-		for _, item := range sel.Group {
-			val, err := marshalValue(item, true)
-			if err != nil {
-				return "", err
-			}
-			groupStr += val + ","
+		if item.Search.Set == "" {
+			return "", errors.New("SEARCH子句缺失SET字段")
 		}
-		groupStr = strings.TrimRight(groupStr, ",")
-		retSQL += "GROUP BY " + groupStr + " "
+		retSQL += " " + m.kw("SEARCH "+item.Search.Mode+" FIRST BY") + " " + by + " " + m.kw("SET") + " " + item.Search.Set
 	}
-	//看有没有having
-	if len(sel.Having.Equation) > 0 {
-		havingStr, err := marshalEquationList(sel.Having)
+	if len(item.Cycle.Field) != 0 {
+		if item.Cycle.Set == "" {
+			return "", errors.New("CYCLE子句缺失SET字段")
+		}
+		to, err := marshalValue(m, item.Cycle.To, true)
 		if err != nil {
 			return "", err
 		}
-		retSQL += "HAVING " + havingStr + " "
-	}
-	//看有没有order
-	if sel.Order != nil {
-		orderStr := ""
-		switch v := sel.Order.(type) {
-		case OrderBy:
-			if len(v.Value) == 0 {
-				return "", errors.New("order by字段不能为空")
-			}
-			for _, item := range v.Value {
-				val, err := marshalValue(item, true)
-				if err != nil {
-					return "", err
-				}
-				orderStr += val + ","
-			}
-			orderStr = strings.TrimRight(orderStr, ",")
-			orderStr = "ORDER BY " + orderStr + " " + v.Collation
-		case Function:
-			orderStr, err = marshalFunction(v)
-			if err != nil {
-				return "", err
-			}
-			orderStr = "ORDER " + orderStr
-		default:
-			return "", errors.New("排序类型不正确")
+		def, err := marshalValue(m, item.Cycle.Default, true)
+		if err != nil {
+			return "", err
 		}
-		retSQL += orderStr
+		retSQL += " " + m.kw("CYCLE") + " " + strings.Join(item.Cycle.Field, ",") + " " + m.kw("SET") + " " + item.Cycle.Set +
+			" " + m.kw("TO") + " " + to + " " + m.kw("DEFAULT") + " " + def
 	}
 	return retSQL, nil
 }
 
 // marshalSelect 序列化查询SQL
-func marshalSelect(sel Select) (retSQL string, err error) {
-	for _, item := range sel.Select {
-		retSQL += item.Aggregate + " "
-		itemSQL := ""
-		itemSQL, err = marshalSelectItem(item)
+func marshalSelect(m *marshaler, sel Select) (retSQL string, err error) {
+	if len(sel.With.Item) != 0 {
+		if retSQL, err = marshalWith(m, sel.With); err != nil {
+			return "", err
+		}
+	}
+	body, err := marshalSelectBody(m, sel.Body)
+	if err != nil {
+		return "", err
+	}
+	retSQL += body + " "
+	if sel.Order != nil {
+		orderStr, err := marshalSelectOrder(m, sel.Order)
+		if err != nil {
+			return "", err
+		}
+		retSQL += orderStr + " "
+	}
+	if sel.Limit.Style != "" {
+		limitStr, err := marshalLimit(m, sel.Limit)
 		if err != nil {
 			return "", err
 		}
-		retSQL += itemSQL + " "
+		retSQL += limitStr
 	}
 	return strings.TrimSpace(removeExtraSpaces(retSQL)), nil
 }
 
 // marshalInsert 序列化新增SQL
-func marshalInsert(insert Insert) (retSQL string, err error) {
+func marshalInsert(m *marshaler, insert Insert) (retSQL string, err error) {
 	if insert.Table == "" {
 		return "", errors.New("INSERT语句表缺失")
 	}
 	if len(insert.Field) == 0 {
-		retSQL += "INSERT INTO " + insert.Table + " "
+		retSQL += m.kw("INSERT INTO") + " " + insert.Table + " "
 	} else {
-		retSQL += "INSERT INTO " + insert.Table + "("
+		retSQL += m.kw("INSERT INTO") + " " + insert.Table + "("
 		for _, item := range insert.Field {
 			retSQL += item + ","
 		}
@@ -1623,17 +2587,17 @@ func marshalInsert(insert Insert) (retSQL string, err error) {
 	case []Value:
 		valStr := ""
 		for _, item := range v {
-			val, err := marshalValue(item, true)
+			val, err := marshalValue(m, item, true)
 			if err != nil {
 				return "", err
 			}
 			valStr += val + ","
 		}
 		valStr = strings.TrimRight(valStr, ",")
-		retSQL += "VALUES(" + valStr + ")"
+		retSQL += m.kw("VALUES") + "(" + valStr + ")"
 	case Select:
 		selStr := ""
-		selStr, err = marshalSelect(v)
+		selStr, err = marshalSelect(m, v)
 		if err != nil {
 			return "", err
 		}
@@ -1643,98 +2607,254 @@ func marshalInsert(insert Insert) (retSQL string, err error) {
 	default:
 		return "", errors.New("不受支持的Value值")
 	}
-	return retSQL, nil
+	return marshalComments(insert.Comments, retSQL), nil
 }
 
 // marshalUpdate 序列化更新语句
-func marshalUpdate(update Update) (retSQL string, err error) {
+func marshalUpdate(m *marshaler, update Update) (retSQL string, err error) {
 	if len(update.Value) == 0 {
 		return "", errors.New("UPDATE语句缺失SET字段")
 	}
 	if update.Table == "" {
 		return "", errors.New("UPDATE语句表缺失")
 	}
-	retSQL += "UPDATE " + update.Table + " SET "
+	retSQL += m.kw("UPDATE") + " " + update.Table + " " + m.kw("SET") + " "
 	for _, item := range update.Value {
-		val, err := marshalValue(item.Value, true)
+		val, err := marshalValue(m, item.Value, true)
 		if err != nil {
 			return "", err
 		}
+		if node, ok := item.Value.Value.(ErrorNode); ok {
+			retSQL += node.Text + ","
+			continue
+		}
 		if item.Field == "" {
 			return "", errors.New("被SET的字段不能为空")
 		}
-		retSQL += item.Field + "=" + val + ","
+		retSQL += marshalComments(item.Comments, item.Field+"="+val) + ","
 	}
 	retSQL = strings.TrimRight(retSQL, ",")
 	if len(update.Where.Equation) != 0 {
-		whereStr, err := marshalEquationList(update.Where)
+		whereStr, err := marshalEquationList(m, update.Where)
 		if err != nil {
 			return "", err
 		}
-		retSQL += " WHERE " + whereStr
+		retSQL += " " + m.kw("WHERE") + " " + whereStr
 	}
-	return retSQL, nil
+	return marshalComments(update.Comments, retSQL), nil
 }
 
 // marshalDelete 序列化删除语句
-func marshalDelete(delete Delete) (retSQL string, err error) {
+func marshalDelete(m *marshaler, delete Delete) (retSQL string, err error) {
 	if delete.Table == "" {
 		return "", errors.New("DELETE语句表缺失")
 	}
-	retSQL += "DELETE FROM " + delete.Table
+	retSQL += m.kw("DELETE FROM") + " " + delete.Table
 	if len(delete.Where.Equation) != 0 {
-		whereStr, err := marshalEquationList(delete.Where)
+		whereStr, err := marshalEquationList(m, delete.Where)
 		if err != nil {
 			return "", err
 		}
-		retSQL += " WHERE " + whereStr
+		retSQL += " " + m.kw("WHERE") + " " + whereStr
 	}
-	return retSQL, nil
+	return marshalComments(delete.Comments, retSQL), nil
+}
+
+// ParseOptions 解析选项
+type ParseOptions struct {
+	//恢复模式：出错后记录错误，跳到下一个子句（FROM、WHERE、GROUP BY、ORDER、集合关键词、分号）继续解析，
+	//不能解析的区域在语法树中用ErrorNode标记，全部错误保存在Statement.Diagnostics中
+	Recover bool
+	//ParseError.Error()使用的语言，默认是中文，也可以通过ParseError.Message按指定的语言取出错误信息
+	Locale Locale
 }
 
 // Unmarshal 将SQL解析成语法树
 func Unmarshal(s string) (stmt Statement, err error) {
-	var placeholder []Placeholder
-	var placeholderPos int
-	s, err = placeholderByString(s, &placeholder, &placeholderPos)
+	return UnmarshalWithOptions(s, ParseOptions{})
+}
+
+// UnmarshalWithOptions 按选项将SQL解析成语法树。恢复模式下，只要有错误就返回第一个错误，同时返回尽可能完整的语法树
+func UnmarshalWithOptions(s string, opts ParseOptions) (stmt Statement, err error) {
+	p, err := newParser(s, opts)
 	if err != nil {
 		return Statement{}, err
 	}
+	stmt.Source = s
+	stmt.Span = p.position(0, len(s))
 	//先判断SQL的类别
-	strType, err := getSqlType(s, &placeholder, &placeholderPos)
-	if err != nil {
-		return Statement{}, err
-	}
-	switch strType {
-	case "SELECT":
-		stmt.Ast, err = parserSelect(s, &placeholder, &placeholderPos)
+	switch getSqlType(p) {
+	case "SELECT", "WITH":
+		stmt.Ast, err = parserSelect(p)
 	case "UPDATE":
-		stmt.Ast, err = parserUpdate(s, &placeholder, &placeholderPos)
+		stmt.Ast, err = parserUpdate(p)
 	case "INSERT":
-		stmt.Ast, err = parserInsert(s, &placeholder, &placeholderPos)
+		stmt.Ast, err = parserInsert(p)
 	case "DELETE":
-		stmt.Ast, err = parserDelete(s, &placeholder, &placeholderPos)
+		stmt.Ast, err = parserDelete(p)
 	default:
-		return Statement{}, errors.New("未能适配的SQL类型")
+		err = p.fail(ErrUnsupportedStmt, "SELECT", "INSERT", "UPDATE", "DELETE")
+		if !p.recover {
+			return Statement{}, err
+		}
+	}
+	if err == nil && p.peek().Type != TokenEOF {
+		err = p.unexpected()
+	}
+	if err != nil {
+		err = p.furthestError(err)
+		if perr, ok := err.(*ParseError); ok && p.recover {
+			p.diagnose(perr)
+		} else {
+			return Statement{}, err
+		}
+	}
+	//词法错误先于语法错误被记录，按位置排序
+	sort.SliceStable(p.diagnostics, func(i, j int) bool {
+		return p.diagnostics[i].Pos.Start < p.diagnostics[j].Pos.Start
+	})
+	stmt.Diagnostics = p.diagnostics
+	//没有归属到任何节点的注释，例：跟在最后一个关键词后面的注释，归属到语句的最后
+	if rest := p.restComments(); len(rest) != 0 {
+		switch v := stmt.Ast.(type) {
+		case Select:
+			v.Body = appendBodyComments(v.Body, rest)
+			stmt.Ast = v
+		case Insert:
+			v.Comments.Trailing = append(v.Comments.Trailing, rest...)
+			stmt.Ast = v
+		case Update:
+			v.Comments.Trailing = append(v.Comments.Trailing, rest...)
+			stmt.Ast = v
+		case Delete:
+			v.Comments.Trailing = append(v.Comments.Trailing, rest...)
+			stmt.Ast = v
+		}
 	}
-	return stmt, err
+	stmt.keywords = p.keywords
+	if len(stmt.Diagnostics) != 0 {
+		return stmt, stmt.Diagnostics[0]
+	}
+	return stmt, nil
+}
+
+// Marshal 将语法树生成新的SQL，关键词全部大写
+func Marshal(stmt Statement) (retSQL string, err error) {
+	return marshalStatement(&marshaler{}, stmt)
 }
 
-// Marshal 将语法树生成新的SQL
-func Marshal(stmt Statement) (string, error) {
+func marshalStatement(m *marshaler, stmt Statement) (retSQL string, err error) {
 	switch v := stmt.Ast.(type) {
 	case Select:
-		return marshalSelect(v)
+		retSQL, err = marshalSelect(m, v)
 	case Insert:
-		return marshalInsert(v)
+		retSQL, err = marshalInsert(m, v)
 	case Update:
-		return marshalUpdate(v)
+		retSQL, err = marshalUpdate(m, v)
 	case Delete:
-		return marshalDelete(v)
+		retSQL, err = marshalDelete(m, v)
+	case PlsqlBlock:
+		return v.Text, nil
+	case ErrorNode:
+		//脚本中解析失败的语句原样输出
+		return v.Text, nil
 	default:
 		return "", errors.New("不支持的语法树类型")
 	}
-	return "", errors.New("未知错误")
+	//最后的行注释后面不需要换行
+	return strings.TrimRight(retSQL, "\n"), err
+}
+
+// KeywordCase 生成SQL时关键词的大小写，标识符、字符串始终保持语法树中的写法
+type KeywordCase int
+
+const (
+	KeywordUpper     KeywordCase = iota //关键词全部大写
+	KeywordLower                        //关键词全部小写
+	KeywordAsWritten                    //关键词保持原SQL中的写法，原SQL中没有出现的关键词跟随原SQL的整体风格
+)
+
+// MarshalOptions 生成SQL的选项
+type MarshalOptions struct {
+	KeywordCase   KeywordCase
+	StripComments bool //不输出语法树中的注释
+}
+
+// MarshalWithOptions 按选项将语法树生成新的SQL
+func MarshalWithOptions(stmt Statement, opts MarshalOptions) (string, error) {
+	m := &marshaler{keywordCase: opts.KeywordCase, written: stmt.keywords}
+	//原SQL中没有出现过的关键词，如果原SQL的关键词都是小写的，那它也用小写
+	m.lower = len(stmt.keywords) != 0
+	for _, word := range stmt.keywords {
+		if word != strings.ToLower(word) {
+			m.lower = false
+			break
+		}
+	}
+	retSQL, err := marshalStatement(m, stmt)
+	if err != nil {
+		return "", err
+	}
+	if opts.StripComments {
+		return stripComments(retSQL)
+	}
+	return retSQL, nil
+}
+
+// marshaler 生成SQL时的选项，关键词由各个序列化函数通过kw输出，标识符、字符串原样输出
+type marshaler struct {
+	keywordCase KeywordCase
+	written     map[string]string //原SQL中关键词的写法，大写的关键词->原写法
+	lower       bool              //原SQL中的关键词都是小写的
+}
+
+// kw 按选项的大小写输出关键词，words可以是空格隔开的多个关键词，例：LEFT OUTER JOIN
+func (m *marshaler) kw(words string) string {
+	words = strings.ToUpper(words)
+	switch m.keywordCase {
+	case KeywordLower:
+		return strings.ToLower(words)
+	case KeywordAsWritten:
+		list := strings.Split(words, " ")
+		for i, word := range list {
+			if w, ok := m.written[word]; ok {
+				list[i] = w
+			} else if m.lower {
+				list[i] = strings.ToLower(word)
+			}
+		}
+		return strings.Join(list, " ")
+	}
+	return words
+}
+
+// Text 取出语法树节点在原SQL中的原文
+func (stmt *Statement) Text(pos Pos) string {
+	if pos.Start < 0 || pos.End > len(stmt.Source) || pos.Start > pos.End {
+		return ""
+	}
+	return stmt.Source[pos.Start:pos.End]
+}
+
+// OrderField 找出排序项引用的查询字段：ORDER BY 2是第2个查询字段，ORDER BY 别名是有该别名的查询字段，不是引用时返回false
+func (sel *SelectItem) OrderField(item OrderItem) (SelectField, bool) {
+	switch v := item.Expr.Value.(type) {
+	case NumberLiteral:
+		n, err := strconv.Atoi(v.Value)
+		if err == nil && n >= 1 && n <= len(sel.Field) {
+			return sel.Field[n-1], true
+		}
+	case ColumnRef:
+		if v.Table != "" {
+			return SelectField{}, false
+		}
+		for _, field := range sel.Field {
+			if field.Alias == v.Column || (tokenIsUnquoted(v.Column) && tokenIsUnquoted(field.Alias) && strings.EqualFold(field.Alias, v.Column)) {
+				return field, true
+			}
+		}
+	}
+	return SelectField{}, false
 }
 
 func (stmt *Statement) Type() string {
@@ -1747,6 +2867,8 @@ func (stmt *Statement) Type() string {
 		return "UPDATE"
 	case Delete:
 		return "DELETE"
+	case PlsqlBlock:
+		return "PLSQL"
 	default:
 		return ""
 	}
@@ -1776,13 +2898,15 @@ func (stmt *Statement) Params() []Params {
 	default:
 		return nil
 	}
-	return nil
 }
 
 func getParamsBySelect(sel Select) (pars []Params) {
-	for _, item := range sel.Select {
-		pars = append(pars, getParamsBySelectItem(item)...)
+	for _, item := range sel.With.Item {
+		pars = append(pars, getParamsBySelect(item.Query)...)
 	}
+	pars = append(pars, getParamsBySelectBody(sel.Body)...)
+	pars = append(pars, getParamsByOrder(sel.Order)...)
+	pars = append(pars, getParamsByLimit(sel.Limit)...)
 	return pars
 }
 
@@ -1795,6 +2919,9 @@ func getParamsBySelectItem(sel SelectItem) (pars []Params) {
 	if sel.Where.Equation != nil {
 		pars = append(pars, getParamsBySelectEquationList(sel.Where)...)
 	}
+	//层次查询
+	pars = append(pars, getParamsBySelectEquationList(sel.StartWith)...)
+	pars = append(pars, getParamsBySelectEquationList(sel.ConnectBy.Condition)...)
 	//分组
 	if len(sel.Group) > 0 {
 		for _, item := range sel.Group {
@@ -1805,19 +2932,27 @@ func getParamsBySelectItem(sel SelectItem) (pars []Params) {
 	if len(sel.Having.Equation) > 0 {
 		pars = append(pars, getParamsBySelectEquationList(sel.Having)...)
 	}
+	//命名窗口
+	for _, item := range sel.Window {
+		pars = append(pars, getParamsByWindowSpec(item.Spec)...)
+	}
 	//排序
-	if sel.Order != nil {
-		switch v := sel.Order.(type) {
-		case OrderBy:
-			if len(v.Value) == 0 {
-				return nil
-			}
-			for _, item := range v.Value {
-				pars = append(pars, getParamsBySelectValue(item)...)
-			}
-		case Function:
-			pars = append(pars, getParamsBySelectFunction(v)...)
+	pars = append(pars, getParamsByOrder(sel.Order)...)
+	//限制行数
+	pars = append(pars, getParamsByLimit(sel.Limit)...)
+	//加锁读
+	pars = append(pars, getParamsByForUpdate(sel.ForUpdate)...)
+	return pars
+}
+
+func getParamsByOrder(order interface{}) (pars []Params) {
+	switch v := order.(type) {
+	case OrderBy:
+		for _, item := range v.Item {
+			pars = append(pars, getParamsBySelectValue(item.Expr)...)
 		}
+	case Function:
+		pars = append(pars, getParamsBySelectFunction(v)...)
 	}
 	return pars
 }
@@ -1828,10 +2963,16 @@ func getParamsBySelectTableList(tables []SelectTable) (pars []Params) {
 	}
 	//每张表用逗号隔开
 	for _, item := range tables {
-		pars = append(pars, getParamsBySelectTable(item.Table)...)
-		if item.JoinKey != "" {
-			pars = append(pars, getParamsBySelectEquationList(item.JoinOn)...)
-		}
+		pars = append(pars, getParamsByTableItem(item)...)
+	}
+	return pars
+}
+
+// getParamsByTableItem 找出单个表中的参数，包括它的PIVOT、UNPIVOT子句
+func getParamsByTableItem(table SelectTable) (pars []Params) {
+	pars = append(pars, getParamsBySelectTable(table.Table)...)
+	if table.Pivot != nil {
+		pars = append(pars, getParamsByPivot(*table.Pivot)...)
 	}
 	return pars
 }
@@ -1841,10 +2982,10 @@ func getParamsBySelectTable(tables interface{}) (pars []Params) {
 	switch v := tables.(type) {
 	case Select:
 		pars = append(pars, getParamsBySelect(v)...)
-	case []SelectTable:
-		pars = append(pars, getParamsBySelectTableList(v)...)
+	case Join:
+		pars = append(pars, getParamsByJoin(v)...)
 	case SelectTable:
-		pars = append(pars, getParamsBySelectTable(v)...)
+		pars = append(pars, getParamsBySelectTable(v.Table)...)
 	}
 	return pars
 }
@@ -1862,18 +3003,25 @@ func getParamsBySelectValue(value Value) (pars []Params) {
 		pars = append(pars, getParamsBySelect(v)...)
 	case Function:
 		pars = append(pars, getParamsBySelectFunction(v)...)
+	case GroupingElement:
+		pars = append(pars, getParamsByGroupingElement(v)...)
 	case CaseWhen:
 		pars = append(pars, getParamsBySelectCaseWhen(v)...)
-	case Number:
-		pars = append(pars, getParamsBySelectNumber(v)...)
+	case BinaryExpr:
+		pars = append(pars, getParamsBySelectValue(v.Left)...)
+		pars = append(pars, getParamsBySelectValue(v.Right)...)
+	case UnaryExpr:
+		pars = append(pars, getParamsBySelectValue(v.Operand)...)
+	case ParenExpr:
+		pars = append(pars, getParamsBySelectValue(v.Expr)...)
+	case RowValue:
+		pars = append(pars, getParamsByRowValue(v)...)
 	case Params:
 		pars = append(pars, v)
+	case StringLiteral, NumberLiteral, NullLiteral, ColumnRef, PseudoColumn, Star:
+		//字面量、字段里没有参数
 	case Value:
 		pars = append(pars, getParamsBySelectValue(v)...)
-	case []Value:
-		for _, item := range v {
-			pars = append(pars, getParamsBySelectValue(item)...)
-		}
 	}
 	return pars
 }
@@ -1882,6 +3030,9 @@ func getParamsBySelectFunction(function Function) (pars []Params) {
 	for _, item := range function.Params {
 		pars = append(pars, getParamsBySelectValue(item)...)
 	}
+	if function.Over != nil {
+		pars = append(pars, getParamsByWindowSpec(*function.Over)...)
+	}
 	return pars
 }
 
@@ -1905,27 +3056,38 @@ func getParamsBySelectCaseWhenItem(caseWhenItem CaseWhenItem) (pars []Params) {
 }
 
 func getParamsBySelectEquationList(eqList EquationList) (pars []Params) {
-	if len(eqList.Equation) == 0 {
-		return nil
-	}
 	for _, item := range eqList.Equation {
-		//外层的不带括号，如果遇上了EquationList，需带上括号
-		switch v := item.Equation.(type) {
-		case EquationNorm:
-			pars = append(pars, getParamsBySelectEquationNorm(v)...)
-		case EquationOther:
-			pars = append(pars, getParamsBySelectEquationOther(v)...)
-		case EquationBetween:
-			pars = append(pars, getParamsBySelectEquationBetween(v)...)
-		case EquationList:
-			pars = append(pars, getParamsBySelectEquationList(v)...)
-		}
+		pars = append(pars, getParamsBySelectEquation(item.Equation)...)
 	}
 	return pars
 }
 
+func getParamsBySelectEquation(eq interface{}) (pars []Params) {
+	switch v := eq.(type) {
+	case EquationNorm:
+		return getParamsBySelectEquationNorm(v)
+	case EquationOther:
+		return getParamsBySelectEquationOther(v)
+	case EquationBetween:
+		return getParamsBySelectEquationBetween(v)
+	case Exists:
+		return getParamsBySelect(v.Query)
+	case Quantified:
+		return getParamsByQuantified(v)
+	case InSubquery:
+		return getParamsByInSubquery(v)
+	case Not:
+		return getParamsBySelectEquation(v.Equation)
+	case PredicateFunction:
+		return getParamsByPredicateFunction(v)
+	case EquationList:
+		return getParamsBySelectEquationList(v)
+	}
+	return nil
+}
+
 func getParamsBySelectEquationNorm(eq EquationNorm) (pars []Params) {
-	if eq.Operator != "<" && eq.Operator != "<=" && eq.Operator != ">" && eq.Operator != ">=" && eq.Operator != "=" && eq.Operator != "!=" && eq.Operator != "<>" {
+	if !isCompareOperator(eq.Operator) {
 		return nil
 	}
 	pars = append(pars, getParamsBySelectValue(eq.Left)...)
@@ -1934,13 +3096,14 @@ func getParamsBySelectEquationNorm(eq EquationNorm) (pars []Params) {
 }
 
 func getParamsBySelectEquationOther(eq EquationOther) (pars []Params) {
-	if eq.Operator != "IS NULL" && eq.Operator != "IS NOT NULL" && eq.Operator != "IN" && eq.Operator != "NOT IN" && eq.Operator != "EXIST" && eq.Operator != "NOT EXIST" && eq.Operator != "LIKE" && eq.Operator != "NOT LIKE" {
+	if !isOtherOperator(eq.Operator) {
 		return nil
 	}
 	pars = append(pars, getParamsBySelectValue(eq.Left)...)
 	for _, item := range eq.Right {
 		pars = append(pars, getParamsBySelectValue(item)...)
 	}
+	pars = append(pars, getParamsBySelectValue(eq.Escape)...)
 	return pars
 }
 
@@ -1951,16 +3114,6 @@ func getParamsBySelectEquationBetween(eq EquationBetween) (pars []Params) {
 	return pars
 }
 
-func getParamsBySelectNumber(num Number) (pars []Params) {
-	if len(num.Number) == 0 {
-		return nil
-	}
-	for _, item := range num.Number {
-		pars = append(pars, getParamsBySelectValue(item.Value)...)
-	}
-	return pars
-}
-
 func getParamsByInsert(insert Insert) (pars []Params) {
 	if insert.Table == "" {
 		return nil
@@ -2021,21 +3174,14 @@ func RemoveParams(pars []Params) (ret []Params) {
 
 // FindParamsByString 给定一个SQL，提取里面有哪些参数，这些参数按照顺序排列
 func FindParamsByString(s string) (pars []Params) {
-	var placeholder []Placeholder
-	var placeholderPos int
-	s = strings.TrimSpace(s)
-	//替换单引号括起的内容
-	s = replacePlaceholder(s, `'(?:[^']|'')*'`, &placeholder, &placeholderPos)
-	//替换双引号
-	s = replacePlaceholder(s, "\".*?\"", &placeholder, &placeholderPos)
-	//替换反单引号
-	s = replacePlaceholder(s, "`.*?`", &placeholder, &placeholderPos)
-	re := regexp.MustCompile(`:\w*`)
-	strs := re.FindAllString(s, -1)
-	for _, item := range strs {
-		pars = append(pars, Params{
-			Name: item,
-		})
+	//字符串、被引号括起的标识符、注释里面的冒号不是参数
+	tokens, _ := Tokenize(s)
+	for _, tok := range tokens {
+		if tok.Type == TokenParam {
+			pars = append(pars, Params{
+				Name: tok.Value,
+			})
+		}
 	}
 	return pars
 }
@@ -2055,9 +3201,12 @@ func (stmt *Statement) DeleteParams(pars []Params) {
 }
 
 func deleteParamsBySelect(sel Select, pars []Params) Select {
-	for i := 0; i < len(sel.Select); i++ {
-		deleteParamsBySelectItem(&sel.Select[i], pars)
+	for i := 0; i < len(sel.With.Item); i++ {
+		sel.With.Item[i].Query = deleteParamsBySelect(sel.With.Item[i].Query, pars)
 	}
+	sel.Body = deleteParamsBySelectBody(sel.Body, pars)
+	sel.Order = deleteParamsByOrder(sel.Order, pars)
+	sel.Limit = deleteParamsByLimit(sel.Limit, pars)
 	return sel
 }
 
@@ -2069,10 +3218,13 @@ func deleteParamsBySelectItem(sel *SelectItem, pars []Params) {
 	//条件
 	if sel.Where.Equation != nil {
 		sel.Where = deleteParamsBySelectEquationList(sel.Where, pars)
-		//删除以后，第一个条件不能有连接符
-		if len(sel.Where.Equation) != 0 {
-			sel.Where.Equation[0].Connector = ""
-		}
+	}
+	//层次查询
+	if len(sel.StartWith.Equation) > 0 {
+		sel.StartWith = deleteParamsBySelectEquationList(sel.StartWith, pars)
+	}
+	if len(sel.ConnectBy.Condition.Equation) > 0 {
+		sel.ConnectBy.Condition = deleteParamsBySelectEquationList(sel.ConnectBy.Condition, pars)
 	}
 	//分组
 	if len(sel.Group) > 0 {
@@ -2089,22 +3241,38 @@ func deleteParamsBySelectItem(sel *SelectItem, pars []Params) {
 	if len(sel.Having.Equation) > 0 {
 		sel.Having = deleteParamsBySelectEquationList(sel.Having, pars)
 	}
+	//命名窗口
+	for i := range sel.Window {
+		sel.Window[i].Spec = deleteParamsByWindowSpec(sel.Window[i].Spec, pars)
+	}
 	//排序
-	if sel.Order != nil {
-		switch v := sel.Order.(type) {
-		case OrderBy:
-			var newOrder []Value
-			for _, item := range v.Value {
-				item = deleteParamsBySelectValue(item, pars)
-				if item.Value != nil {
-					newOrder = append(newOrder, item)
-				}
+	sel.Order = deleteParamsByOrder(sel.Order, pars)
+	//限制行数
+	sel.Limit = deleteParamsByLimit(sel.Limit, pars)
+	//加锁读
+	sel.ForUpdate = deleteParamsByForUpdate(sel.ForUpdate, pars)
+}
+
+// deleteParamsByOrder 排序项中有参数被删除时只删除该项，全部删除时不再排序
+func deleteParamsByOrder(order interface{}, pars []Params) interface{} {
+	switch v := order.(type) {
+	case OrderBy:
+		var newOrder []OrderItem
+		for _, item := range v.Item {
+			item.Expr = deleteParamsBySelectValue(item.Expr, pars)
+			if item.Expr.Value != nil {
+				newOrder = append(newOrder, item)
 			}
-			sel.Order = OrderBy{Value: newOrder, Collation: v.Collation}
-		case Function:
-			sel.Order = deleteParamsBySelectFunction(v, pars)
 		}
+		if len(newOrder) == 0 {
+			return nil
+		}
+		v.Item = newOrder
+		return v
+	case Function:
+		return deleteParamsBySelectFunction(v, pars)
 	}
+	return order
 }
 
 func deleteParamsBySelectField(flds *[]SelectField, pars []Params) {
@@ -2121,29 +3289,36 @@ func deleteParamsBySelectValue(value Value, pars []Params) (val Value) {
 		val.Value = deleteParamsBySelect(v, pars)
 	case Function:
 		val.Value = deleteParamsBySelectFunction(v, pars)
+	case GroupingElement:
+		val.Value = deleteParamsByGroupingElement(v, pars)
 	case CaseWhen:
 		val.Value = deleteParamsBySelectCaseWhen(v, pars)
-	case Number:
-		val.Value = deleteParamsBySelectNumber(v, pars)
+	case BinaryExpr:
+		val.Value = deleteParamsBySelectBinaryExpr(v, pars)
+	case UnaryExpr:
+		v.Operand = deleteParamsBySelectValue(v.Operand, pars)
+		if v.Operand.Value == nil {
+			return Value{Value: nil}
+		}
+		val.Value = v
+	case ParenExpr:
+		v.Expr = deleteParamsBySelectValue(v.Expr, pars)
+		if v.Expr.Value == nil {
+			return Value{Value: nil}
+		}
+		val.Value = v
+	case RowValue:
+		val.Value = deleteParamsByRowValue(v, pars)
 	case Params:
 		for _, item := range pars {
 			if v.Name == item.Name {
 				return Value{Value: nil}
 			}
 		}
+	case StringLiteral, NumberLiteral, NullLiteral, ColumnRef, PseudoColumn, Star:
+		//字面量、字段里没有参数，保持不变
 	case Value:
 		val.Value = deleteParamsBySelectValue(v, pars)
-	case []Value:
-		var bufVal []Value
-		for _, item := range v {
-			buf := deleteParamsBySelectValue(item, pars)
-			if buf.Value != nil {
-				bufVal = append(bufVal, buf)
-			}
-		}
-		if len(bufVal) != 0 {
-			val.Value = bufVal
-		}
 	}
 	return val
 }
@@ -2155,11 +3330,16 @@ func deleteParamsBySelectFunction(function Function, pars []Params) interface{}
 			return nil
 		}
 	}
+	if function.Over != nil {
+		over := deleteParamsByWindowSpec(*function.Over, pars)
+		function.Over = &over
+	}
 	return function
 }
 
 func deleteParamsBySelectCaseWhen(caseWhen CaseWhen, pars []Params) interface{} {
 	var cw CaseWhen
+	cw.Pos = caseWhen.Pos
 	for i := 0; i < len(caseWhen.When); i++ {
 		caseWhen.When[i] = deleteParamsBySelectCaseWhenItem(caseWhen.When[i], pars)
 		if caseWhen.When[i].Equation.Equation != nil && caseWhen.When[i].Value.Value != nil {
@@ -2167,7 +3347,7 @@ func deleteParamsBySelectCaseWhen(caseWhen CaseWhen, pars []Params) interface{}
 		}
 	}
 	if caseWhen.Else.Value != nil {
-		cw.Else.Value = deleteParamsBySelectValue(caseWhen.Else, pars)
+		cw.Else = deleteParamsBySelectValue(caseWhen.Else, pars)
 	}
 	if len(cw.When) == 0 {
 		return nil
@@ -2177,36 +3357,57 @@ func deleteParamsBySelectCaseWhen(caseWhen CaseWhen, pars []Params) interface{}
 
 func deleteParamsBySelectCaseWhenItem(caseWhenItem CaseWhenItem, pars []Params) CaseWhenItem {
 	caseWhenItem.Equation = deleteParamsBySelectEquationList(caseWhenItem.Equation, pars)
-	caseWhenItem.Value.Value = deleteParamsBySelectValue(caseWhenItem.Value, pars)
+	caseWhenItem.Value = deleteParamsBySelectValue(caseWhenItem.Value, pars)
 	return caseWhenItem
 }
 
 func deleteParamsBySelectEquationList(eqList EquationList, pars []Params) (ret EquationList) {
+	ret.Pos = eqList.Pos
 	for _, item := range eqList.Equation {
-		//外层的不带括号，如果遇上了EquationList，需带上括号
-		switch v := item.Equation.(type) {
-		case EquationNorm:
-			item.Equation = deleteParamsBySelectEquationNorm(v, pars)
-		case EquationOther:
-			item.Equation = deleteParamsBySelectEquationOther(v, pars)
-		case EquationBetween:
-			item.Equation = deleteParamsBySelectEquationBetween(v, pars)
-		case EquationList:
-			item.Equation = deleteParamsBySelectEquationList(v, pars)
-			switch nv := item.Equation.(type) {
-			case EquationList:
-				if len(nv.Equation) == 0 {
-					item.Equation = nil
-				}
-			}
-		}
-		if item.Equation != nil {
+		if item.Equation = deleteParamsBySelectEquation(item.Equation, pars); item.Equation != nil {
 			ret.Equation = append(ret.Equation, item)
 		}
 	}
+	//删除以后，第一个条件不能有连接符，括号、NOT、LNNVL里的条件也一样
+	if len(ret.Equation) != 0 {
+		ret.Equation[0].Connector = ""
+	}
 	return ret
 }
 
+// deleteParamsBySelectEquation 返回nil代表整个条件都被删除了
+func deleteParamsBySelectEquation(eq interface{}, pars []Params) interface{} {
+	switch v := eq.(type) {
+	case EquationNorm:
+		return deleteParamsBySelectEquationNorm(v, pars)
+	case EquationOther:
+		return deleteParamsBySelectEquationOther(v, pars)
+	case EquationBetween:
+		return deleteParamsBySelectEquationBetween(v, pars)
+	case Exists:
+		v.Query = deleteParamsBySelect(v.Query, pars)
+		return v
+	case Quantified:
+		return deleteParamsByQuantified(v, pars)
+	case InSubquery:
+		return deleteParamsByInSubquery(v, pars)
+	case Not:
+		//NOT后面的条件被删除时，NOT也一起删除
+		if v.Equation = deleteParamsBySelectEquation(v.Equation, pars); v.Equation == nil {
+			return nil
+		}
+		return v
+	case PredicateFunction:
+		return deleteParamsByPredicateFunction(v, pars)
+	case EquationList:
+		if v = deleteParamsBySelectEquationList(v, pars); len(v.Equation) == 0 {
+			return nil
+		}
+		return v
+	}
+	return eq
+}
+
 func deleteParamsBySelectEquationNorm(eq EquationNorm, pars []Params) interface{} {
 	eq.Left = deleteParamsBySelectValue(eq.Left, pars)
 	eq.Right = deleteParamsBySelectValue(eq.Right, pars)
@@ -2220,8 +3421,14 @@ func deleteParamsBySelectEquationOther(eq EquationOther, pars []Params) interfac
 	//对于other而言，如果是in、exist这种，只要还有参数，就不该直接删除这个条件。如果是like，只要like后面还接参数，也不该删除
 	var ret EquationOther
 	ret.Operator = eq.Operator
-	ret.Left.Value = deleteParamsBySelectValue(eq.Left, pars)
-	if ret.Left.Value == nil {
+	ret.Pos = eq.Pos
+	ret.Left = deleteParamsBySelectValue(eq.Left, pars)
+	if ret.Left.Value == nil && eq.Left.Value != nil {
+		return nil
+	}
+	//ESCAPE字符被删除时，LIKE的含义会改变，整个条件都删除
+	ret.Escape = deleteParamsBySelectValue(eq.Escape, pars)
+	if ret.Escape.Value == nil && eq.Escape.Value != nil {
 		return nil
 	}
 	for _, item := range eq.Right {
@@ -2236,7 +3443,8 @@ func deleteParamsBySelectEquationOther(eq EquationOther, pars []Params) interfac
 	//	}
 	//	return nil
 	//}
-	if len(ret.Right) == 0 {
+	//IS NULL这样本来就没有右值的条件不删除
+	if len(ret.Right) == 0 && len(eq.Right) != 0 {
 		return nil
 	}
 	return ret
@@ -2252,30 +3460,20 @@ func deleteParamsBySelectEquationBetween(eq EquationBetween, pars []Params) inte
 	return eq
 }
 
-func deleteParamsBySelectNumber(num Number, pars []Params) interface{} {
-	var ret Number
-	for _, item := range num.Number {
-		item.Value.Value = deleteParamsBySelectValue(item.Value, pars)
-		if item.Value.Value != nil {
-			ret.Number = append(ret.Number, item)
-		}
-	}
-	if len(ret.Number) == 0 {
+// deleteParamsBySelectBinaryExpr 运算项中有参数被删除时，整个运算都被删除
+func deleteParamsBySelectBinaryExpr(expr BinaryExpr, pars []Params) interface{} {
+	expr.Left = deleteParamsBySelectValue(expr.Left, pars)
+	expr.Right = deleteParamsBySelectValue(expr.Right, pars)
+	if expr.Left.Value == nil || expr.Right.Value == nil {
 		return nil
 	}
-	return ret
+	return expr
 }
 
 func deleteParamsBySelectTableList(tables *[]SelectTable, pars []Params) {
 	var newTabs []SelectTable
 	for i := 0; i < len(*tables); i++ {
-		(*tables)[i].Table = deleteParamsBySelectTable((*tables)[i].Table, pars)
-		if (*tables)[i].JoinKey != "" {
-			(*tables)[i].JoinOn = deleteParamsBySelectEquationList((*tables)[i].JoinOn, pars)
-			if len((*tables)[i].JoinOn.Equation) == 0 {
-				(*tables)[i].JoinKey = ""
-			}
-		}
+		(*tables)[i] = deleteParamsByTableItem((*tables)[i], pars)
 		if (*tables)[i].Table != nil {
 			newTabs = append(newTabs, (*tables)[i])
 		}
@@ -2283,15 +3481,22 @@ func deleteParamsBySelectTableList(tables *[]SelectTable, pars []Params) {
 	tables = &newTabs
 }
 
+func deleteParamsByTableItem(table SelectTable, pars []Params) SelectTable {
+	table.Table = deleteParamsBySelectTable(table.Table, pars)
+	if table.Pivot != nil {
+		table.Pivot = deleteParamsByPivot(*table.Pivot, pars)
+	}
+	return table
+}
+
 func deleteParamsBySelectTable(tables interface{}, pars []Params) interface{} {
 	ret := tables
 	//表可能是字符串，也可能是子查询，子查询需要用括号括起
 	switch v := tables.(type) {
 	case Select:
 		return deleteParamsBySelect(v, pars)
-	case []SelectTable:
-		deleteParamsBySelectTableList(&v, pars)
-		return v
+	case Join:
+		return deleteParamsByJoin(v, pars)
 		//case SelectTable:
 		//	return deleteParamsBySelectTable(v, pars)
 		//	return ret
@@ -2356,9 +3561,11 @@ func (stmt *Statement) ExpandParams(params Params, count int) {
 }
 
 func expandParamsBySelect(sel Select, params Params, count int) Select {
-	for i := 0; i < len(sel.Select); i++ {
-		expandParamsBySelectItem(&sel.Select[i], params, count)
+	for i := 0; i < len(sel.With.Item); i++ {
+		sel.With.Item[i].Query = expandParamsBySelect(sel.With.Item[i].Query, params, count)
 	}
+	sel.Body = expandParamsBySelectBody(sel.Body, params, count)
+	sel.Order = expandParamsByOrder(sel.Order, params, count)
 	return sel
 }
 
@@ -2371,6 +3578,13 @@ func expandParamsBySelectItem(sel *SelectItem, params Params, count int) {
 	if sel.Where.Equation != nil {
 		sel.Where = expandParamsBySelectEquationList(sel.Where, params, count)
 	}
+	//层次查询
+	if len(sel.StartWith.Equation) > 0 {
+		sel.StartWith = expandParamsBySelectEquationList(sel.StartWith, params, count)
+	}
+	if len(sel.ConnectBy.Condition.Equation) > 0 {
+		sel.ConnectBy.Condition = expandParamsBySelectEquationList(sel.ConnectBy.Condition, params, count)
+	}
 	//分组
 	if len(sel.Group) > 0 {
 		var newGroup []Value
@@ -2388,24 +3602,30 @@ func expandParamsBySelectItem(sel *SelectItem, params Params, count int) {
 	if len(sel.Having.Equation) > 0 {
 		sel.Having = expandParamsBySelectEquationList(sel.Having, params, count)
 	}
+	//命名窗口
+	for i := range sel.Window {
+		sel.Window[i].Spec = expandParamsByWindowSpec(sel.Window[i].Spec, params, count)
+	}
 	//排序
-	if sel.Order != nil {
-		switch v := sel.Order.(type) {
-		case OrderBy:
-			var newOrder []Value
-			for _, item := range v.Value {
-				val := expandParamsBySelectValue(item, params, count)
-				if val.Value != nil {
-					newOrder = append(newOrder, val)
-				} else {
-					newOrder = append(newOrder, item)
-				}
+	sel.Order = expandParamsByOrder(sel.Order, params, count)
+}
+
+func expandParamsByOrder(order interface{}, params Params, count int) interface{} {
+	switch v := order.(type) {
+	case OrderBy:
+		newOrder := make([]OrderItem, len(v.Item))
+		for i, item := range v.Item {
+			newOrder[i] = item
+			if val := expandParamsBySelectValue(item.Expr, params, count); val.Value != nil {
+				newOrder[i].Expr = val
 			}
-			sel.Order = OrderBy{Value: newOrder, Collation: v.Collation}
-		case Function:
-			sel.Order = expandParamsBySelectFunction(v, params, count)
 		}
+		v.Item = newOrder
+		return v
+	case Function:
+		return expandParamsBySelectFunction(v, params, count)
 	}
+	return order
 }
 
 func expandParamsBySelectField(flds *[]SelectField, params Params, count int) {
@@ -2421,26 +3641,38 @@ func expandParamsBySelectValue(value Value, params Params, count int) (val Value
 		val.Value = expandParamsBySelect(v, params, count)
 	case Function:
 		val.Value = expandParamsBySelectFunction(v, params, count)
+	case GroupingElement:
+		val.Value = expandParamsByGroupingElement(v, params, count)
 	case CaseWhen:
 		val.Value = expandParamsBySelectCaseWhen(v, params, count)
-	case Number:
-		val.Value = expandParamsBySelectNumber(v, params, count)
+	case BinaryExpr:
+		if left := expandParamsBySelectValue(v.Left, params, count); left.Value != nil {
+			v.Left = left
+		}
+		if right := expandParamsBySelectValue(v.Right, params, count); right.Value != nil {
+			v.Right = right
+		}
+		val.Value = v
+	case UnaryExpr:
+		if operand := expandParamsBySelectValue(v.Operand, params, count); operand.Value != nil {
+			v.Operand = operand
+		}
+		val.Value = v
+	case ParenExpr:
+		if expr := expandParamsBySelectValue(v.Expr, params, count); expr.Value != nil {
+			v.Expr = expr
+		}
+		val.Value = v
+	case RowValue:
+		val.Value = expandParamsByRowValue(v, params, count)
 	case Params:
 		if v.Name == params.Name {
 			return Value{Value: nil}
 		}
+	case StringLiteral, NumberLiteral, NullLiteral, ColumnRef, PseudoColumn, Star:
+		//字面量、字段里没有参数，保持不变
 	case Value:
 		val.Value = expandParamsBySelectValue(v, params, count)
-	case []Value:
-		var bufVal []Value
-		for _, item := range v {
-			buf := expandParamsBySelectValue(item, params, count)
-			if buf.Value == nil {
-				buf = item
-			}
-			bufVal = append(bufVal, buf)
-		}
-		val.Value = bufVal
 	}
 	return val
 }
@@ -2452,11 +3684,16 @@ func expandParamsBySelectFunction(function Function, params Params, count int) i
 			function.Params[i] = val
 		}
 	}
+	if function.Over != nil {
+		over := expandParamsByWindowSpec(*function.Over, params, count)
+		function.Over = &over
+	}
 	return function
 }
 
 func expandParamsBySelectCaseWhen(caseWhen CaseWhen, params Params, count int) interface{} {
 	var cw CaseWhen
+	cw.Pos = caseWhen.Pos
 	for i := 0; i < len(caseWhen.When); i++ {
 		val := expandParamsBySelectCaseWhenItem(caseWhen.When[i], params, count)
 		if val.Equation.Equation != nil && val.Value.Value != nil {
@@ -2465,7 +3702,7 @@ func expandParamsBySelectCaseWhen(caseWhen CaseWhen, params Params, count int) i
 		cw.When = append(cw.When, val)
 	}
 	if caseWhen.Else.Value != nil {
-		cw.Else.Value = expandParamsBySelectValue(caseWhen.Else, params, count)
+		cw.Else = expandParamsBySelectValue(caseWhen.Else, params, count)
 		if cw.Else.Value == nil {
 			cw.Else = caseWhen.Else
 		}
@@ -2476,7 +3713,7 @@ func expandParamsBySelectCaseWhen(caseWhen CaseWhen, params Params, count int) i
 func expandParamsBySelectCaseWhenItem(caseWhenItem CaseWhenItem, params Params, count int) CaseWhenItem {
 	ret := caseWhenItem
 	ret.Equation = expandParamsBySelectEquationList(caseWhenItem.Equation, params, count)
-	caseWhenItem.Value.Value = expandParamsBySelectValue(caseWhenItem.Value, params, count)
+	caseWhenItem.Value = expandParamsBySelectValue(caseWhenItem.Value, params, count)
 	if caseWhenItem.Value.Value != nil {
 		ret.Value = caseWhenItem.Value
 	}
@@ -2484,33 +3721,49 @@ func expandParamsBySelectCaseWhenItem(caseWhenItem CaseWhenItem, params Params,
 }
 
 func expandParamsBySelectEquationList(eqList EquationList, params Params, count int) (ret EquationList) {
+	ret.Pos = eqList.Pos
 	for _, item := range eqList.Equation {
-		//外层的不带括号，如果遇上了EquationList，需带上括号
-		switch v := item.Equation.(type) {
-		case EquationNorm:
-			item.Equation = expandParamsBySelectEquationNorm(v, params, count)
-		case EquationOther:
-			item.Equation = expandParamsBySelectEquationOther(v, params, count)
-		case EquationBetween:
-			item.Equation = expandParamsBySelectEquationBetween(v, params, count)
-		case EquationList:
-			item.Equation = expandParamsBySelectEquationList(v, params, count)
-			switch nv := item.Equation.(type) {
-			case EquationList:
-				if len(nv.Equation) == 0 {
-					item.Equation = nil
-				}
-			}
-		}
-		if item.Equation != nil {
+		if item.Equation = expandParamsBySelectEquation(item.Equation, params, count); item.Equation != nil {
 			ret.Equation = append(ret.Equation, item)
 		}
 	}
 	return ret
 }
 
+func expandParamsBySelectEquation(eq interface{}, params Params, count int) interface{} {
+	switch v := eq.(type) {
+	case EquationNorm:
+		return expandParamsBySelectEquationNorm(v, params, count)
+	case EquationOther:
+		return expandParamsBySelectEquationOther(v, params, count)
+	case EquationBetween:
+		return expandParamsBySelectEquationBetween(v, params, count)
+	case Exists:
+		v.Query = expandParamsBySelect(v.Query, params, count)
+		return v
+	case Quantified:
+		return expandParamsByQuantified(v, params, count)
+	case InSubquery:
+		return expandParamsByInSubquery(v, params, count)
+	case Not:
+		if v.Equation = expandParamsBySelectEquation(v.Equation, params, count); v.Equation == nil {
+			return nil
+		}
+		return v
+	case PredicateFunction:
+		return expandParamsByPredicateFunction(v, params, count)
+	case EquationList:
+		if v = expandParamsBySelectEquationList(v, params, count); len(v.Equation) == 0 {
+			return nil
+		}
+		return v
+	}
+	return eq
+}
+
 func expandParamsBySelectEquationNorm(eq EquationNorm, params Params, count int) interface{} {
 	var ret EquationNorm
+	ret.Pos = eq.Pos
 	ret.Left = expandParamsBySelectValue(eq.Left, params, count)
 	ret.Right = expandParamsBySelectValue(eq.Right, params, count)
 	if ret.Left.Value == nil {
@@ -2527,14 +3780,19 @@ func expandParamsBySelectEquationOther(eq EquationOther, params Params, count in
 	//对于other而言，如果是in、exist这种，只要还有参数，就不该直接删除这个条件。如果是like，只要like后面还接参数，也不该删除
 	var ret EquationOther
 	ret.Operator = eq.Operator
-	ret.Left.Value = expandParamsBySelectValue(eq.Left, params, count)
+	ret.Pos = eq.Pos
+	ret.Left = expandParamsBySelectValue(eq.Left, params, count)
 	if ret.Left.Value == nil {
 		ret.Left = eq.Left
 	}
+	ret.Escape = expandParamsBySelectValue(eq.Escape, params, count)
+	if ret.Escape.Value == nil {
+		ret.Escape = eq.Escape
+	}
 	for _, item := range eq.Right {
 		val := expandParamsBySelectValue(item, params, count)
-		if val.Value == nil {
-			//说明是需要扩展的参数
+		if val.Value == nil && !isLikeOperator(eq.Operator) {
+			//说明是需要扩展的参数，LIKE后面只能有一个值，不扩展
 			par, _ := item.Params()
 			for i := 0; i < count; i++ {
 				newPar := par
@@ -2550,6 +3808,8 @@ func expandParamsBySelectEquationOther(eq EquationOther, params Params, count in
 
 func expandParamsBySelectEquationBetween(eq EquationBetween, params Params, count int) interface{} {
 	var ret EquationBetween
+	ret.Pos = eq.Pos
+	ret.Not = eq.Not
 	ret.Field = expandParamsBySelectValue(eq.Field, params, count)
 	ret.Left = expandParamsBySelectValue(eq.Left, params, count)
 	ret.Right = expandParamsBySelectValue(eq.Right, params, count)
@@ -2565,30 +3825,10 @@ func expandParamsBySelectEquationBetween(eq EquationBetween, params Params, coun
 	return ret
 }
 
-func expandParamsBySelectNumber(num Number, params Params, count int) interface{} {
-	var ret Number
-	for _, item := range num.Number {
-		val := expandParamsBySelectValue(item.Value, params, count)
-		if item.Value.Value == nil {
-			ret.Number = append(ret.Number, item)
-		} else {
-			item.Value.Value = val
-			ret.Number = append(ret.Number, item)
-		}
-	}
-	return ret
-}
-
 func expandParamsBySelectTableList(tables *[]SelectTable, params Params, count int) {
 	var newTabs []SelectTable
 	for i := 0; i < len(*tables); i++ {
-		(*tables)[i].Table = expandParamsBySelectTable((*tables)[i].Table, params, count)
-		if (*tables)[i].JoinKey != "" {
-			(*tables)[i].JoinOn = expandParamsBySelectEquationList((*tables)[i].JoinOn, params, count)
-			if len((*tables)[i].JoinOn.Equation) == 0 {
-				(*tables)[i].JoinKey = ""
-			}
-		}
+		(*tables)[i] = expandParamsByTableItem((*tables)[i], params, count)
 		if (*tables)[i].Table != nil {
 			newTabs = append(newTabs, (*tables)[i])
 		}
@@ -2596,17 +3836,25 @@ func expandParamsBySelectTableList(tables *[]SelectTable, params Params, count i
 	tables = &newTabs
 }
 
+func expandParamsByTableItem(table SelectTable, params Params, count int) SelectTable {
+	table.Table = expandParamsBySelectTable(table.Table, params, count)
+	if table.Pivot != nil {
+		table.Pivot = expandParamsByPivot(*table.Pivot, params, count)
+	}
+	return table
+}
+
 func expandParamsBySelectTable(tables interface{}, params Params, count int) interface{} {
 	ret := tables
 	//表可能是字符串，也可能是子查询，子查询需要用括号括起
 	switch v := tables.(type) {
 	case Select:
 		return expandParamsBySelect(v, params, count)
-	case []SelectTable:
-		expandParamsBySelectTableList(&v, params, count)
-		return v
+	case Join:
+		return expandParamsByJoin(v, params, count)
 	case SelectTable:
-		return expandParamsBySelectTable(v, params, count)
+		v.Table = expandParamsBySelectTable(v.Table, params, count)
+		return v
 	}
 	return ret
 }
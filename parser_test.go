@@ -0,0 +1,906 @@
+package sqlParser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarshalKeywordCase(t *testing.T) {
+	tests := []struct {
+		sql   string
+		upper string
+		lower string
+		as    string
+	}{
+		{
+			//和关键词同名的标识符保持原样
+			sql:   "select t.Mode, t.Key, t.First, t.Row, t.Start from app_user t where t.Current = 1",
+			upper: "SELECT t.Mode,t.Key,t.First,t.Row,t.Start FROM app_user t WHERE t.Current=1",
+			lower: "select t.Mode,t.Key,t.First,t.Row,t.Start from app_user t where t.Current=1",
+			as:    "select t.Mode,t.Key,t.First,t.Row,t.Start from app_user t where t.Current=1",
+		},
+		{
+			sql:   "Select a From t Left Join s on t.id=s.id Where x is not null union all select b from u order by 1",
+			upper: "SELECT a FROM t LEFT JOIN s ON t.id=s.id WHERE x IS NOT NULL UNION ALL SELECT b FROM u ORDER BY 1",
+			lower: "select a from t left join s on t.id=s.id where x is not null union all select b from u order by 1",
+			as:    "Select a From t Left Join s on t.id=s.id Where x is not null union all Select b From u order by 1",
+		},
+		{
+			//标识符、字符串保持原SQL的写法
+			sql:   "select \"Mixed\", t.CamelCase, 'Str' from \"Tab\" t where t.Name like 'Ab%'",
+			upper: "SELECT \"Mixed\",t.CamelCase,'Str' FROM \"Tab\" t WHERE t.Name LIKE 'Ab%'",
+			lower: "select \"Mixed\",t.CamelCase,'Str' from \"Tab\" t where t.Name like 'Ab%'",
+			as:    "select \"Mixed\",t.CamelCase,'Str' from \"Tab\" t where t.Name like 'Ab%'",
+		},
+		{
+			sql:   "SELECT count(Distinct t.Id) FROM t GROUP BY t.Kind",
+			upper: "SELECT count(DISTINCT t.Id) FROM t GROUP BY t.Kind",
+			lower: "select count(distinct t.Id) from t group by t.Kind",
+			as:    "SELECT count(Distinct t.Id) FROM t GROUP BY t.Kind",
+		},
+		{
+			//原SQL中没有出现的关键词跟随原SQL的整体风格
+			sql:   "select a from t where b between 1 and 2",
+			upper: "SELECT a FROM t WHERE b BETWEEN 1 AND 2",
+			lower: "select a from t where b between 1 and 2",
+			as:    "select a from t where b between 1 and 2",
+		},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		for kc, want := range map[KeywordCase]string{KeywordUpper: tt.upper, KeywordLower: tt.lower, KeywordAsWritten: tt.as} {
+			got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: kc})
+			if err != nil {
+				t.Fatalf("MarshalWithOptions(%q, %d): %v", tt.sql, kc, err)
+			}
+			if got != want {
+				t.Errorf("MarshalWithOptions(%q, %d) = %q, want %q", tt.sql, kc, got, want)
+			}
+		}
+		got, err := Marshal(stmt)
+		if err != nil || got != tt.upper {
+			t.Errorf("Marshal(%q) = %q, %v, want %q", tt.sql, got, err, tt.upper)
+		}
+	}
+}
+
+// remarshal 解析SQL后重新生成SQL
+func remarshal(t *testing.T, sql string) string {
+	t.Helper()
+	stmt, err := Unmarshal(sql)
+	if err != nil {
+		t.Fatalf("Unmarshal(%q): %v", sql, err)
+	}
+	got, err := Marshal(stmt)
+	if err != nil {
+		t.Fatalf("Marshal(%q): %v", sql, err)
+	}
+	return got
+}
+
+// paramNames 取出参数名，便于比较
+func paramNames(pars []Params) (names []string) {
+	for _, item := range pars {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+func TestUnmarshalRecover(t *testing.T) {
+	tests := []struct {
+		sql   string
+		want  string
+		diags int
+	}{
+		//多余的右括号被当作错误的内容跳过，后面的子句继续解析
+		{"SELECT a FROM t WHERE x = ) ORDER BY b", "SELECT a FROM t WHERE x = ) ORDER BY b", 1},
+		{"SELECT a FROM t WHERE x = 1 ) ORDER BY b", "SELECT a FROM t WHERE x = 1 ) ORDER BY b", 1},
+		//闭合子查询的右括号是子句边界
+		{"SELECT a FROM (SELECT b FROM t WHERE x = ) s ORDER BY a", "SELECT a FROM (SELECT b FROM t WHERE x =) s ORDER BY a", 1},
+		{"SELECT a FROM t WHERE x = 1", "SELECT a FROM t WHERE x=1", 0},
+		//每个子句的错误单独记录，出错的区域原样保留
+		{"SELECT A, B + FROM T WHERE X = = 1 GROUP BY C ORDER BY D", "SELECT A, B + FROM T WHERE X = = 1 GROUP BY C ORDER BY D", 2},
+		{"SELECT A FROM T WHERE X IN (SELECT Y FROM U WHERE Z = ) AND Q = 1", "SELECT A FROM T WHERE X IN(SELECT Y FROM U WHERE Z =) AND Q=1", 1},
+		{"SELECT A ? B FROM T WHERE X = 'abc", "SELECT A ? B FROM T WHERE X = 'abc", 2},
+		{"SELECT A B C FROM T UNION SELECT D FROM WHERE E = 1", "SELECT A B C FROM T UNION SELECT D FROM WHERE E=1", 2},
+		{"UPDATE T SET A 1, B = 2 WHERE C = :P", "UPDATE T SET A 1, B = 2 WHERE C=:P", 1},
+		//字段、分组列表末尾多余的逗号在出错的原文里，不能被去掉
+		{"select a, from t where b = 1", "SELECT a, FROM t WHERE b=1", 1},
+		{"select a, b from t group by a, order by a", "SELECT a,b FROM t GROUP BY a, ORDER BY a", 1},
+	}
+	for _, tt := range tests {
+		stmt, err := UnmarshalWithOptions(tt.sql, ParseOptions{Recover: true})
+		if len(stmt.Diagnostics) != tt.diags || (err != nil) != (tt.diags != 0) {
+			t.Fatalf("%q: err %v, %d diagnostics, want %d", tt.sql, err, len(stmt.Diagnostics), tt.diags)
+		}
+		got, err := Marshal(stmt)
+		if err != nil {
+			t.Fatalf("Marshal(%q): %v", tt.sql, err)
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+		//非恢复模式下只返回错误
+		if tt.diags != 0 {
+			if stmt, err := Unmarshal(tt.sql); err == nil || stmt.Ast != nil {
+				t.Errorf("Unmarshal(%q) = %v, %v, want only an error", tt.sql, stmt.Ast, err)
+			}
+		}
+	}
+}
+
+func TestSelectItemOrder(t *testing.T) {
+	tests := []struct {
+		sql   string
+		items int //OrderBy的排序项数量，-1表示是Function
+		want  string
+	}{
+		{"select a from t order by a desc, 2 nulls first", 2, "SELECT a FROM t ORDER BY a DESC,2 NULLS FIRST"},
+		{"select a from t connect by prior id = pid order siblings by a", 1, "SELECT a FROM t CONNECT BY PRIOR id=pid ORDER SIBLINGS BY a"},
+		{"select a from t order decode(a, 1, 2)", -1, "SELECT a FROM t ORDER decode(a,1,2)"},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		sel := stmt.Ast.(Select)
+		order := sel.Order
+		if item, ok := sel.Body.(SelectItem); ok && order == nil {
+			order = item.Order
+		}
+		switch v := order.(type) {
+		case OrderBy:
+			if len(v.Item) != tt.items {
+				t.Errorf("%q: %d order items, want %d", tt.sql, len(v.Item), tt.items)
+			}
+		case Function:
+			if tt.items != -1 {
+				t.Errorf("%q: got Function, want OrderBy", tt.sql)
+			}
+		default:
+			t.Errorf("%q: unexpected order %T", tt.sql, order)
+		}
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+	//排序方向、NULLS保持原来的写法
+	stmt, err := Unmarshal("select a x, b from t Order By X Asc, 2 Desc Nulls Last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "select a x,b from t Order By X Asc,2 Desc Nulls Last"
+	if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: KeywordAsWritten}); err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestOrderField(t *testing.T) {
+	tests := []struct {
+		sql   string
+		field string //引用的字段的原文，空表示不是引用
+	}{
+		{"select a, b from t order by 2", "b"},
+		{"select a, b from t order by 3", ""},
+		{"select a, b from t order by 0", ""},
+		//别名不区分大小写，带引号的别名要完全一致
+		{"select a x, b from t order by X desc", "a x"},
+		{`select a "X", b from t order by x`, ""},
+		{`select a "X", b from t order by "X"`, `a "X"`},
+		//带表名的字段、表达式不是引用
+		{"select a x from t order by t.x", ""},
+		{"select a x from t order by x + 1", ""},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		sel := stmt.Ast.(Select).Body.(SelectItem)
+		field, ok := sel.OrderField(sel.Order.(OrderBy).Item[0])
+		if got := stmt.Text(field.Pos); ok != (tt.field != "") || got != tt.field {
+			t.Errorf("%q: OrderField = %q, %v, want %q", tt.sql, got, ok, tt.field)
+		}
+	}
+}
+
+func TestOrderItemDeleteParams(t *testing.T) {
+	testParams(t, []paramsCase{
+		//排序项中有参数被删除时只删除该项，全部删除时去掉ORDER BY
+		{"select a from t order by a desc, :p nulls first, b", []string{":p"}, ":p", "SELECT a FROM t ORDER BY a DESC,b", "", ""},
+		{"select a from t order by :p", []string{":p"}, ":p", "SELECT a FROM t", "", ""},
+		{
+			"select a from t order by a, (select max(b) from s where c in (:p)) desc",
+			[]string{":p"},
+			":p", "SELECT a FROM t ORDER BY a,(SELECT max(b) FROM s) DESC",
+			":p", "SELECT a FROM t ORDER BY a,(SELECT max(b) FROM s WHERE c IN(:p0,:p1,:p2)) DESC",
+		},
+	})
+}
+
+func TestOrderSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select a from t order by", ErrUnexpectedEOF, 25},
+		{"select a from t order by a, ", ErrUnexpectedEOF, 29},
+		{"select a from t order by a nulls", ErrMissingKeyword, 33},
+		{"select a from t order by a desc nulls middle", ErrMissingKeyword, 39},
+		{"select a from t order by a asc desc", ErrUnexpectedToken, 32},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+}
+
+func TestMarshalOrderItemErrors(t *testing.T) {
+	col := Value{Value: ColumnRef{Column: "a"}}
+	tests := map[string]OrderItem{
+		"unknown direction": {Expr: col, Direction: "UP"},
+		"unknown nulls":     {Expr: col, Nulls: "MIDDLE"},
+		"empty column":      {Expr: Value{Value: ColumnRef{}}},
+	}
+	for name, item := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalOrderItems(&marshaler{}, []OrderItem{item}); err == nil {
+				t.Errorf("marshalOrderItems = %q, should fail", got)
+			}
+		})
+	}
+}
+
+func TestPositions(t *testing.T) {
+	src := "SELECT A.X, 1+2 AS C\nFROM T_A A LEFT JOIN T_B B ON A.ID = B.ID\nWHERE A.Y = :P AND (B.Z > 1 OR B.Z < 0)"
+	stmt, err := Unmarshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := stmt.Ast.(Select).Body.(SelectItem)
+	join := item.Table[0].Table.(Join)
+	tests := []struct {
+		name   string
+		pos    Pos
+		text   string
+		line   int
+		column int
+	}{
+		{"statement", stmt.Span, src, 1, 1},
+		{"field 0", item.Field[0].Pos, "A.X", 1, 8},
+		{"field 1", item.Field[1].Pos, "1+2 AS C", 1, 13},
+		{"column", item.Field[0].Field.Value.(ColumnRef).Pos, "A.X", 1, 8},
+		{"join", item.Table[0].Pos, "T_A A LEFT JOIN T_B B ON A.ID = B.ID", 2, 6},
+		{"join left", join.Left.Pos, "T_A A", 2, 6},
+		{"join right", join.Right.Pos, "T_B B", 2, 22},
+		{"where 0", item.Where.Equation[0].Pos, "A.Y = :P", 3, 7},
+		{"where 1", item.Where.Equation[1].Pos, "(B.Z > 1 OR B.Z < 0)", 3, 20},
+		{"param", item.Where.Equation[0].Equation.(EquationNorm).Right.Value.(Params).Pos, ":P", 3, 13},
+	}
+	for _, tt := range tests {
+		if got := stmt.Text(tt.pos); got != tt.text {
+			t.Errorf("%s: Text = %q, want %q", tt.name, got, tt.text)
+		}
+		if tt.pos.Line != tt.line || tt.pos.Column != tt.column {
+			t.Errorf("%s: line %d column %d, want %d %d", tt.name, tt.pos.Line, tt.pos.Column, tt.line, tt.column)
+		}
+	}
+	if got := stmt.Text(Pos{Start: 5, End: 1}); got != "" {
+		t.Errorf("Text of invalid Pos = %q, want empty", got)
+	}
+}
+
+func TestValueNodes(t *testing.T) {
+	sql := `select 'it''s', N'x', 1.5e3, null, sysdate, s.t.c, t.c, "Col", "rownum", :p from t`
+	want := []interface{}{
+		StringLiteral{Value: "it's", Raw: "'it''s'"},
+		StringLiteral{Value: "x", Raw: "N'x'"},
+		NumberLiteral{Value: "1.5e3"},
+		NullLiteral{},
+		PseudoColumn{Name: "sysdate"},
+		ColumnRef{Schema: "s", Table: "t", Column: "c"},
+		ColumnRef{Table: "t", Column: "c"},
+		ColumnRef{Column: `"Col"`},
+		//被引号括起的不是伪列
+		ColumnRef{Column: `"rownum"`},
+		Params{Name: ":p"},
+	}
+	stmt, err := Unmarshal(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := stmt.Ast.(Select).Body.(SelectItem).Field
+	if len(fields) != len(want) {
+		t.Fatalf("got %d fields, want %d", len(fields), len(want))
+	}
+	for i, field := range fields {
+		got := field.Field.Value
+		//位置由TestPositions检查，这里只比较内容
+		switch v := got.(type) {
+		case StringLiteral:
+			v.Pos = Pos{}
+			got = v
+		case NumberLiteral:
+			v.Pos = Pos{}
+			got = v
+		case NullLiteral:
+			v.Pos = Pos{}
+			got = v
+		case PseudoColumn:
+			v.Pos = Pos{}
+			got = v
+		case ColumnRef:
+			v.Pos = Pos{}
+			got = v
+		case Params:
+			v.Pos = Pos{}
+			got = v
+		}
+		if got != want[i] {
+			t.Errorf("field %d = %#v, want %#v", i, got, want[i])
+		}
+	}
+	if got, want := remarshal(t, sql), `SELECT 'it''s',N'x',1.5e3,NULL,sysdate,s.t.c,t.c,"Col","rownum",:p FROM t`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if par, err := fields[9].Field.Params(); err != nil || par.Name != ":p" {
+		t.Errorf("Value.Params() = %v, %v", par, err)
+	}
+	if _, err := fields[0].Field.Params(); err == nil {
+		t.Error("Value.Params() of a string should fail")
+	}
+}
+
+func TestMarshalValueNodes(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+		err   bool
+	}{
+		//没有原文的字符串按内容加上引号
+		{StringLiteral{Value: "it's"}, "'it''s'", false},
+		{ColumnRef{Schema: "s", Table: "t", Column: "c", OuterJoin: true}, "s.t.c(+)", false},
+		{NullLiteral{}, "NULL", false},
+		{ColumnRef{Table: "t"}, "", true},
+		{Params{Name: "p"}, "", true},
+		{Params{}, "", true},
+		{struct{}{}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := marshalValue(&marshaler{}, Value{Value: tt.value}, true)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("marshalValue(%#v) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+// paramsCase Params、DeleteParams、ExpandParams的测试用例，deleted是删除参数delete后的SQL，expanded是把参数expand扩展成3个后的SQL
+type paramsCase struct {
+	sql      string
+	params   []string
+	delete   string
+	deleted  string
+	expand   string
+	expanded string
+}
+
+func testParams(t *testing.T, tests []paramsCase) {
+	t.Helper()
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		if got := paramNames(stmt.Params()); !reflect.DeepEqual(got, tt.params) {
+			t.Errorf("%q: Params() = %v, want %v", tt.sql, got, tt.params)
+		}
+		if tt.delete != "" {
+			stmt, _ := Unmarshal(tt.sql)
+			stmt.DeleteParams([]Params{{Name: tt.delete}})
+			if got, err := Marshal(stmt); err != nil || got != tt.deleted {
+				t.Errorf("%q: delete %s = %q, %v, want %q", tt.sql, tt.delete, got, err, tt.deleted)
+			}
+		}
+		if tt.expand != "" {
+			stmt, _ := Unmarshal(tt.sql)
+			stmt.ExpandParams(Params{Name: tt.expand}, 3)
+			if got, err := Marshal(stmt); err != nil || got != tt.expanded {
+				t.Errorf("%q: expand %s = %q, %v, want %q", tt.sql, tt.expand, got, err, tt.expanded)
+			}
+		}
+	}
+}
+
+func TestExpressions(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		//原SQL中的括号保留
+		{"select a + b * c, (a + b) * c, ((a)) from t", "SELECT a+b*c,(a+b)*c,((a)) FROM t"},
+		{"select a - (b - c), a - b - c, (a - b) - c from t", "SELECT a-(b-c),a-b-c,(a-b)-c FROM t"},
+		{"select -a, - -a, a - -1, 2*-3, -(a+b) from t", "SELECT -a,- -a,a- -1,2*-3,-(a+b) FROM t"},
+		{"select a / (b * c), a || b || 'x' from t", "SELECT a/(b*c),a||b||'x' FROM t"},
+	}
+	for _, tt := range tests {
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+	stmt, err := Unmarshal("select a + b * c from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, ok := stmt.Ast.(Select).Body.(SelectItem).Field[0].Field.Value.(BinaryExpr)
+	if !ok || expr.Op != "+" {
+		t.Fatalf("got %#v, want a+(b*c)", expr)
+	}
+	if right, ok := expr.Right.Value.(BinaryExpr); !ok || right.Op != "*" {
+		t.Errorf("right operand = %#v, want b*c", expr.Right.Value)
+	}
+}
+
+func TestExpressionParams(t *testing.T) {
+	testParams(t, []paramsCase{
+		//运算中的参数被删除时删除整个条件
+		{"select a from t where a + :p * 2 > :q and b = 1", []string{":p", ":q"}, ":p", "SELECT a FROM t WHERE b=1", ":q", "SELECT a FROM t WHERE a+:p*2>:q AND b=1"},
+		{"select a from t where x in (:p) and y = -:q", []string{":p", ":q"}, ":q", "SELECT a FROM t WHERE x IN(:p)", ":p", "SELECT a FROM t WHERE x IN(:p0,:p1,:p2) AND y=-:q"},
+	})
+}
+
+func TestMarshalExpressionError(t *testing.T) {
+	tests := []interface{}{
+		BinaryExpr{Op: "%", Left: Value{Value: "a"}, Right: Value{Value: "b"}},
+		BinaryExpr{Op: "+", Left: Value{Value: ColumnRef{}}, Right: Value{Value: "b"}},
+		UnaryExpr{Op: "!", Operand: Value{Value: "a"}},
+	}
+	for _, value := range tests {
+		if got, err := marshalValue(&marshaler{}, Value{Value: value}, true); err == nil {
+			t.Errorf("marshalValue(%#v) = %q, should fail", value, got)
+		}
+	}
+}
+
+func TestMarshalBinaryExprPrecedence(t *testing.T) {
+	col := func(name string) Value { return Value{Value: ColumnRef{Column: name}} }
+	tests := []struct {
+		expr BinaryExpr
+		want string
+	}{
+		//语法树中没有ParenExpr时，按优先级给运算项加上括号
+		{BinaryExpr{Op: "*", Left: Value{Value: BinaryExpr{Op: "+", Left: col("a"), Right: col("b")}}, Right: col("c")}, "(a+b)*c"},
+		{BinaryExpr{Op: "-", Left: col("a"), Right: Value{Value: BinaryExpr{Op: "-", Left: col("b"), Right: col("c")}}}, "a-(b-c)"},
+		{BinaryExpr{Op: "-", Left: Value{Value: BinaryExpr{Op: "-", Left: col("a"), Right: col("b")}}, Right: col("c")}, "a-b-c"},
+	}
+	for _, tt := range tests {
+		if got, err := marshalBinaryExpr(&marshaler{}, tt.expr); err != nil || got != tt.want {
+			t.Errorf("marshalBinaryExpr = %q, %v, want %q", got, err, tt.want)
+		}
+	}
+}
+
+func TestWithClause(t *testing.T) {
+	tests := []struct {
+		where string //WITH出现的位置
+		sql   string
+		want  string
+	}{
+		{"statement", "with a as (select x from t), b (c, d) as (select 1, 2 from dual) select * from a, b",
+			"WITH a AS (SELECT x FROM t),b(c,d) AS (SELECT 1,2 FROM dual) SELECT * FROM a,b"},
+		{"from subquery", "select * from (with a as (select 1 from dual) select * from a)",
+			"SELECT * FROM (WITH a AS (SELECT 1 FROM dual) SELECT * FROM a)"},
+		{"in subquery", "select * from t where x in (with a as (select 1 y from dual) select y from a)",
+			"SELECT * FROM t WHERE x IN(WITH a AS (SELECT 1 y FROM dual) SELECT y FROM a)"},
+		//后面的命名子查询可以引用前面的
+		{"chained", "with a as (select 1 x from dual), b as (select x from a) select x from b",
+			"WITH a AS (SELECT 1 x FROM dual),b AS (SELECT x FROM a) SELECT x FROM b"},
+	}
+	for _, tt := range tests {
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.where, got, tt.want)
+		}
+	}
+}
+
+func TestWithRecursiveClauses(t *testing.T) {
+	sql := "with t (id, pid) as (select id, pid from x union all select x.id, x.pid from x, t where x.pid = t.id) " +
+		"search breadth first by id desc, pid set ord cycle id, pid set is_cycle to 'Y' default 'N' select * from t"
+	stmt, err := Unmarshal(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := stmt.Ast.(Select).With.Item[0]
+	if item.Name != "t" || !reflect.DeepEqual(item.Field, []string{"id", "pid"}) {
+		t.Errorf("name %q fields %q", item.Name, item.Field)
+	}
+	if item.Search.Mode != "BREADTH" || item.Search.Set != "ord" || len(item.Search.By.Item) != 2 || item.Search.By.Item[0].Direction != "DESC" {
+		t.Errorf("search = %+v", item.Search)
+	}
+	if !reflect.DeepEqual(item.Cycle.Field, []string{"id", "pid"}) || item.Cycle.Set != "is_cycle" {
+		t.Errorf("cycle = %+v", item.Cycle)
+	}
+	if to, ok := item.Cycle.To.Value.(StringLiteral); !ok || to.Value != "Y" {
+		t.Errorf("cycle to = %#v", item.Cycle.To.Value)
+	}
+	//SEARCH、CYCLE的关键词跟随关键词的大小写
+	got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: KeywordLower})
+	want := "with t(id,pid) as (select id,pid from x union all select x.id,x.pid from x,t where x.pid=t.id) " +
+		"search breadth first by id desc,pid set ord cycle id,pid set is_cycle to 'Y' default 'N' select * from t"
+	if err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestWithParamsOrder(t *testing.T) {
+	//参数按原SQL中的顺序返回，命名子查询里的参数在主查询前面
+	stmt, err := Unmarshal("with a as (select x from t where y = :a), b as (select x from s where z in (:b)) select * from a, b where a.x = :c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paramNames(stmt.Params()); !reflect.DeepEqual(got, []string{":a", ":b", ":c"}) {
+		t.Errorf("Params() = %v", got)
+	}
+	//只删除所在的命名子查询里的条件；等号后面的参数不扩展
+	stmt.DeleteParams([]Params{{Name: ":b"}})
+	stmt.ExpandParams(Params{Name: ":c"}, 2)
+	want := "WITH a AS (SELECT x FROM t WHERE y=:a),b AS (SELECT x FROM s) SELECT * FROM a,b WHERE a.x=:c"
+	if got, err := Marshal(stmt); err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestWithSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql      string
+		code     ErrorCode
+		expected []string
+		column   int
+	}{
+		{"with a as select 1 from dual select * from a", ErrMissingLeftParen, []string{"("}, 11},
+		{"with a as (select 1 from dual), select * from a", ErrMissingName, nil, 33},
+		{"with a(x,) as (select 1 from dual) select * from a", ErrMissingName, nil, 10},
+		{"with a as (select 1 from dual) search sideways first by a set o select * from a", ErrMissingKeyword, []string{"DEPTH", "BREADTH"}, 39},
+		{"with a as (select 1 from dual) search depth first by a select * from a", ErrMissingKeyword, []string{"SET"}, 56},
+		{"with a as (select 1 from dual) cycle a set b to 1 select * from a", ErrMissingKeyword, []string{"DEFAULT"}, 51},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%q: got %v, want *ParseError", tt.sql, err)
+			continue
+		}
+		if perr.Code != tt.code || perr.Pos.Column != tt.column || !reflect.DeepEqual(perr.Expected, tt.expected) && len(perr.Expected)+len(tt.expected) != 0 {
+			t.Errorf("%q: got %s %q at column %d, want %s %q at column %d", tt.sql, perr.Code, perr.Expected, perr.Pos.Column, tt.code, tt.expected, tt.column)
+		}
+	}
+}
+
+func TestMarshalWithItemErrors(t *testing.T) {
+	query := Select{Body: SelectItem{Field: []SelectField{{Field: Value{Value: NumberLiteral{Value: "1"}}}}, Table: []SelectTable{{Table: "dual"}}}}
+	order := OrderBy{Item: []OrderItem{{Expr: Value{Value: ColumnRef{Column: "a"}}}}}
+	tests := map[string]WithItem{
+		"no name":            {Query: query},
+		"empty query":        {Name: "a"},
+		"search without set": {Name: "a", Query: query, Search: WithSearch{Mode: "DEPTH", By: order}},
+		"search without by":  {Name: "a", Query: query, Search: WithSearch{Mode: "DEPTH", Set: "ord"}},
+		"cycle without set":  {Name: "a", Query: query, Cycle: WithCycle{Field: []string{"a"}}},
+	}
+	for name, item := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalWithItem(&marshaler{}, item); err == nil {
+				t.Errorf("marshalWithItem = %q, should fail", got)
+			}
+		})
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"select distinct a, b from t", "SELECT DISTINCT a,b FROM t"},
+		{"select unique a from t", "SELECT UNIQUE a FROM t"},
+		{"select All a from t", "SELECT ALL a FROM t"},
+		{"select count(distinct a), count(*), sum(all b), count(unique c) from t", "SELECT count(DISTINCT a),count(*),sum(ALL b),count(UNIQUE c) FROM t"},
+		{"select distinct count(distinct a) over (partition by b) from t", "SELECT DISTINCT count(DISTINCT a) OVER (PARTITION BY b) FROM t"},
+	}
+	for _, tt := range tests {
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+	stmt, err := Unmarshal("select distinct count(*), count(distinct a) from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sel := stmt.Ast.(Select).Body.(SelectItem)
+	if sel.Distinct != "DISTINCT" {
+		t.Errorf("Distinct = %q, want DISTINCT", sel.Distinct)
+	}
+	count := sel.Field[0].Field.Value.(Function)
+	if _, ok := count.Params[0].Value.(Star); !ok || len(count.Params) != 1 || count.Quantifier != "" {
+		t.Errorf("count(*) = %#v", count)
+	}
+	if f := sel.Field[1].Field.Value.(Function); f.Quantifier != "DISTINCT" || len(f.Params) != 1 {
+		t.Errorf("count(distinct a) = %#v", f)
+	}
+}
+
+func TestDistinctKeywordCase(t *testing.T) {
+	//DISTINCT后面紧跟括号时括号属于第一个字段
+	stmt, err := Unmarshal("select Distinct(a), count(Unique b) from t where c in (select all d from u)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := stmt.Ast.(Select).Body.(SelectItem).Field[0].Field.Value
+	if _, ok := first.(ParenExpr); !ok {
+		t.Errorf("DISTINCT(a) field = %#v, want ParenExpr", first)
+	}
+	tests := []struct {
+		kc   KeywordCase
+		want string
+	}{
+		{KeywordUpper, "SELECT DISTINCT (a),count(UNIQUE b) FROM t WHERE c IN(SELECT ALL d FROM u)"},
+		{KeywordLower, "select distinct (a),count(unique b) from t where c in(select all d from u)"},
+		{KeywordAsWritten, "select Distinct (a),count(Unique b) from t where c in(select all d from u)"},
+	}
+	for _, tt := range tests {
+		if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: tt.kc}); err != nil || got != tt.want {
+			t.Errorf("case %v: got %q, %v, want %q", tt.kc, got, err, tt.want)
+		}
+	}
+	//函数参数中的参数不受DISTINCT影响
+	testParams(t, []paramsCase{
+		{"select count(distinct :p), b from t where c in (:q)", []string{":p", ":q"}, ":q", "SELECT count(DISTINCT :p),b FROM t", ":q", "SELECT count(DISTINCT :p),b FROM t WHERE c IN(:q0,:q1,:q2)"},
+	})
+}
+
+func TestDistinctSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select count(distinct) from t", ErrInvalidValue, 22},
+		{"select count(all) from t", ErrInvalidValue, 17},
+		{"select count(* from t", ErrMissingRightParen, 16},
+		{"select distinct from t", ErrUnexpectedToken, 17},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+	//有DISTINCT没有参数的函数不能序列化
+	f := Function{Name: "count", Quantifier: "DISTINCT"}
+	if got, err := marshalFunction(&marshaler{}, f); err == nil {
+		t.Errorf("marshalFunction(%#v) = %q, should fail", f, got)
+	}
+}
+
+func TestHierarchical(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{
+			"select id, level, sys_connect_by_path(name, '/') path, connect_by_root name root, connect_by_isleaf from emp start with pid is null connect by nocycle prior id = pid order siblings by name",
+			"SELECT id,level,sys_connect_by_path(name,'/') path,CONNECT_BY_ROOT name root,connect_by_isleaf FROM emp START WITH pid IS NULL CONNECT BY NOCYCLE PRIOR id=pid ORDER SIBLINGS BY name",
+		},
+		//CONNECT BY可以写在START WITH前面，序列化时统一放在后面
+		{"select id from emp connect by prior id = pid and level <= 3 start with id = 1", "SELECT id FROM emp START WITH id=1 CONNECT BY PRIOR id=pid AND level<=3"},
+		{"select id from emp connect by prior (id) = pid", "SELECT id FROM emp CONNECT BY PRIOR (id)=pid"},
+	}
+	for _, tt := range tests {
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+	stmt, err := Unmarshal(tests[0].sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sel := stmt.Ast.(Select).Body.(SelectItem)
+	if !sel.ConnectBy.NoCycle || len(sel.StartWith.Equation) != 1 {
+		t.Errorf("got StartWith %+v ConnectBy %+v", sel.StartWith, sel.ConnectBy)
+	}
+	if order, ok := sel.Order.(OrderBy); !ok || !order.Siblings {
+		t.Errorf("Order = %#v, want ORDER SIBLINGS BY", sel.Order)
+	}
+	if _, ok := sel.Field[1].Field.Value.(PseudoColumn); !ok {
+		t.Errorf("level = %#v, want PseudoColumn", sel.Field[1].Field.Value)
+	}
+	if expr, ok := sel.Field[3].Field.Value.(UnaryExpr); !ok || expr.Op != "CONNECT_BY_ROOT" {
+		t.Errorf("connect_by_root = %#v", sel.Field[3].Field.Value)
+	}
+}
+
+func TestHierarchicalPrior(t *testing.T) {
+	//PRIOR可以在比较符号的任意一边，也可以在查询字段里
+	stmt, err := Unmarshal("select id, prior name from emp where a = 1 connect by pid = prior id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sel := stmt.Ast.(Select).Body.(SelectItem)
+	if expr, ok := sel.Field[1].Field.Value.(UnaryExpr); !ok || expr.Op != "PRIOR" {
+		t.Errorf("prior name = %#v", sel.Field[1].Field.Value)
+	}
+	eq := sel.ConnectBy.Condition.Equation[0].Equation.(EquationNorm)
+	if expr, ok := eq.Right.Value.(UnaryExpr); !ok || expr.Op != "PRIOR" || sel.ConnectBy.NoCycle {
+		t.Errorf("connect by = %#v", eq)
+	}
+	want := "select id,prior name from emp where a=1 connect by pid=prior id"
+	if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: KeywordLower}); err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+	if got := remarshal(t, "select level from dual connect by level <= 10"); got != "SELECT level FROM dual CONNECT BY level<=10" {
+		t.Errorf("level: got %q", got)
+	}
+}
+
+func TestHierarchicalDeleteParams(t *testing.T) {
+	tests := []struct {
+		sql    string
+		delete string
+		want   string
+	}{
+		//START WITH的条件都被删除时去掉整个子句
+		{"select id from emp start with id = :p connect by prior id = pid and dept in (:d)", ":p", "SELECT id FROM emp CONNECT BY PRIOR id=pid AND dept IN(:d)"},
+		//CONNECT BY只去掉含参数的条件，保留NOCYCLE
+		{"select id from emp connect by nocycle prior id = pid and dept = :d", ":d", "SELECT id FROM emp CONNECT BY NOCYCLE PRIOR id=pid"},
+		{"select id from emp connect by nocycle prior id = :p", ":p", "SELECT id FROM emp"},
+		{"select id from emp start with id = :p connect by prior id = pid order siblings by :p, id", ":p", "SELECT id FROM emp CONNECT BY PRIOR id=pid ORDER SIBLINGS BY id"},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		stmt.DeleteParams([]Params{{Name: tt.delete}})
+		if got, err := Marshal(stmt); err != nil || got != tt.want {
+			t.Errorf("%q delete %s: got %q, %v, want %q", tt.sql, tt.delete, got, err, tt.want)
+		}
+	}
+	testParams(t, []paramsCase{{
+		"select id from emp start with id = :p connect by prior id = pid and dept in (:d)",
+		[]string{":p", ":d"},
+		"", "",
+		":d", "SELECT id FROM emp START WITH id=:p CONNECT BY PRIOR id=pid AND dept IN(:d0,:d1,:d2)",
+	}})
+}
+
+func TestHierarchicalSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select prior from t", ErrUnexpectedToken, 14},
+		{"select connect_by_root from emp", ErrUnexpectedToken, 24},
+		{"select id from emp connect by nocycle", ErrUnexpectedEOF, 38},
+		{"select id from emp start with connect by prior id = pid", ErrInvalidComparison, 39},
+		{"select id from emp order siblings name", ErrInvalidOrder, 26},
+		//START WITH、CONNECT BY各自只能出现一次
+		{"select id from emp connect by prior id = pid connect by x = 1", ErrUnexpectedToken, 46},
+		{"select id from emp start with a = 1 connect by prior id = pid start with x = 1", ErrUnexpectedToken, 63},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+}
+
+func TestComparePredicates(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"select a from t where a not between 1 and 5 and b between :p and :q", "SELECT a FROM t WHERE a NOT BETWEEN 1 AND 5 AND b BETWEEN :p AND :q"},
+		{`select a from t where name like 'a\_%' escape '\' and b not like :p escape '!'`, `SELECT a FROM t WHERE name LIKE 'a\_%' ESCAPE '\' AND b NOT LIKE :p ESCAPE '!'`},
+		{"select a from t where a likec 'x' or a like2 'y' or a not like4 'z'", "SELECT a FROM t WHERE a LIKEC 'x' OR a LIKE2 'y' OR a NOT LIKE4 'z'"},
+		{"select a from t where x is nan or y is not nan or z is infinite or w is not infinite", "SELECT a FROM t WHERE x IS NAN OR y IS NOT NAN OR z IS INFINITE OR w IS NOT INFINITE"},
+		{"select a from t where a ^= 1 and b ~= 2 and c != 3 and d <> 4", "SELECT a FROM t WHERE a^=1 AND b~=2 AND c!=3 AND d<>4"},
+	}
+	for _, tt := range tests {
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+	stmt, err := Unmarshal(tests[1].sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eq := stmt.Ast.(Select).Body.(SelectItem).Where.Equation[1].Equation.(EquationOther)
+	if eq.Operator != "NOT LIKE" || eq.Escape.Value == nil {
+		t.Errorf("got %#v, want NOT LIKE with ESCAPE", eq)
+	}
+	//BETWEEN里的AND不是条件的连接符
+	stmt, err = Unmarshal("select a from t where a Not Between 1 And 2 And b Is Not Nan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	where := stmt.Ast.(Select).Body.(SelectItem).Where
+	if between, ok := where.Equation[0].Equation.(EquationBetween); len(where.Equation) != 2 || !ok || !between.Not {
+		t.Errorf("where = %#v", where)
+	}
+	want := "select a from t where a Not Between 1 And 2 And b Is Not Nan"
+	if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: KeywordAsWritten}); err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestComparePredicateDeleteParams(t *testing.T) {
+	testParams(t, []paramsCase{
+		{"select a from t where a not between :p and 5", []string{":p"}, ":p", "SELECT a FROM t", "", ""},
+		//ESCAPE中的参数被删除时删除整个条件
+		{"select a from t where a like 'x' escape :p and b = 1", []string{":p"}, ":p", "SELECT a FROM t WHERE b=1", "", ""},
+		//LIKE后面只能有一个值，参数不扩展
+		{
+			"select a from t where b not like :p escape '!' and c in (:p)",
+			[]string{":p", ":p"},
+			"", "",
+			":p", "SELECT a FROM t WHERE b NOT LIKE :p ESCAPE '!' AND c IN(:p0,:p1,:p2)",
+		},
+		{
+			"select a from t where a between (select min(x) from s where y in (:p)) and 3",
+			[]string{":p"},
+			":p", "SELECT a FROM t WHERE a BETWEEN (SELECT min(x) FROM s) AND 3",
+			":p", "SELECT a FROM t WHERE a BETWEEN (SELECT min(x) FROM s WHERE y IN(:p0,:p1,:p2)) AND 3",
+		},
+	})
+}
+
+func TestComparePredicateSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select a from t where a not between 1", ErrMissingKeyword, 38},
+		{"select a from t where a between b and c between d and e", ErrUnexpectedToken, 41},
+		{"select a from t where a like 'x' escape", ErrUnexpectedEOF, 40},
+		{"select a from t where a is not", ErrMissingKeyword, 31},
+		{"select a from t where a not", ErrInvalidComparison, 25},
+		//比较符号中间不能有空格
+		{"select a from t where a ^ = 1", ErrIllegalCharacter, 25},
+		{"select a from t where a is nan escape 'x'", ErrUnexpectedToken, 32},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+}
+
+func TestMarshalComparePredicateErrors(t *testing.T) {
+	col := Value{Value: ColumnRef{Column: "a"}}
+	str := Value{Value: StringLiteral{Value: "x"}}
+	if got, err := marshalEquationNorm(&marshaler{}, EquationNorm{Left: col, Operator: "=~", Right: col}); err == nil {
+		t.Errorf("marshalEquationNorm with =~ = %q, should fail", got)
+	}
+	tests := map[string]EquationOther{
+		"unknown operator":  {Left: col, Operator: "ILIKE", Right: []Value{str}},
+		"escape on is nan":  {Left: col, Operator: "IS NAN", Escape: str},
+		"escape on in list": {Left: col, Operator: "IN", Right: []Value{col}, Escape: str},
+	}
+	for name, eq := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalEquationOther(&marshaler{}, eq); err == nil {
+				t.Errorf("marshalEquationOther = %q, should fail", got)
+			}
+		})
+	}
+}
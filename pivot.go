@@ -0,0 +1,367 @@
+package sqlParser
+
+import (
+	"errors"
+	"strings"
+)
+
+// Pivot 表的PIVOT、UNPIVOT子句，它跟在表后面、别名前面，例：
+// T PIVOT (SUM(AMT) AS S FOR MONTH IN (1 AS JAN, 2 AS FEB)) P；
+// T UNPIVOT INCLUDE NULLS ((AMT, QTY) FOR MONTH IN ((JAN_AMT, JAN_QTY) AS 1, (FEB_AMT, FEB_QTY) AS 2))
+type Pivot struct {
+	Unpivot   bool
+	XML       bool        //PIVOT XML
+	Nulls     string      //UNPIVOT的INCLUDE、EXCLUDE，没有写明时为空
+	Aggregate []PivotItem //PIVOT的聚合函数及其别名，Value只有一个值
+	Column    []string    //UNPIVOT的值字段，多个时被括号括起
+	For       []string    //FOR后面的字段，多个时被括号括起
+	In        []PivotItem //IN里的值，多个值时被括号括起
+	Any       bool        //PIVOT XML的IN (ANY)，FOR后面的每个字段一个ANY
+	Query     *Select     //PIVOT XML的IN子查询，没有时为nil
+	Pos       Pos
+}
+
+// PivotItem PIVOT、UNPIVOT中的单项
+type PivotItem struct {
+	Value   []Value //单个值，或者被括号括起的多个值
+	Alias   string  //PIVOT的别名
+	Literal []Value //UNPIVOT中AS后面的常量，多个时被括号括起
+	Pos     Pos
+}
+
+// isPivotStart 当前词法单元是否是PIVOT、UNPIVOT子句的开始，它们不能被当作别名
+func (p *parser) isPivotStart() bool {
+	next := p.peekN(1)
+	isParen := next.Type == TokenPunct && next.Value == "("
+	switch {
+	case p.isKeyword("PIVOT"):
+		return isParen || p.isKeywordAt(1, "XML")
+	case p.isKeyword("UNPIVOT"):
+		return isParen || p.isKeywordAt(1, "INCLUDE") || p.isKeywordAt(1, "EXCLUDE")
+	}
+	return false
+}
+
+// getPivot 解析PIVOT、UNPIVOT子句，调用前需用isPivotStart判断
+func getPivot(p *parser) (pivot *Pivot, err error) {
+	start := p.peek().Start
+	pivot = &Pivot{Unpivot: p.isKeyword("UNPIVOT")}
+	p.keyword()
+	if pivot.Unpivot {
+		if p.isAnyKeyword("INCLUDE", "EXCLUDE") {
+			pivot.Nulls = strings.ToUpper(p.keyword().Value)
+			if err = p.expectKeyword("NULLS"); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		pivot.XML = p.acceptKeyword("XML")
+	}
+	if err = p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	if pivot.Unpivot {
+		if pivot.Column, err = getPivotColumns(p); err != nil {
+			return nil, err
+		}
+	} else {
+		for {
+			itemStart := p.peek().Start
+			val, err := getValue(p)
+			if err != nil {
+				return nil, err
+			}
+			alias, err := getAlias(p)
+			if err != nil {
+				return nil, err
+			}
+			pivot.Aggregate = append(pivot.Aggregate, PivotItem{Value: []Value{val}, Alias: alias, Pos: p.posFrom(itemStart)})
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+	}
+	if err = p.expectKeyword("FOR"); err != nil {
+		return nil, err
+	}
+	if pivot.For, err = getPivotColumns(p); err != nil {
+		return nil, err
+	}
+	if err = p.expectKeyword("IN"); err != nil {
+		return nil, err
+	}
+	if err = p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	switch {
+	case !pivot.XML && !pivot.Unpivot && (p.isSelectStart(0) || p.isKeyword("ANY")):
+		//子查询、ANY只能用于PIVOT XML
+		return nil, p.unexpected()
+	case pivot.XML && p.isSelectStart(0):
+		sel, err := parserSelect(p)
+		if err != nil {
+			return nil, err
+		}
+		pivot.Query = &sel
+	case pivot.XML && p.isKeyword("ANY"):
+		//IN (ANY)，FOR后面有多个字段时每个字段一个ANY：IN (ANY, ANY)
+		pivot.Any = true
+		p.keyword()
+		for p.acceptPunct(",") {
+			if err = p.expectKeyword("ANY"); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if pivot.In, err = getPivotInItems(p, pivot.Unpivot); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	pivot.Pos = p.posFrom(start)
+	return pivot, nil
+}
+
+// getPivotColumns 解析单个字段，或者被括号括起的多个字段
+func getPivotColumns(p *parser) (columns []string, err error) {
+	if !p.acceptPunct("(") {
+		name, err := getObjectName(p)
+		if err != nil {
+			return nil, err
+		}
+		return []string{name}, nil
+	}
+	if columns, err = getNameList(p); err != nil {
+		return nil, err
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// getPivotInItems 解析IN里逗号隔开的值，PIVOT的值后面是别名，UNPIVOT的值后面是AS常量
+func getPivotInItems(p *parser, unpivot bool) (items []PivotItem, err error) {
+	for {
+		var item PivotItem
+		start := p.peek().Start
+		if item.Value, err = getValueList(p); err != nil {
+			return nil, err
+		}
+		if !unpivot {
+			if item.Alias, err = getAlias(p); err != nil {
+				return nil, err
+			}
+		} else if p.acceptKeyword("AS") {
+			if item.Literal, err = getValueList(p); err != nil {
+				return nil, err
+			}
+		}
+		item.Pos = p.posFrom(start)
+		items = append(items, item)
+		if !p.acceptPunct(",") {
+			return items, nil
+		}
+	}
+}
+
+// marshalPivot 序列化PIVOT、UNPIVOT子句
+func marshalPivot(m *marshaler, pivot Pivot) (retSQL string, err error) {
+	if len(pivot.For) == 0 {
+		return "", errors.New("PIVOT缺失FOR字段")
+	}
+	if pivot.Unpivot {
+		retSQL = m.kw("UNPIVOT") + " "
+		if pivot.Nulls != "" {
+			retSQL += m.kw(pivot.Nulls+" NULLS") + " "
+		}
+		if len(pivot.Column) == 0 {
+			return "", errors.New("UNPIVOT缺失值字段")
+		}
+		retSQL += "(" + marshalPivotColumns(pivot.Column)
+	} else {
+		retSQL = m.kw("PIVOT") + " "
+		if pivot.XML {
+			retSQL += m.kw("XML") + " "
+		}
+		if len(pivot.Aggregate) == 0 {
+			return "", errors.New("PIVOT缺失聚合函数")
+		}
+		items, err := marshalPivotItems(m, pivot.Aggregate)
+		if err != nil {
+			return "", err
+		}
+		retSQL += "(" + items
+	}
+	retSQL += " " + m.kw("FOR") + " " + marshalPivotColumns(pivot.For) + " " + m.kw("IN") + " ("
+	if !pivot.XML && (pivot.Query != nil || pivot.Any) {
+		return "", errors.New("PIVOT的IN子查询、ANY只能用于PIVOT XML")
+	}
+	switch {
+	case pivot.Query != nil:
+		sel, err := marshalSelect(m, *pivot.Query)
+		if err != nil {
+			return "", err
+		}
+		retSQL += sel
+	case pivot.Any:
+		retSQL += strings.TrimRight(strings.Repeat(m.kw("ANY")+",", len(pivot.For)), ",")
+	default:
+		if len(pivot.In) == 0 {
+			return "", errors.New("PIVOT的IN不能为空")
+		}
+		items, err := marshalPivotItems(m, pivot.In)
+		if err != nil {
+			return "", err
+		}
+		retSQL += items
+	}
+	return retSQL + "))", nil
+}
+
+// marshalPivotColumns 序列化单个字段，多个字段时用括号括起
+func marshalPivotColumns(columns []string) string {
+	if len(columns) == 1 {
+		return columns[0]
+	}
+	return "(" + strings.Join(columns, ",") + ")"
+}
+
+// marshalPivotItems 序列化逗号隔开的PIVOT、UNPIVOT单项
+func marshalPivotItems(m *marshaler, items []PivotItem) (retSQL string, err error) {
+	for _, item := range items {
+		val, err := marshalPivotValues(m, item.Value)
+		if err != nil {
+			return "", err
+		}
+		if item.Alias != "" {
+			val += " " + item.Alias
+		}
+		if len(item.Literal) != 0 {
+			literal, err := marshalPivotValues(m, item.Literal)
+			if err != nil {
+				return "", err
+			}
+			val += " " + m.kw("AS") + " " + literal
+		}
+		retSQL += val + ","
+	}
+	return strings.TrimRight(retSQL, ","), nil
+}
+
+// marshalPivotValues 序列化单个值，多个值时用括号括起
+func marshalPivotValues(m *marshaler, values []Value) (retSQL string, err error) {
+	if len(values) == 0 {
+		return "", errors.New("PIVOT的值不能为空")
+	}
+	for _, item := range values {
+		val, err := marshalValue(m, item, true)
+		if err != nil {
+			return "", err
+		}
+		retSQL += val + ","
+	}
+	retSQL = strings.TrimRight(retSQL, ",")
+	if len(values) > 1 {
+		retSQL = "(" + retSQL + ")"
+	}
+	return retSQL, nil
+}
+
+func getParamsByPivot(pivot Pivot) (pars []Params) {
+	for _, items := range [][]PivotItem{pivot.Aggregate, pivot.In} {
+		for _, item := range items {
+			for _, val := range item.Value {
+				pars = append(pars, getParamsBySelectValue(val)...)
+			}
+			for _, val := range item.Literal {
+				pars = append(pars, getParamsBySelectValue(val)...)
+			}
+		}
+	}
+	if pivot.Query != nil {
+		pars = append(pars, getParamsBySelect(*pivot.Query)...)
+	}
+	return pars
+}
+
+// deleteParamsByPivot 和分组一样，聚合函数、IN里的单项中有参数被删除时只删除该项，全部删除时删除整个子句
+func deleteParamsByPivot(pivot Pivot, pars []Params) *Pivot {
+	pivot.Aggregate = deleteParamsByPivotItems(pivot.Aggregate, pars)
+	pivot.In = deleteParamsByPivotItems(pivot.In, pars)
+	if pivot.Query != nil {
+		sel := deleteParamsBySelect(*pivot.Query, pars)
+		pivot.Query = &sel
+	}
+	if (!pivot.Unpivot && len(pivot.Aggregate) == 0) || (pivot.Query == nil && !pivot.Any && len(pivot.In) == 0) {
+		return nil
+	}
+	return &pivot
+}
+
+func deleteParamsByPivotItems(items []PivotItem, pars []Params) (ret []PivotItem) {
+	for _, item := range items {
+		var ok bool
+		if item.Value, ok = deleteParamsByPivotValues(item.Value, pars); !ok {
+			continue
+		}
+		if item.Literal, ok = deleteParamsByPivotValues(item.Literal, pars); !ok {
+			continue
+		}
+		ret = append(ret, item)
+	}
+	return ret
+}
+
+// deleteParamsByPivotValues 有参数被删除时返回false
+func deleteParamsByPivotValues(values []Value, pars []Params) ([]Value, bool) {
+	if values == nil {
+		return nil, true
+	}
+	ret := make([]Value, len(values))
+	for i, item := range values {
+		if ret[i] = deleteParamsBySelectValue(item, pars); ret[i].Value == nil {
+			return nil, false
+		}
+	}
+	return ret, true
+}
+
+func expandParamsByPivot(pivot Pivot, params Params, count int) *Pivot {
+	pivot.Aggregate = expandParamsByPivotItems(pivot.Aggregate, params, count)
+	pivot.In = expandParamsByPivotItems(pivot.In, params, count)
+	if pivot.Query != nil {
+		sel := expandParamsBySelect(*pivot.Query, params, count)
+		pivot.Query = &sel
+	}
+	return &pivot
+}
+
+func expandParamsByPivotItems(items []PivotItem, params Params, count int) []PivotItem {
+	ret := make([]PivotItem, len(items))
+	for i, item := range items {
+		ret[i] = item
+		ret[i].Value = expandParamsByPivotValues(item.Value, params, count)
+		ret[i].Literal = expandParamsByPivotValues(item.Literal, params, count)
+	}
+	return ret
+}
+
+func expandParamsByPivotValues(values []Value, params Params, count int) []Value {
+	if values == nil {
+		return nil
+	}
+	ret := make([]Value, len(values))
+	for i, item := range values {
+		ret[i] = item
+		if val := expandParamsBySelectValue(item, params, count); val.Value != nil {
+			ret[i] = val
+		}
+	}
+	return ret
+}
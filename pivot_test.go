@@ -0,0 +1,124 @@
+package sqlParser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPivotForms(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"select * from sales pivot (sum(amt) as s, count(*) c for month in (1 as jan, 2 as feb)) p", "SELECT * FROM sales PIVOT (sum(amt) s,count(*) c FOR month IN (1 jan,2 feb)) p"},
+		{"select * from sales pivot (sum(amt) for (month, year) in ((1, 2020) as jan20, (2, 2020) feb20))", "SELECT * FROM sales PIVOT (sum(amt) FOR (month,year) IN ((1,2020) jan20,(2,2020) feb20))"},
+		{"select * from sales pivot xml (sum(amt) for (month, year) in (any, any))", "SELECT * FROM sales PIVOT XML (sum(amt) FOR (month,year) IN (ANY,ANY))"},
+		{"select * from sales pivot xml (sum(amt) for month in (select m from months))", "SELECT * FROM sales PIVOT XML (sum(amt) FOR month IN (SELECT m FROM months))"},
+		{
+			"select * from s unpivot include nulls ((amt, qty) for month in ((jan_amt, jan_qty) as 1, (feb_amt, feb_qty) as 2))",
+			"SELECT * FROM s UNPIVOT INCLUDE NULLS ((amt,qty) FOR month IN ((jan_amt,jan_qty) AS 1,(feb_amt,feb_qty) AS 2))",
+		},
+		{"select * from s unpivot (amt for month in (jan as 'JAN', feb)) u", "SELECT * FROM s UNPIVOT (amt FOR month IN (jan AS 'JAN',feb)) u"},
+		{"select * from s unpivot exclude nulls (amt for month in (jan))", "SELECT * FROM s UNPIVOT EXCLUDE NULLS (amt FOR month IN (jan))"},
+		//后面不是括号时PIVOT是普通的名字
+		{"select pivot from pivot", "SELECT pivot FROM pivot"},
+	}
+	for _, tt := range tests {
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+	stmt, err := Unmarshal(tests[4].sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pivot := stmt.Ast.(Select).Body.(SelectItem).Table[0].Pivot
+	if pivot == nil || !pivot.Unpivot || pivot.Nulls != "INCLUDE" || len(pivot.In) != 2 || len(pivot.In[0].Value) != 2 || len(pivot.In[0].Literal) != 1 {
+		t.Fatalf("got %+v", pivot)
+	}
+	if !reflect.DeepEqual(pivot.Column, []string{"amt", "qty"}) || !reflect.DeepEqual(pivot.For, []string{"month"}) {
+		t.Errorf("columns %q for %q", pivot.Column, pivot.For)
+	}
+}
+
+func TestPivotXML(t *testing.T) {
+	//FOR后面有几个字段就有几个ANY
+	stmt, err := Unmarshal("select * from sales Pivot Xml (sum(amt) for (month, year) in (any, any)) p inner join y on p.k = y.k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	join := stmt.Ast.(Select).Body.(SelectItem).Table[0].Table.(Join)
+	pivot := join.Left.Pivot
+	if pivot == nil || !pivot.XML || !pivot.Any || pivot.Query != nil || len(pivot.In) != 0 || join.Left.Alias != "p" {
+		t.Fatalf("got %+v", join.Left)
+	}
+	want := "select * from sales Pivot Xml (sum(amt) for (month,year) in (any,any)) p inner join y on p.k=y.k"
+	if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: KeywordAsWritten}); err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+	//不是XML时不能有ANY、子查询
+	for _, pivot := range []Pivot{*pivot, {Aggregate: pivot.Aggregate, For: pivot.For, Query: &Select{}}} {
+		pivot.XML = false
+		if got, err := marshalPivot(&marshaler{}, pivot); err == nil {
+			t.Errorf("marshalPivot(%+v) = %q, should fail", pivot, got)
+		}
+	}
+}
+
+func TestPivotDeleteParams(t *testing.T) {
+	testParams(t, []paramsCase{
+		//IN里的单项有参数被删除时只删除该项
+		{"select * from sales pivot (sum(amt) for month in (:p as a, 2 as b)) where x = :q", []string{":p", ":q"}, ":p", "SELECT * FROM sales PIVOT (sum(amt) FOR month IN (2 b)) WHERE x=:q", "", ""},
+		//全部删除时删除整个子句，表的别名保留
+		{"select * from sales pivot (sum(amt) for month in (:p as a)) s", []string{":p"}, ":p", "SELECT * FROM sales s", "", ""},
+		{"select * from s unpivot (amt for month in (jan as :p, feb as :q))", []string{":p", ":q"}, ":q", "SELECT * FROM s UNPIVOT (amt FOR month IN (jan AS :p))", "", ""},
+		{
+			"select * from sales pivot xml (sum(amt) for month in (select m from months where y in (:p)))",
+			[]string{":p"},
+			":p", "SELECT * FROM sales PIVOT XML (sum(amt) FOR month IN (SELECT m FROM months))",
+			":p", "SELECT * FROM sales PIVOT XML (sum(amt) FOR month IN (SELECT m FROM months WHERE y IN(:p0,:p1,:p2)))",
+		},
+	})
+}
+
+func TestPivotSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select * from sales pivot (sum(amt) month in (1))", ErrMissingKeyword, 43},
+		{"select * from sales pivot (sum(amt) for month in ())", ErrUnexpectedToken, 51},
+		{"select * from sales pivot (sum(amt) for month in (1)", ErrMissingRightParen, 53},
+		{"select * from s unpivot include (amt for month in (jan))", ErrMissingKeyword, 33},
+		{"select * from sales pivot xml (sum(amt) for month in (any, 1))", ErrMissingKeyword, 60},
+		//ANY、子查询只能用于PIVOT XML
+		{"select * from sales pivot (sum(amt) for month in (any))", ErrUnexpectedToken, 51},
+		{"select * from sales pivot (sum(amt) for month in (select m from months))", ErrUnexpectedToken, 51},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+}
+
+func TestMarshalPivotErrors(t *testing.T) {
+	sum := PivotItem{Value: []Value{{Value: Function{Name: "sum"}}}}
+	one := PivotItem{Value: []Value{{Value: NumberLiteral{Value: "1"}}}}
+	tests := map[string]Pivot{
+		"no for":            {Aggregate: []PivotItem{sum}, In: []PivotItem{one}},
+		"no aggregate":      {For: []string{"month"}, In: []PivotItem{one}},
+		"unpivot no column": {Unpivot: true, For: []string{"month"}, In: []PivotItem{one}},
+		"empty in":          {Aggregate: []PivotItem{sum}, For: []string{"month"}},
+		"empty in item":     {Aggregate: []PivotItem{sum}, For: []string{"month"}, In: []PivotItem{{}}},
+	}
+	for name, pivot := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalPivot(&marshaler{}, pivot); err == nil {
+				t.Errorf("marshalPivot = %q, should fail", got)
+			}
+		})
+	}
+}
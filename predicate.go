@@ -0,0 +1,207 @@
+package sqlParser
+
+import (
+	"errors"
+	"strings"
+)
+
+// Not 被NOT取反的条件，它可以出现在条件的任何位置，例：NOT (A = 1 OR B = 2)、NOT A LIKE 'X%'。NOT EXISTS是Exists，NOT IN、NOT LIKE仍然是EquationOther
+type Not struct {
+	Equation interface{} //和Equation.Equation一样
+	Pos      Pos
+}
+
+// PredicateFunction 返回布尔值的函数，它可以单独作为条件，例：REGEXP_LIKE(NAME, '^A', 'i')、LNNVL(A > 1)、
+// XMLEXISTS('/a/b' PASSING DOC)、JSON_EXISTS(DOC, '$.a' PASSING :P AS "v" FALSE ON ERROR)。后面还有比较符号时是普通的Function
+type PredicateFunction struct {
+	Name      string        //REGEXP_LIKE、LNNVL、XMLEXISTS、JSON_EXISTS
+	Params    []Value       //REGEXP_LIKE的参数，XMLEXISTS的XQuery，JSON_EXISTS的JSON、路径
+	Condition EquationList  //LNNVL的条件
+	Passing   []PassingItem //XMLEXISTS、JSON_EXISTS的PASSING，没有时为空
+	OnError   string        //JSON_EXISTS的TRUE、FALSE、ERROR ON ERROR，没有写明时为空
+	Pos       Pos
+}
+
+// PassingItem PASSING后面的单项，例：DOC AS "d"。XMLEXISTS的PASSING BY VALUE是默认的传值方式，生成SQL时省略BY VALUE
+type PassingItem struct {
+	Value Value
+	Alias string //AS后面的名称，没有时为空
+	Pos   Pos
+}
+
+// predicateFunctions 可以单独作为条件的函数
+var predicateFunctions = []string{"REGEXP_LIKE", "LNNVL", "XMLEXISTS", "JSON_EXISTS"}
+
+// isPredicateFunctionStart 当前词法单元是否是可以单独作为条件的函数
+func (p *parser) isPredicateFunctionStart() bool {
+	next := p.peekN(1)
+	return p.isAnyKeyword(predicateFunctions...) && next.Type == TokenPunct && next.Value == "("
+}
+
+// getPredicateFunction 解析返回布尔值的函数，调用前需用isPredicateFunctionStart判断
+func getPredicateFunction(p *parser) (fn PredicateFunction, err error) {
+	start := p.peek().Start
+	fn.Name = p.next().Value
+	p.next()
+	if strings.EqualFold(fn.Name, "LNNVL") {
+		if fn.Condition, err = getEquationList(p); err != nil {
+			return PredicateFunction{}, err
+		}
+	} else {
+		for {
+			val, err := getValue(p)
+			if err != nil {
+				return PredicateFunction{}, err
+			}
+			fn.Params = append(fn.Params, val)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+	}
+	if p.acceptKeyword("PASSING") {
+		p.acceptKeyword("BY", "VALUE")
+		if fn.Passing, err = getPassingItems(p); err != nil {
+			return PredicateFunction{}, err
+		}
+	}
+	if p.isAnyKeyword("TRUE", "FALSE", "ERROR") && p.isKeywordAt(1, "ON", "ERROR") {
+		fn.OnError = strings.ToUpper(p.keyword().Value)
+		p.keyword()
+		p.keyword()
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return PredicateFunction{}, err
+	}
+	fn.Pos = p.posFrom(start)
+	return fn, nil
+}
+
+// getPassingItems 解析PASSING后面逗号隔开的值及其名称
+func getPassingItems(p *parser) (items []PassingItem, err error) {
+	for {
+		var item PassingItem
+		start := p.peek().Start
+		if item.Value, err = getValue(p); err != nil {
+			return nil, err
+		}
+		if p.acceptKeyword("AS") {
+			if item.Alias, err = getName(p); err != nil {
+				return nil, err
+			}
+		}
+		item.Pos = p.posFrom(start)
+		items = append(items, item)
+		if !p.acceptPunct(",") {
+			return items, nil
+		}
+	}
+}
+
+// marshalNot 序列化被NOT取反的条件
+func marshalNot(m *marshaler, not Not) (retSQL string, err error) {
+	if retSQL, err = marshalEquation(m, not.Equation); err != nil {
+		return "", err
+	}
+	return m.kw("NOT") + " " + retSQL, nil
+}
+
+// marshalPredicateFunction 序列化返回布尔值的函数
+func marshalPredicateFunction(m *marshaler, fn PredicateFunction) (retSQL string, err error) {
+	switch strings.ToUpper(fn.Name) {
+	case "LNNVL":
+		if len(fn.Condition.Equation) == 0 {
+			return "", errors.New("LNNVL缺失条件")
+		}
+		retSQL, err = marshalEquationList(m, fn.Condition)
+	case "REGEXP_LIKE", "XMLEXISTS", "JSON_EXISTS":
+		if len(fn.Params) == 0 {
+			return "", errors.New(fn.Name + "缺失参数")
+		}
+		retSQL, err = marshalValueItems(m, fn.Params)
+	default:
+		return "", errors.New("不能识别的条件函数" + fn.Name)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(fn.Passing) != 0 {
+		var passing []string
+		for _, item := range fn.Passing {
+			val, err := marshalValue(m, item.Value, true)
+			if err != nil {
+				return "", err
+			}
+			if item.Alias != "" {
+				val += " " + m.kw("AS") + " " + item.Alias
+			}
+			passing = append(passing, val)
+		}
+		retSQL += " " + m.kw("PASSING") + " " + strings.Join(passing, ",")
+	}
+	switch fn.OnError {
+	case "":
+	case "TRUE", "FALSE", "ERROR":
+		retSQL += " " + m.kw(fn.OnError+" ON ERROR")
+	default:
+		return "", errors.New("不能识别的错误处理方式" + fn.OnError)
+	}
+	return fn.Name + "(" + retSQL + ")", nil
+}
+
+func getParamsByPredicateFunction(fn PredicateFunction) (pars []Params) {
+	for _, item := range fn.Params {
+		pars = append(pars, getParamsBySelectValue(item)...)
+	}
+	pars = append(pars, getParamsBySelectEquationList(fn.Condition)...)
+	for _, item := range fn.Passing {
+		pars = append(pars, getParamsBySelectValue(item.Value)...)
+	}
+	return pars
+}
+
+// deleteParamsByPredicateFunction 和函数一样，参数中有参数被删除时删除整个条件
+func deleteParamsByPredicateFunction(fn PredicateFunction, pars []Params) interface{} {
+	params := make([]Value, len(fn.Params))
+	for i, item := range fn.Params {
+		if params[i] = deleteParamsBySelectValue(item, pars); params[i].Value == nil {
+			return nil
+		}
+	}
+	passing := make([]PassingItem, len(fn.Passing))
+	for i, item := range fn.Passing {
+		passing[i] = item
+		if passing[i].Value = deleteParamsBySelectValue(item.Value, pars); passing[i].Value.Value == nil {
+			return nil
+		}
+	}
+	if len(fn.Condition.Equation) != 0 {
+		if fn.Condition = deleteParamsBySelectEquationList(fn.Condition, pars); len(fn.Condition.Equation) == 0 {
+			return nil
+		}
+	}
+	fn.Params = params
+	fn.Passing = passing
+	return fn
+}
+
+func expandParamsByPredicateFunction(fn PredicateFunction, params Params, count int) interface{} {
+	values := make([]Value, len(fn.Params))
+	for i, item := range fn.Params {
+		values[i] = item
+		if val := expandParamsBySelectValue(item, params, count); val.Value != nil {
+			values[i] = val
+		}
+	}
+	passing := make([]PassingItem, len(fn.Passing))
+	for i, item := range fn.Passing {
+		passing[i] = item
+		if val := expandParamsBySelectValue(item.Value, params, count); val.Value != nil {
+			passing[i].Value = val
+		}
+	}
+	fn.Params = values
+	fn.Passing = passing
+	fn.Condition = expandParamsBySelectEquationList(fn.Condition, params, count)
+	return fn
+}
@@ -0,0 +1,128 @@
+package sqlParser
+
+import "testing"
+
+func TestNotAndPredicateFunctions(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"select a from t where not (a = 1 or b = 2) and not a like 'x%'", "SELECT a FROM t WHERE NOT (a=1 OR b=2) AND NOT a LIKE 'x%'"},
+		{"select a from t where not not not a = 1", "SELECT a FROM t WHERE NOT NOT NOT a=1"},
+		//后面不是括号时是普通的名字
+		{"select regexp_like from t", "SELECT regexp_like FROM t"},
+		{"select a from t where regexp_like(name, '^A', 'i') and lnnvl(a > 1) or not regexp_like(b, 'x')", "SELECT a FROM t WHERE regexp_like(name,'^A','i') AND lnnvl(a>1) OR NOT regexp_like(b,'x')"},
+		//PASSING BY VALUE是默认的传值方式，生成SQL时省略
+		{`select a from t where xmlexists('/a/b' passing by value doc as "d")`, `SELECT a FROM t WHERE xmlexists('/a/b' PASSING doc AS "d")`},
+		{`select a from t where json_exists(doc, '$.a' passing :p as "v" false on error)`, `SELECT a FROM t WHERE json_exists(doc,'$.a' PASSING :p AS "v" FALSE ON ERROR)`},
+		{"select a from t where regexp_like(name, 'x') = 1", "SELECT a FROM t WHERE regexp_like(name,'x')=1"},
+		//参数不符合谓词函数时按普通的函数解析
+		{"select a from t where lnnvl(a) = 1", "SELECT a FROM t WHERE lnnvl(a)=1"},
+		{"select a from t where regexp_like(a) in (1, 2) and lnnvl(b = 1)", "SELECT a FROM t WHERE regexp_like(a) IN(1,2) AND lnnvl(b=1)"},
+	}
+	for _, tt := range tests {
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+	stmt, err := Unmarshal("select a from t where not (a = 1 or b = 2) and lnnvl(a > 1) and regexp_like(name, 'x') = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eqs := stmt.Ast.(Select).Body.(SelectItem).Where.Equation
+	if not, ok := eqs[0].Equation.(Not); !ok {
+		t.Errorf("got %#v, want Not", eqs[0].Equation)
+	} else if list, ok := not.Equation.(EquationList); !ok || len(list.Equation) != 2 {
+		t.Errorf("NOT operand = %#v, want (a=1 OR b=2)", not.Equation)
+	}
+	if fn, ok := eqs[1].Equation.(PredicateFunction); !ok || len(fn.Condition.Equation) != 1 {
+		t.Errorf("got %#v, want LNNVL", eqs[1].Equation)
+	}
+	//后面还有比较符号时是普通的Function
+	if eq, ok := eqs[2].Equation.(EquationNorm); !ok {
+		t.Errorf("got %#v, want EquationNorm", eqs[2].Equation)
+	} else if _, ok := eq.Left.Value.(Function); !ok {
+		t.Errorf("left = %#v, want Function", eq.Left.Value)
+	}
+}
+
+func TestPredicateDeleteParams(t *testing.T) {
+	testParams(t, []paramsCase{
+		//NOT、LNNVL里的条件部分删除时，第一个条件不能有连接符
+		{"select a from t where not (a = :p or b = :q)", []string{":p", ":q"}, ":p", "SELECT a FROM t WHERE NOT (b=:q)", "", ""},
+		{"select a from t where lnnvl(a = :p and b = :q)", []string{":p", ":q"}, ":p", "SELECT a FROM t WHERE lnnvl(b=:q)", "", ""},
+		{"select a from t where (a = :p or b = :q) and c = 1", []string{":p", ":q"}, ":p", "SELECT a FROM t WHERE (b=:q) AND c=1", "", ""},
+		{"select a from t group by a having count(*) > :p or max(b) = 1", []string{":p"}, ":p", "SELECT a FROM t GROUP BY a HAVING max(b)=1", "", ""},
+		//NOT后面的条件被删除时，NOT也一起删除
+		{"select a from t where not a = :p and b = :q", []string{":p", ":q"}, ":p", "SELECT a FROM t WHERE b=:q", "", ""},
+		//函数的参数被删除时删除整个条件
+		{"select a from t where regexp_like(name, :p) and b = :q", []string{":p", ":q"}, ":p", "SELECT a FROM t WHERE b=:q", "", ""},
+		{`select a from t where json_exists(doc, '$.a' passing :p as "v")`, []string{":p"}, ":p", "SELECT a FROM t", "", ""},
+		{"select a from t where lnnvl(a in (:p))", []string{":p"}, ":p", "SELECT a FROM t", ":p", "SELECT a FROM t WHERE lnnvl(a IN(:p0,:p1,:p2))"},
+		{"select a from t where not a in (:p)", []string{":p"}, "", "", ":p", "SELECT a FROM t WHERE NOT a IN(:p0,:p1,:p2)"},
+	})
+}
+
+func TestPredicateFunctionName(t *testing.T) {
+	//函数名保持原来的写法，NOT跟随关键词的大小写
+	stmt, err := Unmarshal("select a from t where Not Regexp_Like(name, 'x') and LNNVL(a > 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eqs := stmt.Ast.(Select).Body.(SelectItem).Where.Equation
+	if fn, ok := eqs[0].Equation.(Not).Equation.(PredicateFunction); !ok || fn.Name != "Regexp_Like" || len(fn.Params) != 2 {
+		t.Errorf("got %#v", eqs[0].Equation)
+	}
+	tests := map[KeywordCase]string{
+		KeywordUpper:     "SELECT a FROM t WHERE NOT Regexp_Like(name,'x') AND LNNVL(a>1)",
+		KeywordAsWritten: "select a from t where Not Regexp_Like(name,'x') and LNNVL(a>1)",
+	}
+	for kc, want := range tests {
+		if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: kc}); err != nil || got != want {
+			t.Errorf("case %v: got %q, %v, want %q", kc, got, err, want)
+		}
+	}
+}
+
+func TestPredicateSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select a from t where not", ErrUnexpectedEOF, 26},
+		{"select a from t where regexp_like(name", ErrMissingRightParen, 39},
+		{"select a from t where lnnvl()", ErrUnexpectedToken, 29},
+		{"select a from t where json_exists(doc, '$.a' passing)", ErrUnexpectedToken, 53},
+		//按普通的函数也解析不了时，返回谓词函数的错误
+		{"select a from t where lnnvl(a)", ErrInvalidComparison, 30},
+		{"select a from t where json_exists(doc, '$.a' empty on error)", ErrMissingRightParen, 46},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+}
+
+func TestMarshalPredicateFunctionErrors(t *testing.T) {
+	col := Value{Value: ColumnRef{Column: "a"}}
+	tests := map[string]PredicateFunction{
+		"lnnvl without condition":  {Name: "LNNVL"},
+		"regexp_like without args": {Name: "REGEXP_LIKE"},
+		"unknown function":         {Name: "XMLCAST", Params: []Value{col}},
+		"unknown on error":         {Name: "JSON_EXISTS", Params: []Value{col}, OnError: "EMPTY"},
+		"bad passing value":        {Name: "XMLEXISTS", Params: []Value{col}, Passing: []PassingItem{{Value: Value{Value: ColumnRef{}}}}},
+	}
+	for name, fn := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalPredicateFunction(&marshaler{}, fn); err == nil {
+				t.Errorf("marshalPredicateFunction = %q, should fail", got)
+			}
+		})
+	}
+	if got, err := marshalNot(&marshaler{}, Not{}); err == nil {
+		t.Errorf("marshalNot of an empty condition = %q, should fail", got)
+	}
+}
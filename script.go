@@ -0,0 +1,202 @@
+package sqlParser
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// PlsqlBlock 脚本中的PL/SQL块（DECLARE、BEGIN开头的匿名块，CREATE FUNCTION、PROCEDURE、PACKAGE、TRIGGER、TYPE），它不会被解析，原样保留
+type PlsqlBlock struct {
+	Text string
+	Pos  Pos
+}
+
+// plsqlObjects CREATE [OR REPLACE]后面跟着这些关键词时，语句是PL/SQL块
+var plsqlObjects = map[string]bool{
+	"FUNCTION": true, "PROCEDURE": true, "PACKAGE": true, "TRIGGER": true, "TYPE": true,
+}
+
+// isPlsqlStart 根据语句开头的单词判断是否是PL/SQL块，words是大写的
+func isPlsqlStart(words []string) bool {
+	if len(words) == 0 {
+		return false
+	}
+	if words[0] == "DECLARE" {
+		return true
+	}
+	//BEGIN;、BEGIN TRANSACTION、BEGIN WORK是开始事务的语句，不是PL/SQL块
+	if words[0] == "BEGIN" {
+		return len(words) > 1 && words[1] != ";" && words[1] != "TRANSACTION" && words[1] != "WORK"
+	}
+	if words[0] != "CREATE" {
+		return false
+	}
+	for _, word := range words[1:] {
+		switch word {
+		case "OR", "REPLACE", "EDITIONABLE", "NONEDITIONABLE":
+			continue
+		}
+		return plsqlObjects[word]
+	}
+	return false
+}
+
+// isSlashLine 判断位置在start的/是否单独占一行，即SQL*Plus的执行符。atEOF为false且看不到行尾时返回more
+func isSlashLine(data string, start int, atEOF bool) (slash bool, more bool) {
+	lineStart := strings.LastIndexByte(data[:start], '\n') + 1
+	if strings.TrimSpace(data[lineStart:start]) != "" {
+		return false, false
+	}
+	rest := data[start+1:]
+	lineEnd := strings.IndexByte(rest, '\n')
+	if lineEnd == -1 {
+		if !atEOF {
+			return false, true
+		}
+		lineEnd = len(rest)
+	}
+	return strings.TrimSpace(rest[:lineEnd]) == "", false
+}
+
+// scanStatement 从data中找出第一条语句。语句以不在字符串、注释里的分号结束，或者以单独占一行的/结束；
+// PL/SQL块里面的分号不结束语句，它只以/或者数据的结尾结束。
+// 返回语句在data中的开始、结束位置（包含语句前面的注释，不包含结束符），以及被消费的字节数。
+// 只有注释、空白时start等于end；atEOF为false且数据不足以确定语句的结尾时，advance为0，需要更多的数据，block是目前为止的判断结果
+func scanStatement(data string, atEOF bool) (start, end, advance int, block bool) {
+	lex := NewLexer(data)
+	start, end = -1, -1
+	var words []string
+	for {
+		tok, err := lex.Next()
+		if perr, ok := err.(*ParseError); ok && perr.Code == ErrIllegalCharacter {
+			//无法识别的字符也属于语句，由解析报告错误
+			tok, err = perr.Token, nil
+		}
+		if err != nil {
+			//字符串、注释没有结束，可能是数据还不够；已经是结尾时，剩下的都属于这条语句，由解析报告错误
+			if !atEOF {
+				return 0, 0, 0, block
+			}
+			if start == -1 {
+				start = lex.pos
+			}
+			return start, len(data), len(data), block
+		}
+		if tok.Type == TokenEOF {
+			if !atEOF {
+				return 0, 0, 0, block
+			}
+			if start == -1 {
+				return len(data), len(data), len(data), false
+			}
+			return start, end, len(data), block
+		}
+		if tok.Type == TokenOperator && tok.Value == "/" {
+			slash, more := isSlashLine(data, tok.Start, atEOF)
+			if more {
+				return 0, 0, 0, block
+			}
+			if slash {
+				if start == -1 {
+					start, end = tok.Start, tok.Start
+				}
+				return start, end, tok.End, block
+			}
+		}
+		if tok.Type == TokenPunct && tok.Value == ";" && !block {
+			if start == -1 {
+				start, end = tok.Start, tok.Start
+			}
+			return start, end, tok.End, false
+		}
+		if start == -1 {
+			start = tok.Start
+		}
+		end = tok.End
+		//BEGIN后面不是标识符时也要记下来，用来区分PL/SQL块和开始事务的语句
+		if (tok.Type == TokenIdent || len(words) == 1 && words[0] == "BEGIN") && len(words) < 5 && !block {
+			words = append(words, strings.ToUpper(tok.Value))
+			block = isPlsqlStart(words)
+		}
+	}
+}
+
+// lineColumn 计算s结尾处的行列号，line、column是s开头处的行列号
+func lineColumn(s string, line, column int) (int, int) {
+	if n := strings.Count(s, "\n"); n != 0 {
+		return line + n, utf8.RuneCountInString(s[strings.LastIndexByte(s, '\n')+1:]) + 1
+	}
+	return line, column + utf8.RuneCountInString(s)
+}
+
+// unmarshalSpan 解析脚本中的一条语句，span是它在脚本中的位置。
+// 解析失败时语法树是覆盖整条语句的ErrorNode，错误保存在Diagnostics中；返回的错误的位置会被换算成脚本中的位置
+func unmarshalSpan(text string, span Pos, block bool) (Statement, error) {
+	whole := Pos{End: len(text), Line: 1, Column: 1}
+	if block {
+		stmt := Statement{Ast: PlsqlBlock{Text: text, Pos: whole}, Source: text, Span: span}
+		return stmt, nil
+	}
+	stmt, err := Unmarshal(text)
+	if perr, ok := err.(*ParseError); ok {
+		stmt := Statement{Ast: ErrorNode{Text: text, Err: perr, Pos: whole}, Source: text, Span: span, Diagnostics: []*ParseError{perr}}
+		shifted := *perr
+		shifted.Token.Start += span.Start
+		shifted.Token.End += span.Start
+		shifted.Pos.Start += span.Start
+		shifted.Pos.End += span.Start
+		if shifted.Pos.Line == 1 {
+			shifted.Pos.Column += span.Column - 1
+		}
+		shifted.Pos.Line += span.Line - 1
+		return stmt, &shifted
+	}
+	if err != nil {
+		return Statement{}, err
+	}
+	stmt.Span = span
+	return stmt, nil
+}
+
+// UnmarshalScript 将包含多条语句的脚本解析成语法树列表，语句之间用分号或者单独占一行的/（SQL*Plus）分隔。
+// 每条语句的Source是它自己的原文，Span是它在脚本中的位置；PL/SQL块不会被解析，语法树是PlsqlBlock。
+// 某条语句解析失败时继续解析后面的语句，失败的语句的语法树是ErrorNode，返回全部语句和第一个错误
+func UnmarshalScript(s string) (stmts []Statement, err error) {
+	offset, line, column := 0, 1, 1
+	for offset < len(s) {
+		start, end, advance, block := scanStatement(s[offset:], true)
+		if start < end {
+			startLine, startColumn := lineColumn(s[offset:offset+start], line, column)
+			span := Pos{Start: offset + start, End: offset + end, Line: startLine, Column: startColumn}
+			stmt, stmtErr := unmarshalSpan(s[offset+start:offset+end], span, block)
+			if stmtErr != nil && err == nil {
+				err = stmtErr
+			}
+			stmts = append(stmts, stmt)
+		}
+		line, column = lineColumn(s[offset:offset+advance], line, column)
+		offset += advance
+	}
+	return stmts, err
+}
+
+// MarshalScript 将语法树列表生成脚本，每条语句以分号结束，PL/SQL块以单独占一行的/结束
+func MarshalScript(stmts []Statement) (string, error) {
+	var sb strings.Builder
+	for _, stmt := range stmts {
+		retSQL, err := Marshal(stmt)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := stmt.Ast.(PlsqlBlock); ok {
+			sb.WriteString(retSQL + "\n/\n")
+			continue
+		}
+		//最后是行注释时，分号要换行
+		if tokens, _ := Tokenize(retSQL); len(tokens) != 0 && strings.HasPrefix(tokens[len(tokens)-1].Value, "--") {
+			retSQL += "\n"
+		}
+		sb.WriteString(retSQL + ";\n")
+	}
+	return sb.String(), nil
+}
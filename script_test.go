@@ -0,0 +1,130 @@
+package sqlParser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalScript(t *testing.T) {
+	script := "-- report\nSELECT 'a;b' FROM T; /* x; */ UPDATE T SET A = 1 WHERE B = ';'\n/\nDECLARE\n  v NUMBER;\nBEGIN\n  v := 1;\nEND;\n/\n" +
+		"CREATE OR REPLACE PROCEDURE P AS BEGIN NULL; END;\n/\n\nDELETE FROM T WHERE X = :X -- bye\n;;\n"
+	want := []struct {
+		typ    string
+		span   Pos
+		source string
+	}{
+		{"SELECT", Pos{Start: 0, End: 29, Line: 1, Column: 1}, "-- report\nSELECT 'a;b' FROM T"},
+		{"UPDATE", Pos{Start: 31, End: 72, Line: 2, Column: 22}, "/* x; */ UPDATE T SET A = 1 WHERE B = ';'"},
+		{"PLSQL", Pos{Start: 75, End: 115, Line: 4, Column: 1}, "DECLARE\n  v NUMBER;\nBEGIN\n  v := 1;\nEND;"},
+		{"PLSQL", Pos{Start: 118, End: 167, Line: 10, Column: 1}, "CREATE OR REPLACE PROCEDURE P AS BEGIN NULL; END;"},
+		{"DELETE", Pos{Start: 171, End: 204, Line: 13, Column: 1}, "DELETE FROM T WHERE X = :X -- bye"},
+	}
+	stmts, err := UnmarshalScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != len(want) {
+		t.Fatalf("got %d statements, want %d", len(stmts), len(want))
+	}
+	for i, w := range want {
+		if stmts[i].Type() != w.typ || stmts[i].Span != w.span || stmts[i].Source != w.source {
+			t.Errorf("statement %d = %s %+v %q, want %s %+v %q", i, stmts[i].Type(), stmts[i].Span, stmts[i].Source, w.typ, w.span, w.source)
+		}
+	}
+	if got := paramNames(stmts[4].Params()); !reflect.DeepEqual(got, []string{":X"}) {
+		t.Errorf("Params() = %v, want [:X]", got)
+	}
+	out, err := MarshalScript(stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantScript := "-- report\nSELECT 'a;b' FROM T;\n/* x; */ UPDATE T SET A=1 WHERE B=';';\nDECLARE\n  v NUMBER;\nBEGIN\n  v := 1;\nEND;\n/\n" +
+		"CREATE OR REPLACE PROCEDURE P AS BEGIN NULL; END;\n/\nDELETE FROM T WHERE X=:X -- bye\n;\n"
+	if out != wantScript {
+		t.Errorf("MarshalScript = %q, want %q", out, wantScript)
+	}
+	//生成的脚本可以被再次解析
+	again, err := UnmarshalScript(out)
+	if err != nil || len(again) != len(stmts) {
+		t.Errorf("reparse: %d statements, %v", len(again), err)
+	}
+}
+
+func TestUnmarshalScriptError(t *testing.T) {
+	stmts, err := UnmarshalScript("SELECT A FROM T;\n\n  SELECT A FROM T WHERE")
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("got %v, want *ParseError", err)
+	}
+	//错误位置是脚本中的位置
+	if perr.Code != ErrUnexpectedEOF || perr.Pos.Line != 3 || perr.Pos.Column != 24 || perr.Pos.Start != 41 {
+		t.Errorf("got %s at %+v", perr.Code, perr.Pos)
+	}
+	//出错的语句也在结果里，Diagnostics中的位置是语句中的位置
+	if len(stmts) != 2 || len(stmts[1].Diagnostics) != 1 || stmts[1].Diagnostics[0].Pos.Start != 21 {
+		t.Fatalf("got %d statements: %+v", len(stmts), stmts)
+	}
+}
+
+func TestUnmarshalScriptContinue(t *testing.T) {
+	tests := []struct {
+		script string
+		types  []string //解析失败的语句类型为空
+		errPos int      //第一个错误在脚本中的位置
+		out    string
+	}{
+		//不支持的语句不影响后面的语句
+		{"create table x(a int);\nselect a from t;", []string{"", "SELECT"}, 0, "create table x(a int);\nSELECT a FROM t;\n"},
+		{"select a from t;\ndrop table x;\nupdate t set a = 1 where;\ndelete from t",
+			[]string{"SELECT", "", "", "DELETE"}, 17, "SELECT a FROM t;\ndrop table x;\nupdate t set a = 1 where;\nDELETE FROM t;\n"},
+		//BEGIN;、BEGIN TRANSACTION、BEGIN WORK是开始事务，以分号结束
+		{"BEGIN;\nselect b from u;\nCOMMIT;", []string{"", "SELECT", ""}, 0, "BEGIN;\nSELECT b FROM u;\nCOMMIT;\n"},
+		{"begin transaction;\nselect b from u;\nbegin work;", []string{"", "SELECT", ""}, 0, "begin transaction;\nSELECT b FROM u;\nbegin work;\n"},
+		//BEGIN后面是其他内容时是PL/SQL块，里面的分号不结束语句
+		{"BEGIN\n  NULL;\nEND;\n/\nselect b from u;", []string{"PLSQL", "SELECT"}, -1, "BEGIN\n  NULL;\nEND;\n/\nSELECT b FROM u;\n"},
+		{"BEGIN :x := 1; END;\n/\n", []string{"PLSQL"}, -1, "BEGIN :x := 1; END;\n/\n"},
+		//CREATE OR REPLACE EDITIONABLE PACKAGE要看到第5个单词才能确定
+		{"create or replace editionable package p as x number; end;\n/\n", []string{"PLSQL"}, -1, "create or replace editionable package p as x number; end;\n/\n"},
+	}
+	for _, tt := range tests {
+		stmts, err := UnmarshalScript(tt.script)
+		var types []string
+		for _, stmt := range stmts {
+			types = append(types, stmt.Type())
+			if node, ok := stmt.Ast.(ErrorNode); ok && (node.Text != stmt.Source || len(stmt.Diagnostics) != 1) {
+				t.Errorf("%q: ErrorNode %q, %d diagnostics", tt.script, node.Text, len(stmt.Diagnostics))
+			}
+		}
+		if !reflect.DeepEqual(types, tt.types) {
+			t.Errorf("%q: types = %q, want %q", tt.script, types, tt.types)
+		}
+		if perr, ok := err.(*ParseError); tt.errPos == -1 && err != nil || tt.errPos != -1 && (!ok || perr.Pos.Start < tt.errPos) {
+			t.Errorf("%q: err = %v", tt.script, err)
+		}
+		if out, err := MarshalScript(stmts); err != nil || out != tt.out {
+			t.Errorf("%q: MarshalScript = %q, %v, want %q", tt.script, out, err, tt.out)
+		}
+	}
+}
+
+func TestIsPlsqlStart(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  bool
+	}{
+		{[]string{"DECLARE"}, true},
+		{[]string{"BEGIN"}, false},
+		{[]string{"BEGIN", ";"}, false},
+		{[]string{"BEGIN", "TRANSACTION"}, false},
+		{[]string{"BEGIN", "WORK"}, false},
+		{[]string{"BEGIN", "NULL"}, true},
+		{[]string{"CREATE", "OR", "REPLACE", "EDITIONABLE", "PACKAGE"}, true},
+		{[]string{"CREATE", "TABLE"}, false},
+		{[]string{"SELECT"}, false},
+	}
+	for _, tt := range tests {
+		if got := isPlsqlStart(tt.words); got != tt.want {
+			t.Errorf("isPlsqlStart(%q) = %v, want %v", tt.words, got, tt.want)
+		}
+	}
+}
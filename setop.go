@@ -0,0 +1,171 @@
+package sqlParser
+
+import "errors"
+
+// SetOperation 集合运算，例：A UNION B、(A UNION ALL B) MINUS C。集合运算没有优先级，从左向右组成树：A UNION B MINUS C是(A UNION B) MINUS C，
+// 被括号括起的查询是Select。最后一个单查询后面的排序、限制行数属于集合运算整体，放在外层的Select上
+type SetOperation struct {
+	Left  interface{} //它可以是SelectItem、SetOperation、Select
+	Op    string      //UNION、UNION ALL、INTERSECT、INTERSECT ALL、MINUS、MINUS ALL、EXCEPT、EXCEPT ALL
+	Right interface{} //它可以是SelectItem、Select，是SetOperation时生成SQL会用括号括起
+	Pos   Pos
+}
+
+// setOperators 集合运算的关键词，它们后面都可以跟ALL
+var setOperators = []string{"UNION", "INTERSECT", "MINUS", "EXCEPT"}
+
+// acceptSetOperator 解析集合运算的关键词，没有则返回空串
+func acceptSetOperator(p *parser) string {
+	for _, op := range setOperators {
+		if p.acceptKeyword(op, "ALL") {
+			return op + " ALL"
+		}
+		if p.acceptKeyword(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// getSetOperation 解析用集合运算连接的查询，只有一个查询时直接返回它
+func getSetOperation(p *parser) (body interface{}, err error) {
+	start := p.peek().Start
+	if body, err = getSetOperand(p); err != nil {
+		return nil, err
+	}
+	last := body
+	for {
+		state := p.save()
+		op := acceptSetOperator(p)
+		if op == "" {
+			return body, nil
+		}
+		if item, ok := last.(SelectItem); ok && (item.Order != nil || item.Limit.Style != "" || item.ForUpdate.Mode != "") {
+			//集合运算前面的单查询不能有排序、限制行数、加锁读，要用括号括起
+			p.restore(state)
+			return nil, p.unexpected()
+		}
+		right, err := getSetOperand(p)
+		if err != nil {
+			return nil, err
+		}
+		body = SetOperation{Left: body, Op: op, Right: right, Pos: p.posFrom(start)}
+		last = right
+	}
+}
+
+// getSetOperand 解析集合运算的一项：单查询，或者被括号括起的查询
+func getSetOperand(p *parser) (interface{}, error) {
+	if !p.acceptPunct("(") {
+		return parserSelectItem(p)
+	}
+	inner, err := parserSelect(p)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return inner, nil
+}
+
+// marshalSelectBody 序列化查询体，被括号括起的查询保留括号
+func marshalSelectBody(m *marshaler, body interface{}) (retSQL string, err error) {
+	switch v := body.(type) {
+	case SelectItem:
+		if retSQL, err = marshalSelectItem(m, v); err != nil {
+			return "", err
+		}
+		return marshalComments(v.Comments, retSQL), nil
+	case SetOperation:
+		return marshalSetOperation(m, v)
+	case Select:
+		if retSQL, err = marshalSelect(m, v); err != nil {
+			return "", err
+		}
+		return "(" + retSQL + ")", nil
+	}
+	return "", errors.New("查询体类型不正确")
+}
+
+// marshalSetOperation 序列化集合运算
+func marshalSetOperation(m *marshaler, op SetOperation) (retSQL string, err error) {
+	valid := false
+	for _, item := range setOperators {
+		valid = valid || op.Op == item || op.Op == item+" ALL"
+	}
+	if !valid {
+		return "", errors.New("不能识别的集合运算" + op.Op)
+	}
+	left, err := marshalSelectBody(m, op.Left)
+	if err != nil {
+		return "", err
+	}
+	right, err := marshalSelectBody(m, op.Right)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := op.Right.(SetOperation); ok {
+		right = "(" + right + ")"
+	}
+	return left + " " + m.kw(op.Op) + " " + right, nil
+}
+
+// appendBodyComments 把注释加到查询体最后一个单查询的Trailing中
+func appendBodyComments(body interface{}, comments []Comment) interface{} {
+	switch v := body.(type) {
+	case SelectItem:
+		v.Comments.Trailing = append(v.Comments.Trailing, comments...)
+		return v
+	case SetOperation:
+		v.Right = appendBodyComments(v.Right, comments)
+		return v
+	case Select:
+		v.Body = appendBodyComments(v.Body, comments)
+		return v
+	}
+	return body
+}
+
+func getParamsBySelectBody(body interface{}) (pars []Params) {
+	switch v := body.(type) {
+	case SelectItem:
+		pars = append(pars, getParamsBySelectItem(v)...)
+	case SetOperation:
+		pars = append(pars, getParamsBySelectBody(v.Left)...)
+		pars = append(pars, getParamsBySelectBody(v.Right)...)
+	case Select:
+		pars = append(pars, getParamsBySelect(v)...)
+	}
+	return pars
+}
+
+func deleteParamsBySelectBody(body interface{}, pars []Params) interface{} {
+	switch v := body.(type) {
+	case SelectItem:
+		deleteParamsBySelectItem(&v, pars)
+		return v
+	case SetOperation:
+		v.Left = deleteParamsBySelectBody(v.Left, pars)
+		v.Right = deleteParamsBySelectBody(v.Right, pars)
+		return v
+	case Select:
+		return deleteParamsBySelect(v, pars)
+	}
+	return body
+}
+
+func expandParamsBySelectBody(body interface{}, params Params, count int) interface{} {
+	switch v := body.(type) {
+	case SelectItem:
+		expandParamsBySelectItem(&v, params, count)
+		return v
+	case SetOperation:
+		v.Left = expandParamsBySelectBody(v.Left, params, count)
+		v.Right = expandParamsBySelectBody(v.Right, params, count)
+		return v
+	case Select:
+		return expandParamsBySelect(v, params, count)
+	}
+	return body
+}
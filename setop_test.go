@@ -0,0 +1,131 @@
+package sqlParser
+
+import "testing"
+
+func TestSetOperationParentheses(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"(select a from x union select a from y) minus select a from z", "(SELECT a FROM x UNION SELECT a FROM y) MINUS SELECT a FROM z"},
+		{"select a from x union all select a from y intersect select a from z", "SELECT a FROM x UNION ALL SELECT a FROM y INTERSECT SELECT a FROM z"},
+		{"select a from x union (select a from y minus select a from z)", "SELECT a FROM x UNION (SELECT a FROM y MINUS SELECT a FROM z)"},
+		{
+			"select a from x except all select a from y intersect all select a from z order by a fetch first 5 rows only",
+			"SELECT a FROM x EXCEPT ALL SELECT a FROM y INTERSECT ALL SELECT a FROM z ORDER BY a FETCH FIRST 5 ROWS ONLY",
+		},
+		//前面的单查询有排序时要用括号括起
+		{"(select a from x order by a) union select a from y", "(SELECT a FROM x ORDER BY a) UNION SELECT a FROM y"},
+		//整个查询被括号括起时去掉括号
+		{"((select a from x))", "SELECT a FROM x"},
+	}
+	for _, tt := range tests {
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestSetOperationTree(t *testing.T) {
+	//集合运算从左向右组成树
+	stmt, err := Unmarshal("select a from x union all select a from y minus select a from z order by 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sel := stmt.Ast.(Select)
+	op, ok := sel.Body.(SetOperation)
+	if !ok || op.Op != "MINUS" {
+		t.Fatalf("Body = %#v, want MINUS", sel.Body)
+	}
+	if left, ok := op.Left.(SetOperation); !ok || left.Op != "UNION ALL" {
+		t.Errorf("Left = %#v, want UNION ALL", op.Left)
+	}
+	//最后一个单查询后面的排序属于集合运算整体
+	if right, ok := op.Right.(SelectItem); !ok || right.Order != nil {
+		t.Errorf("Right = %#v, want a SelectItem without ORDER BY", op.Right)
+	}
+	if sel.Order == nil {
+		t.Error("ORDER BY should be on the compound query")
+	}
+	//被括号括起的查询是Select
+	stmt, err = Unmarshal("select a from x union (select a from y minus select a from z)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stmt.Ast.(Select).Body.(SetOperation).Right.(Select); !ok {
+		t.Errorf("Right = %#v, want Select", stmt.Ast.(Select).Body.(SetOperation).Right)
+	}
+}
+
+func TestSetOperationDeleteParams(t *testing.T) {
+	tests := []struct {
+		sql    string
+		delete string
+		want   string
+	}{
+		//每个单查询只删除自己的条件
+		{"select a from x where b = :p union all select a from y where c in (:q)", ":p", "SELECT a FROM x UNION ALL SELECT a FROM y WHERE c IN(:q)"},
+		{"(select a from x where b in (:p)) minus select a from z where d = :q", ":q", "(SELECT a FROM x WHERE b IN(:p)) MINUS SELECT a FROM z"},
+		//集合运算整体的排序、限制行数
+		{"select a from x union select a from y order by :p, a", ":p", "SELECT a FROM x UNION SELECT a FROM y ORDER BY a"},
+		{"select a from x union select a from y fetch first :n rows only", ":n", "SELECT a FROM x UNION SELECT a FROM y"},
+	}
+	for _, tt := range tests {
+		stmt, err := Unmarshal(tt.sql)
+		if err != nil {
+			t.Fatalf("Unmarshal(%q): %v", tt.sql, err)
+		}
+		stmt.DeleteParams([]Params{{Name: tt.delete}})
+		if got, err := Marshal(stmt); err != nil || got != tt.want {
+			t.Errorf("%q delete %s: got %q, %v, want %q", tt.sql, tt.delete, got, err, tt.want)
+		}
+	}
+	stmt, err := Unmarshal("select a from x Union All select a from y where c in (:q) Minus select a from z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt.ExpandParams(Params{Name: ":q"}, 2)
+	want := "select a from x Union All select a from y where c in(:q0,:q1) Minus select a from z"
+	if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: KeywordAsWritten}); err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestSetOperationSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select a from x union", ErrMissingKeyword, 22},
+		{"select a from x union all all select a from y", ErrMissingKeyword, 27},
+		{"(select a from x union select a from y", ErrMissingRightParen, 39},
+		{"select a from x union select a from y order by", ErrUnexpectedEOF, 47},
+		//前面的单查询不能有排序、限制行数、加锁读
+		{"select a from x order by a union select a from y", ErrUnexpectedToken, 28},
+		{"select a from x limit 1 union select a from y", ErrUnexpectedToken, 25},
+		{"select a from x union select a from y for update minus select a from z", ErrUnexpectedToken, 50},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+}
+
+func TestMarshalSetOperationErrors(t *testing.T) {
+	item := SelectItem{Field: []SelectField{{Field: Value{Value: ColumnRef{Column: "a"}}}}, Table: []SelectTable{{Table: "x"}}}
+	tests := map[string]SetOperation{
+		"unknown operator":   {Left: item, Op: "UNION DISTINCT", Right: item},
+		"unsupported right":  {Left: item, Op: "UNION", Right: "x"},
+		"missing left query": {Left: nil, Op: "MINUS", Right: item},
+	}
+	for name, op := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalSetOperation(&marshaler{}, op); err == nil {
+				t.Errorf("marshalSetOperation = %q, should fail", got)
+			}
+		})
+	}
+}
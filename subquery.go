@@ -0,0 +1,311 @@
+package sqlParser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Exists EXISTS条件，它没有左值，例：NOT EXISTS (SELECT 1 FROM T WHERE T.ID = A.ID)
+type Exists struct {
+	Not   bool
+	Query Select
+	Pos   Pos
+}
+
+// Quantified 带有ANY、SOME、ALL的比较式，例：SAL > ALL (SELECT SAL FROM EMP)、SAL = ANY (1000, 2000)
+type Quantified struct {
+	Left       Value
+	Operator   string  //和EquationNorm一样的比较符号
+	Quantifier string  //ANY、SOME、ALL
+	Query      *Select //子查询，是值列表时为nil
+	List       []Value //值列表，是子查询时为空
+	Pos        Pos
+}
+
+// InSubquery IN后面是子查询的条件，例：(A, B) IN (SELECT X, Y FROM T)；IN后面是值列表时是EquationOther
+type InSubquery struct {
+	Left  Value
+	Not   bool
+	Query Select
+	Pos   Pos
+}
+
+// RowValue 行值构造器，它是Value，原SQL中被括号括起的多个值，例：(A, B) = ((1, 2))中的(A, B)、(1, 2)
+type RowValue struct {
+	Items []Value
+	Pos   Pos
+}
+
+// quantifiers 比较符号后面的量词
+var quantifiers = []string{"ANY", "SOME", "ALL"}
+
+// getSubquery 解析被括号括起的子查询，不是子查询时不消费词法单元
+func getSubquery(p *parser) (query Select, ok bool) {
+	if !p.isPunct("(") || !p.isSelectStart(1) {
+		return Select{}, false
+	}
+	//括号可能属于子查询里的集合查询，例：((SELECT A FROM T) UNION (SELECT B FROM S))，解析失败时说明括号里是值列表
+	state := p.save()
+	p.next()
+	query, err := parserSelect(p)
+	if err == nil {
+		err = p.expectPunct(")")
+	}
+	if err != nil {
+		p.restore(state)
+		return Select{}, false
+	}
+	return query, true
+}
+
+// getExists 解析EXISTS、NOT EXISTS条件
+func getExists(p *parser) (exists Exists, err error) {
+	start := p.peek().Start
+	exists.Not = p.acceptKeyword("NOT")
+	p.keyword()
+	if err = p.expectPunct("("); err != nil {
+		return Exists{}, err
+	}
+	if exists.Query, err = parserSelect(p); err != nil {
+		return Exists{}, err
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return Exists{}, err
+	}
+	exists.Pos = p.posFrom(start)
+	return exists, nil
+}
+
+// isQuantifierStart 比较符号后面是否是ANY、SOME、ALL
+func (p *parser) isQuantifierStart() bool {
+	next := p.peekN(1)
+	return p.isAnyKeyword(quantifiers...) && next.Type == TokenPunct && next.Value == "("
+}
+
+// getQuantified 解析比较符号后面的ANY、SOME、ALL，调用前需用isQuantifierStart判断
+func getQuantified(p *parser, left Value, op string, start int) (eq Quantified, err error) {
+	eq.Left = left
+	eq.Operator = op
+	eq.Quantifier = strings.ToUpper(p.keyword().Value)
+	if query, ok := getSubquery(p); ok {
+		eq.Query = &query
+	} else if eq.List, err = getValueList(p); err != nil {
+		return Quantified{}, err
+	}
+	eq.Pos = p.posFrom(start)
+	return eq, nil
+}
+
+// getRowValue 解析行值构造器中第一个值之后的部分，调用时已经解析了左括号和第一个值
+func getRowValue(p *parser, first Value, start int) (row RowValue, err error) {
+	row.Items = []Value{first}
+	for p.acceptPunct(",") {
+		val, err := getValue(p)
+		if err != nil {
+			return RowValue{}, err
+		}
+		row.Items = append(row.Items, val)
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return RowValue{}, err
+	}
+	row.Pos = p.posFrom(start)
+	return row, nil
+}
+
+// marshalExists 序列化EXISTS条件
+func marshalExists(m *marshaler, eq Exists) (retSQL string, err error) {
+	if retSQL, err = marshalSelect(m, eq.Query); err != nil {
+		return "", err
+	}
+	retSQL = m.kw("EXISTS") + "(" + retSQL + ")"
+	if eq.Not {
+		retSQL = m.kw("NOT") + " " + retSQL
+	}
+	return retSQL, nil
+}
+
+// marshalQuantified 序列化带有ANY、SOME、ALL的比较式
+func marshalQuantified(m *marshaler, eq Quantified) (retSQL string, err error) {
+	if !isCompareOperator(eq.Operator) {
+		return "", errors.New("比较式的符合" + eq.Operator + "不符合规则")
+	}
+	valid := false
+	for _, item := range quantifiers {
+		valid = valid || eq.Quantifier == item
+	}
+	if !valid {
+		return "", errors.New("不能识别的量词" + eq.Quantifier)
+	}
+	lv, err := marshalValue(m, eq.Left, true)
+	if err != nil {
+		return "", err
+	}
+	var rv string
+	if eq.Query != nil {
+		rv, err = marshalSelect(m, *eq.Query)
+	} else if len(eq.List) == 0 {
+		return "", errors.New(eq.Quantifier + "缺失子查询或者值列表")
+	} else {
+		rv, err = marshalValueItems(m, eq.List)
+	}
+	if err != nil {
+		return "", err
+	}
+	return lv + eq.Operator + m.kw(eq.Quantifier) + "(" + rv + ")", nil
+}
+
+// marshalInSubquery 序列化IN子查询
+func marshalInSubquery(m *marshaler, eq InSubquery) (retSQL string, err error) {
+	lv, err := marshalValue(m, eq.Left, true)
+	if err != nil {
+		return "", err
+	}
+	sel, err := marshalSelect(m, eq.Query)
+	if err != nil {
+		return "", err
+	}
+	op := "IN"
+	if eq.Not {
+		op = "NOT IN"
+	}
+	return lv + " " + m.kw(op) + "(" + sel + ")", nil
+}
+
+// marshalRowValue 序列化行值构造器
+func marshalRowValue(m *marshaler, row RowValue) (retSQL string, err error) {
+	if len(row.Items) == 0 {
+		return "", errors.New("行值构造器不能为空")
+	}
+	if retSQL, err = marshalValueItems(m, row.Items); err != nil {
+		return "", err
+	}
+	return "(" + retSQL + ")", nil
+}
+
+// marshalValueItems 序列化逗号隔开的值
+func marshalValueItems(m *marshaler, values []Value) (retSQL string, err error) {
+	for _, item := range values {
+		val, err := marshalValue(m, item, true)
+		if err != nil {
+			return "", err
+		}
+		retSQL += val + ","
+	}
+	return strings.TrimRight(retSQL, ","), nil
+}
+
+func getParamsByQuantified(eq Quantified) (pars []Params) {
+	pars = append(pars, getParamsBySelectValue(eq.Left)...)
+	if eq.Query != nil {
+		pars = append(pars, getParamsBySelect(*eq.Query)...)
+	}
+	for _, item := range eq.List {
+		pars = append(pars, getParamsBySelectValue(item)...)
+	}
+	return pars
+}
+
+func getParamsByInSubquery(eq InSubquery) (pars []Params) {
+	pars = append(pars, getParamsBySelectValue(eq.Left)...)
+	pars = append(pars, getParamsBySelect(eq.Query)...)
+	return pars
+}
+
+func getParamsByRowValue(row RowValue) (pars []Params) {
+	for _, item := range row.Items {
+		pars = append(pars, getParamsBySelectValue(item)...)
+	}
+	return pars
+}
+
+// deleteParamsByQuantified 左值中有参数被删除时删除整个条件，值列表和IN一样只删除该项，全部删除时删除整个条件
+func deleteParamsByQuantified(eq Quantified, pars []Params) interface{} {
+	if eq.Left = deleteParamsBySelectValue(eq.Left, pars); eq.Left.Value == nil {
+		return nil
+	}
+	if eq.Query != nil {
+		query := deleteParamsBySelect(*eq.Query, pars)
+		eq.Query = &query
+		return eq
+	}
+	var list []Value
+	for _, item := range eq.List {
+		if val := deleteParamsBySelectValue(item, pars); val.Value != nil {
+			list = append(list, val)
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	eq.List = list
+	return eq
+}
+
+func deleteParamsByInSubquery(eq InSubquery, pars []Params) interface{} {
+	if eq.Left = deleteParamsBySelectValue(eq.Left, pars); eq.Left.Value == nil {
+		return nil
+	}
+	eq.Query = deleteParamsBySelect(eq.Query, pars)
+	return eq
+}
+
+// deleteParamsByRowValue 和运算一样，其中有参数被删除时，整个行值都被删除
+func deleteParamsByRowValue(row RowValue, pars []Params) interface{} {
+	items := make([]Value, len(row.Items))
+	for i, item := range row.Items {
+		if items[i] = deleteParamsBySelectValue(item, pars); items[i].Value == nil {
+			return nil
+		}
+	}
+	row.Items = items
+	return row
+}
+
+// expandParamsByQuantified 值列表和IN一样，需要扩展的参数扩展成多个
+func expandParamsByQuantified(eq Quantified, params Params, count int) interface{} {
+	if left := expandParamsBySelectValue(eq.Left, params, count); left.Value != nil {
+		eq.Left = left
+	}
+	if eq.Query != nil {
+		query := expandParamsBySelect(*eq.Query, params, count)
+		eq.Query = &query
+	}
+	var list []Value
+	for _, item := range eq.List {
+		val := expandParamsBySelectValue(item, params, count)
+		if val.Value != nil {
+			list = append(list, val)
+			continue
+		}
+		par, _ := item.Params()
+		for i := 0; i < count; i++ {
+			newPar := par
+			newPar.Name = par.Name + strconv.Itoa(i)
+			list = append(list, Value{Value: newPar})
+		}
+	}
+	eq.List = list
+	return eq
+}
+
+func expandParamsByInSubquery(eq InSubquery, params Params, count int) interface{} {
+	if left := expandParamsBySelectValue(eq.Left, params, count); left.Value != nil {
+		eq.Left = left
+	}
+	eq.Query = expandParamsBySelect(eq.Query, params, count)
+	return eq
+}
+
+func expandParamsByRowValue(row RowValue, params Params, count int) RowValue {
+	items := make([]Value, len(row.Items))
+	for i, item := range row.Items {
+		items[i] = item
+		if val := expandParamsBySelectValue(item, params, count); val.Value != nil {
+			items[i] = val
+		}
+	}
+	row.Items = items
+	return row
+}
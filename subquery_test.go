@@ -0,0 +1,122 @@
+package sqlParser
+
+import "testing"
+
+func TestSubqueryConditions(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"select a from t where exists (select 1 from s where s.id = t.id) and not exists (select 1 from u)", "SELECT a FROM t WHERE EXISTS(SELECT 1 FROM s WHERE s.id=t.id) AND NOT EXISTS(SELECT 1 FROM u)"},
+		{"select a from t where sal > all (select sal from emp) or sal = any (1000, 2000) or x < some (select y from z)", "SELECT a FROM t WHERE sal>ALL(SELECT sal FROM emp) OR sal=ANY(1000,2000) OR x<SOME(SELECT y FROM z)"},
+		{"select a from t where x in (select a from s) and (a, b) not in (select x, y from u)", "SELECT a FROM t WHERE x IN(SELECT a FROM s) AND (a,b) NOT IN(SELECT x,y FROM u)"},
+		{"select a from t where (a, b) = ((1, 2)) and (a, b) in ((1, 2), (3, 4))", "SELECT a FROM t WHERE (a,b)=((1,2)) AND (a,b) IN((1,2),(3,4))"},
+		//ANY、SOME、ALL后面不是括号时是普通的名字
+		{"select any from t where x = some (1)", "SELECT any FROM t WHERE x=SOME(1)"},
+		{"select a from t where x in (select a from s) or (a) in (1) and not (a, b) = (1, 2)", "SELECT a FROM t WHERE x IN(SELECT a FROM s) OR (a) IN(1) AND NOT (a,b)=(1,2)"},
+		//括号属于子查询里的集合运算
+		{"select a from t where x in ((select a from s) union (select b from u))", "SELECT a FROM t WHERE x IN((SELECT a FROM s) UNION (SELECT b FROM u))"},
+	}
+	for _, tt := range tests {
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestSubqueryNodes(t *testing.T) {
+	stmt, err := Unmarshal("select a from t where not exists (select 1 from u) and sal > all (select sal from emp) and (a, b) in (select x, y from u)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eqs := stmt.Ast.(Select).Body.(SelectItem).Where.Equation
+	if len(eqs) != 3 {
+		t.Fatalf("got %d conditions, want 3", len(eqs))
+	}
+	if exists, ok := eqs[0].Equation.(Exists); !ok || !exists.Not {
+		t.Errorf("got %#v, want NOT EXISTS", eqs[0].Equation)
+	}
+	if eq, ok := eqs[1].Equation.(Quantified); !ok || eq.Operator != ">" || eq.Quantifier != "ALL" || eq.Query == nil {
+		t.Errorf("got %#v, want > ALL subquery", eqs[1].Equation)
+	}
+	in, ok := eqs[2].Equation.(InSubquery)
+	if !ok || in.Not {
+		t.Fatalf("got %#v, want IN subquery", eqs[2].Equation)
+	}
+	if row, ok := in.Left.Value.(RowValue); !ok || len(row.Items) != 2 {
+		t.Errorf("left = %#v, want (a,b)", in.Left.Value)
+	}
+	if fields := in.Query.Body.(SelectItem).Field; len(fields) != 2 {
+		t.Errorf("subquery has %d fields, want 2", len(fields))
+	}
+}
+
+func TestSubqueryDeleteParams(t *testing.T) {
+	testParams(t, []paramsCase{
+		//值列表和IN一样，只删除被删除的项
+		{"select a from t where sal = any (:p, :q, 3)", []string{":p", ":q"}, ":p", "SELECT a FROM t WHERE sal=ANY(:q,3)", ":p", "SELECT a FROM t WHERE sal=ANY(:p0,:p1,:p2,:q,3)"},
+		{"select a from t where sal = any (:p)", []string{":p"}, ":p", "SELECT a FROM t", "", ""},
+		//左值中有参数被删除时删除整个条件
+		{"select a from t where :p > all (select b from s where c = :q)", []string{":p", ":q"}, ":p", "SELECT a FROM t", "", ""},
+		{"select a from t where x in (select a from s where b = :p) and y = :q", []string{":p", ":q"}, ":p", "SELECT a FROM t WHERE x IN(SELECT a FROM s) AND y=:q", "", ""},
+		{"select a from t where (a, :p) = (1, 2) and y = :q", []string{":p", ":q"}, ":p", "SELECT a FROM t WHERE y=:q", "", ""},
+		{
+			"select a from t where exists (select 1 from s where b in (:p))",
+			[]string{":p"},
+			":p", "SELECT a FROM t WHERE EXISTS(SELECT 1 FROM s)",
+			":p", "SELECT a FROM t WHERE EXISTS(SELECT 1 FROM s WHERE b IN(:p0,:p1,:p2))",
+		},
+	})
+}
+
+func TestSubqueryKeywordCase(t *testing.T) {
+	stmt, err := Unmarshal("select a from t where Not Exists (select 1 from u) and x = Some (1) and y Not In (select b from s)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "select a from t where Not Exists(select 1 from u) and x=Some(1) and y Not In(select b from s)"
+	if got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: KeywordAsWritten}); err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestSubquerySyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql    string
+		code   ErrorCode
+		column int
+	}{
+		{"select a from t where exists select 1 from s", ErrMissingLeftParen, 30},
+		{"select a from t where exists (1, 2)", ErrMissingKeyword, 31},
+		{"select a from t where exists (select 1 from s", ErrMissingRightParen, 46},
+		{"select a from t where x > all ()", ErrUnexpectedToken, 32},
+		{"select a from t where x in (select a from s", ErrMissingRightParen, 44},
+		{"select a from t where (a, b = (1, 2)", ErrMissingRightParen, 29},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		if perr, ok := err.(*ParseError); !ok || perr.Code != tt.code || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %s at column %d", tt.sql, err, tt.code, tt.column)
+		}
+	}
+}
+
+func TestMarshalQuantifiedErrors(t *testing.T) {
+	col := Value{Value: ColumnRef{Column: "a"}}
+	tests := map[string]Quantified{
+		"unknown operator":   {Left: col, Operator: "<=>", Quantifier: "ALL", List: []Value{col}},
+		"unknown quantifier": {Left: col, Operator: "=", Quantifier: "EVERY", List: []Value{col}},
+		"empty list":         {Left: col, Operator: "=", Quantifier: "ANY"},
+		"bad left":           {Left: Value{Value: ColumnRef{}}, Operator: "=", Quantifier: "ANY", List: []Value{col}},
+	}
+	for name, eq := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalQuantified(&marshaler{}, eq); err == nil {
+				t.Errorf("marshalQuantified = %q, should fail", got)
+			}
+		})
+	}
+	if got, err := marshalRowValue(&marshaler{}, RowValue{}); err == nil {
+		t.Errorf("marshalRowValue of an empty row = %q, should fail", got)
+	}
+}
@@ -0,0 +1,382 @@
+package sqlParser
+
+import (
+	"errors"
+	"strings"
+)
+
+// WindowSpec 分析函数的窗口，即OVER后面的部分，例：OVER (PARTITION BY DEPT ORDER BY SAL DESC ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)
+type WindowSpec struct {
+	Name      string //引用的命名窗口，例：OVER W、OVER (W ORDER BY SAL)
+	Partition []Value
+	Order     []OrderItem
+	Frame     WindowFrame
+	Pos       Pos
+}
+
+// OrderItem 单个排序项，例：SAL DESC NULLS LAST。查询的ORDER BY中Expr可以是查询字段的序号或者别名，例：ORDER BY 2，可以用SelectItem.OrderField找出它引用的字段
+type OrderItem struct {
+	Expr      Value
+	Direction string //ASC、DESC，没有写明时为空
+	Nulls     string //FIRST、LAST，没有写明时为空
+	Pos       Pos
+}
+
+// WindowFrame 窗口范围，例：ROWS BETWEEN 1 PRECEDING AND CURRENT ROW，Unit为空时没有该子句
+type WindowFrame struct {
+	Unit  string     //ROWS、RANGE、GROUPS
+	Start FrameBound //没有BETWEEN时只有Start
+	End   FrameBound //没有BETWEEN时Kind为空
+	Pos   Pos
+}
+
+// FrameBound 窗口范围的边界
+type FrameBound struct {
+	Kind   string //UNBOUNDED PRECEDING、UNBOUNDED FOLLOWING、CURRENT ROW、PRECEDING、FOLLOWING
+	Offset Value  //Kind是PRECEDING、FOLLOWING时的偏移量
+	Pos    Pos
+}
+
+// NamedWindow 查询的WINDOW子句中的命名窗口，例：WINDOW W AS (PARTITION BY DEPT)
+type NamedWindow struct {
+	Name string
+	Spec WindowSpec
+	Pos  Pos
+}
+
+// getOver 解析分析函数的OVER部分，OVER关键词已被解析
+func getOver(p *parser) (spec WindowSpec, err error) {
+	start := p.peek().Start
+	if !p.isPunct("(") {
+		//引用命名窗口
+		if spec.Name, err = getName(p); err != nil {
+			return WindowSpec{}, err
+		}
+		spec.Pos = p.posFrom(start)
+		return spec, nil
+	}
+	p.next()
+	if spec, err = getWindowSpec(p); err != nil {
+		return WindowSpec{}, err
+	}
+	if err = p.expectPunct(")"); err != nil {
+		return WindowSpec{}, err
+	}
+	spec.Pos = p.posFrom(start)
+	return spec, nil
+}
+
+// getWindowSpec 解析窗口括号里的内容：[窗口名] [PARTITION BY 值列表] [ORDER BY 排序项列表] [窗口范围]
+func getWindowSpec(p *parser) (spec WindowSpec, err error) {
+	start := p.peek().Start
+	if !p.isAnyKeyword("PARTITION", "ORDER", "ROWS", "RANGE", "GROUPS") && !p.isPunct(")") {
+		if spec.Name, err = getName(p); err != nil {
+			return WindowSpec{}, err
+		}
+	}
+	if p.acceptKeyword("PARTITION") {
+		if err = p.expectKeyword("BY"); err != nil {
+			return WindowSpec{}, err
+		}
+		for {
+			val, err := getValue(p)
+			if err != nil {
+				return WindowSpec{}, err
+			}
+			spec.Partition = append(spec.Partition, val)
+			if !p.acceptPunct(",") {
+				break
+			}
+		}
+	}
+	if p.acceptKeyword("ORDER") {
+		if err = p.expectKeyword("BY"); err != nil {
+			return WindowSpec{}, err
+		}
+		if spec.Order, err = getOrderItems(p); err != nil {
+			return WindowSpec{}, err
+		}
+	}
+	if p.isAnyKeyword("ROWS", "RANGE", "GROUPS") {
+		if spec.Frame, err = getWindowFrame(p); err != nil {
+			return WindowSpec{}, err
+		}
+	}
+	spec.Pos = p.posFrom(start)
+	return spec, nil
+}
+
+// getOrderItems 解析逗号隔开的排序项：值 [ASC|DESC] [NULLS FIRST|LAST]
+func getOrderItems(p *parser) (items []OrderItem, err error) {
+	for {
+		var item OrderItem
+		start := p.peek().Start
+		if item.Expr, err = getValue(p); err != nil {
+			return nil, err
+		}
+		if p.isAnyKeyword("ASC", "DESC") {
+			item.Direction = strings.ToUpper(p.keyword().Value)
+		}
+		if p.acceptKeyword("NULLS") {
+			if !p.isAnyKeyword("FIRST", "LAST") {
+				return nil, p.fail(ErrMissingKeyword, "FIRST", "LAST")
+			}
+			item.Nulls = strings.ToUpper(p.keyword().Value)
+		}
+		item.Pos = p.posFrom(start)
+		items = append(items, item)
+		if !p.acceptPunct(",") {
+			return items, nil
+		}
+	}
+}
+
+// getWindowFrame 解析窗口范围：ROWS|RANGE|GROUPS 边界，或者ROWS|RANGE|GROUPS BETWEEN 边界 AND 边界
+func getWindowFrame(p *parser) (frame WindowFrame, err error) {
+	start := p.peek().Start
+	frame.Unit = strings.ToUpper(p.keyword().Value)
+	between := p.acceptKeyword("BETWEEN")
+	if frame.Start, err = getFrameBound(p); err != nil {
+		return WindowFrame{}, err
+	}
+	if between {
+		if err = p.expectKeyword("AND"); err != nil {
+			return WindowFrame{}, err
+		}
+		if frame.End, err = getFrameBound(p); err != nil {
+			return WindowFrame{}, err
+		}
+	}
+	frame.Pos = p.posFrom(start)
+	return frame, nil
+}
+
+// getFrameBound 解析窗口范围的边界
+func getFrameBound(p *parser) (bound FrameBound, err error) {
+	start := p.peek().Start
+	switch {
+	case p.acceptKeyword("UNBOUNDED", "PRECEDING"):
+		bound.Kind = "UNBOUNDED PRECEDING"
+	case p.acceptKeyword("UNBOUNDED", "FOLLOWING"):
+		bound.Kind = "UNBOUNDED FOLLOWING"
+	case p.acceptKeyword("CURRENT", "ROW"):
+		bound.Kind = "CURRENT ROW"
+	default:
+		if bound.Offset, err = getValue(p); err != nil {
+			return FrameBound{}, err
+		}
+		if !p.isAnyKeyword("PRECEDING", "FOLLOWING") {
+			return FrameBound{}, p.fail(ErrMissingKeyword, "PRECEDING", "FOLLOWING")
+		}
+		bound.Kind = strings.ToUpper(p.keyword().Value)
+	}
+	bound.Pos = p.posFrom(start)
+	return bound, nil
+}
+
+// getNamedWindows 解析查询的WINDOW子句，WINDOW关键词已被解析
+func getNamedWindows(p *parser) (windows []NamedWindow, err error) {
+	for {
+		var window NamedWindow
+		start := p.peek().Start
+		if window.Name, err = getName(p); err != nil {
+			return nil, err
+		}
+		if err = p.expectKeyword("AS"); err != nil {
+			return nil, err
+		}
+		if err = p.expectPunct("("); err != nil {
+			return nil, err
+		}
+		if window.Spec, err = getWindowSpec(p); err != nil {
+			return nil, err
+		}
+		if err = p.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		window.Pos = p.posFrom(start)
+		windows = append(windows, window)
+		if !p.acceptPunct(",") {
+			return windows, nil
+		}
+	}
+}
+
+// marshalOver 序列化分析函数的OVER部分，只引用命名窗口时不加括号
+func marshalOver(m *marshaler, spec WindowSpec) (retSQL string, err error) {
+	if spec.Name != "" && len(spec.Partition) == 0 && len(spec.Order) == 0 && spec.Frame.Unit == "" {
+		return m.kw("OVER") + " " + spec.Name, nil
+	}
+	retSQL, err = marshalWindowSpec(m, spec)
+	if err != nil {
+		return "", err
+	}
+	return m.kw("OVER") + " (" + retSQL + ")", nil
+}
+
+// marshalWindowSpec 序列化窗口括号里的内容
+func marshalWindowSpec(m *marshaler, spec WindowSpec) (retSQL string, err error) {
+	var parts []string
+	if spec.Name != "" {
+		parts = append(parts, spec.Name)
+	}
+	if len(spec.Partition) != 0 {
+		var values []string
+		for _, item := range spec.Partition {
+			val, err := marshalValue(m, item, true)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, val)
+		}
+		parts = append(parts, m.kw("PARTITION BY")+" "+strings.Join(values, ","))
+	}
+	if len(spec.Order) != 0 {
+		orderStr, err := marshalOrderItems(m, spec.Order)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, m.kw("ORDER BY")+" "+orderStr)
+	}
+	if spec.Frame.Unit != "" {
+		frameStr, err := marshalWindowFrame(m, spec.Frame)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, frameStr)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// marshalOrderItems 序列化逗号隔开的排序项
+func marshalOrderItems(m *marshaler, items []OrderItem) (retSQL string, err error) {
+	for _, item := range items {
+		val, err := marshalValue(m, item.Expr, true)
+		if err != nil {
+			return "", err
+		}
+		switch item.Direction {
+		case "":
+		case "ASC", "DESC":
+			val += " " + m.kw(item.Direction)
+		default:
+			return "", errors.New("不能识别的排序方向" + item.Direction)
+		}
+		switch item.Nulls {
+		case "":
+		case "FIRST", "LAST":
+			val += " " + m.kw("NULLS "+item.Nulls)
+		default:
+			return "", errors.New("NULLS只能是FIRST、LAST")
+		}
+		retSQL += val + ","
+	}
+	return strings.TrimRight(retSQL, ","), nil
+}
+
+// marshalWindowFrame 序列化窗口范围
+func marshalWindowFrame(m *marshaler, frame WindowFrame) (retSQL string, err error) {
+	if frame.Unit != "ROWS" && frame.Unit != "RANGE" && frame.Unit != "GROUPS" {
+		return "", errors.New("窗口范围只能是ROWS、RANGE、GROUPS")
+	}
+	startStr, err := marshalFrameBound(m, frame.Start)
+	if err != nil {
+		return "", err
+	}
+	if frame.End.Kind == "" {
+		return m.kw(frame.Unit) + " " + startStr, nil
+	}
+	endStr, err := marshalFrameBound(m, frame.End)
+	if err != nil {
+		return "", err
+	}
+	return m.kw(frame.Unit+" BETWEEN") + " " + startStr + " " + m.kw("AND") + " " + endStr, nil
+}
+
+// marshalFrameBound 序列化窗口范围的边界
+func marshalFrameBound(m *marshaler, bound FrameBound) (retSQL string, err error) {
+	switch bound.Kind {
+	case "UNBOUNDED PRECEDING", "UNBOUNDED FOLLOWING", "CURRENT ROW":
+		return m.kw(bound.Kind), nil
+	case "PRECEDING", "FOLLOWING":
+		offset, err := marshalValue(m, bound.Offset, true)
+		if err != nil {
+			return "", err
+		}
+		return offset + " " + m.kw(bound.Kind), nil
+	}
+	return "", errors.New("不能识别的窗口边界")
+}
+
+// marshalNamedWindows 序列化查询的WINDOW子句
+func marshalNamedWindows(m *marshaler, windows []NamedWindow) (retSQL string, err error) {
+	for _, item := range windows {
+		if item.Name == "" {
+			return "", errors.New("命名窗口的名称不能为空")
+		}
+		spec, err := marshalWindowSpec(m, item.Spec)
+		if err != nil {
+			return "", err
+		}
+		retSQL += item.Name + " " + m.kw("AS") + " (" + spec + "),"
+	}
+	return m.kw("WINDOW") + " " + strings.TrimRight(retSQL, ","), nil
+}
+
+func getParamsByWindowSpec(spec WindowSpec) (pars []Params) {
+	for _, item := range spec.Partition {
+		pars = append(pars, getParamsBySelectValue(item)...)
+	}
+	for _, item := range spec.Order {
+		pars = append(pars, getParamsBySelectValue(item.Expr)...)
+	}
+	pars = append(pars, getParamsBySelectValue(spec.Frame.Start.Offset)...)
+	pars = append(pars, getParamsBySelectValue(spec.Frame.End.Offset)...)
+	return pars
+}
+
+// deleteParamsByWindowSpec 和分组、排序一样，分区值、排序项中有参数被删除时只删除该项，边界中有参数被删除时删除整个窗口范围
+func deleteParamsByWindowSpec(spec WindowSpec, pars []Params) WindowSpec {
+	var partition []Value
+	for _, item := range spec.Partition {
+		item = deleteParamsBySelectValue(item, pars)
+		if item.Value != nil {
+			partition = append(partition, item)
+		}
+	}
+	spec.Partition = partition
+	var order []OrderItem
+	for _, item := range spec.Order {
+		item.Expr = deleteParamsBySelectValue(item.Expr, pars)
+		if item.Expr.Value != nil {
+			order = append(order, item)
+		}
+	}
+	spec.Order = order
+	for _, bound := range []FrameBound{spec.Frame.Start, spec.Frame.End} {
+		if bound.Offset.Value != nil && deleteParamsBySelectValue(bound.Offset, pars).Value == nil {
+			spec.Frame = WindowFrame{}
+		}
+	}
+	return spec
+}
+
+func expandParamsByWindowSpec(spec WindowSpec, params Params, count int) WindowSpec {
+	partition := make([]Value, len(spec.Partition))
+	for i, item := range spec.Partition {
+		partition[i] = item
+		if val := expandParamsBySelectValue(item, params, count); val.Value != nil {
+			partition[i] = val
+		}
+	}
+	spec.Partition = partition
+	order := make([]OrderItem, len(spec.Order))
+	for i, item := range spec.Order {
+		order[i] = item
+		if val := expandParamsBySelectValue(item.Expr, params, count); val.Value != nil {
+			order[i].Expr = val
+		}
+	}
+	spec.Order = order
+	return spec
+}
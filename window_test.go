@@ -0,0 +1,182 @@
+package sqlParser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// windowOf 解析只有一个单查询的SQL，返回第i个查询字段上分析函数的窗口
+func windowOf(t *testing.T, sql string, i int) WindowSpec {
+	t.Helper()
+	stmt, err := Unmarshal(sql)
+	if err != nil {
+		t.Fatalf("Unmarshal(%q): %v", sql, err)
+	}
+	fn, ok := stmt.Ast.(Select).Body.(SelectItem).Field[i].Field.Value.(Function)
+	if !ok || fn.Over == nil {
+		t.Fatalf("%q: field %d is not an analytic function", sql, i)
+	}
+	return *fn.Over
+}
+
+func TestOverForms(t *testing.T) {
+	tests := []struct {
+		sql  string
+		spec WindowSpec
+		want string
+	}{
+		//括号里只有窗口名时等价于OVER W，输出不带括号
+		{"select sum(x) over w from t window w as (partition by a)", WindowSpec{Name: "w"}, "SELECT sum(x) OVER w FROM t WINDOW w AS (PARTITION BY a)"},
+		{"select sum(x) over (w) from t window w as (partition by a)", WindowSpec{Name: "w"}, "SELECT sum(x) OVER w FROM t WINDOW w AS (PARTITION BY a)"},
+		{"select count(*) over () from t", WindowSpec{}, "SELECT count(*) OVER () FROM t"},
+		{
+			"select rank() over (w order by a desc nulls first) from t window w as (partition by b)",
+			WindowSpec{Name: "w", Order: []OrderItem{{Expr: Value{Value: ColumnRef{Column: "a"}}, Direction: "DESC", Nulls: "FIRST"}}},
+			"SELECT rank() OVER (w ORDER BY a DESC NULLS FIRST) FROM t WINDOW w AS (PARTITION BY b)",
+		},
+	}
+	for _, tt := range tests {
+		spec := windowOf(t, tt.sql, 0)
+		if spec.Name != tt.spec.Name || len(spec.Partition) != 0 || len(spec.Order) != len(tt.spec.Order) {
+			t.Errorf("%q: got %+v", tt.sql, spec)
+		}
+		for i, item := range spec.Order {
+			want := tt.spec.Order[i]
+			if item.Direction != want.Direction || item.Nulls != want.Nulls || item.Expr.Value.(ColumnRef).Column != want.Expr.Value.(ColumnRef).Column {
+				t.Errorf("%q: order %d = %+v", tt.sql, i, item)
+			}
+		}
+		if got := remarshal(t, tt.sql); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestWindowFrameBounds(t *testing.T) {
+	tests := []struct {
+		frame      string
+		unit       string
+		start, end string
+	}{
+		{"rows unbounded preceding", "ROWS", "UNBOUNDED PRECEDING", ""},
+		{"range 3 preceding", "RANGE", "PRECEDING", ""},
+		{"rows between unbounded preceding and current row", "ROWS", "UNBOUNDED PRECEDING", "CURRENT ROW"},
+		{"range between current row and unbounded following", "RANGE", "CURRENT ROW", "UNBOUNDED FOLLOWING"},
+		{"groups between 1 preceding and 2 following", "GROUPS", "PRECEDING", "FOLLOWING"},
+		{"rows between :a preceding and :b + 1 following", "ROWS", "PRECEDING", "FOLLOWING"},
+	}
+	for _, tt := range tests {
+		sql := "select sum(x) over (order by d " + tt.frame + ") from t"
+		frame := windowOf(t, sql, 0).Frame
+		if frame.Unit != tt.unit || frame.Start.Kind != tt.start || frame.End.Kind != tt.end {
+			t.Errorf("%q: got %s %q/%q", tt.frame, frame.Unit, frame.Start.Kind, frame.End.Kind)
+		}
+		//只有PRECEDING、FOLLOWING带偏移量
+		for _, bound := range []FrameBound{frame.Start, frame.End} {
+			hasOffset := bound.Offset.Value != nil
+			if hasOffset != (bound.Kind == "PRECEDING" || bound.Kind == "FOLLOWING") {
+				t.Errorf("%q: bound %q offset %#v", tt.frame, bound.Kind, bound.Offset.Value)
+			}
+		}
+	}
+	stmt, err := Unmarshal("select sum(x) over (order by d rows between :a preceding and :b + 1 following) from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := MarshalWithOptions(stmt, MarshalOptions{KeywordCase: KeywordLower})
+	if want := "select sum(x) over (order by d rows between :a preceding and :b+1 following) from t"; err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestNamedWindowClause(t *testing.T) {
+	sql := "select sum(x) over (w rows unbounded preceding), avg(x) over w2 from t window w as (partition by a), w2 as (w order by b)"
+	stmt, err := Unmarshal(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows := stmt.Ast.(Select).Body.(SelectItem).Window
+	if len(windows) != 2 || windows[0].Name != "w" || windows[1].Name != "w2" {
+		t.Fatalf("windows = %+v", windows)
+	}
+	//命名窗口可以引用前面的命名窗口
+	if windows[1].Spec.Name != "w" || len(windows[1].Spec.Order) != 1 || len(windows[0].Spec.Partition) != 1 {
+		t.Errorf("specs = %+v, %+v", windows[0].Spec, windows[1].Spec)
+	}
+	want := "SELECT sum(x) OVER (w ROWS UNBOUNDED PRECEDING),avg(x) OVER w2 FROM t WINDOW w AS (PARTITION BY a),w2 AS (w ORDER BY b)"
+	if got, err := Marshal(stmt); err != nil || got != want {
+		t.Errorf("got %q, %v, want %q", got, err, want)
+	}
+	//ORDER BY里的分析函数
+	if got := remarshal(t, "select a from t order by row_number() over (partition by b order by c)"); got != "SELECT a FROM t ORDER BY row_number() OVER (PARTITION BY b ORDER BY c)" {
+		t.Errorf("order by: got %q", got)
+	}
+}
+
+func TestWindowDeleteParams(t *testing.T) {
+	sql := "select sum(x) over (partition by :a, b order by :b, c rows between :c preceding and current row) from t window w as (order by :d)"
+	stmt, err := Unmarshal(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paramNames(stmt.Params()); !reflect.DeepEqual(got, []string{":a", ":b", ":c", ":d"}) {
+		t.Errorf("Params() = %v", got)
+	}
+	//分区、排序只去掉含参数的项，范围边界含参数时去掉整个范围
+	tests := map[string]string{
+		":a": "SELECT sum(x) OVER (PARTITION BY b ORDER BY :b,c ROWS BETWEEN :c PRECEDING AND CURRENT ROW) FROM t WINDOW w AS (ORDER BY :d)",
+		":b": "SELECT sum(x) OVER (PARTITION BY :a,b ORDER BY c ROWS BETWEEN :c PRECEDING AND CURRENT ROW) FROM t WINDOW w AS (ORDER BY :d)",
+		":c": "SELECT sum(x) OVER (PARTITION BY :a,b ORDER BY :b,c) FROM t WINDOW w AS (ORDER BY :d)",
+		":d": "SELECT sum(x) OVER (PARTITION BY :a,b ORDER BY :b,c ROWS BETWEEN :c PRECEDING AND CURRENT ROW) FROM t WINDOW w AS ()",
+	}
+	for name, want := range tests {
+		stmt, _ := Unmarshal(sql)
+		stmt.DeleteParams([]Params{{Name: name}})
+		if got, err := Marshal(stmt); err != nil || got != want {
+			t.Errorf("delete %s: got %q, %v, want %q", name, got, err, want)
+		}
+	}
+	//窗口里子查询的IN列表可以扩展
+	testParams(t, []paramsCase{{
+		"select sum(x) over (order by (select max(y) from u where z in (:p))) from t",
+		[]string{":p"},
+		"", "",
+		":p", "SELECT sum(x) OVER (ORDER BY (SELECT max(y) FROM u WHERE z IN(:p0,:p1,:p2))) FROM t",
+	}})
+}
+
+func TestWindowSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected []string
+		column   int
+	}{
+		{"select sum(x) over (rows between 1 preceding) from t", []string{"AND"}, 45},
+		{"select sum(x) over (order by a nulls) from t", []string{"FIRST", "LAST"}, 37},
+		{"select sum(x) over (rows 1) from t", []string{"PRECEDING", "FOLLOWING"}, 27},
+		{"select sum(x) over (partition a) from t", []string{"BY"}, 31},
+		{"select a from t window w (partition by a)", []string{"AS"}, 26},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal(tt.sql)
+		perr, ok := err.(*ParseError)
+		if !ok || perr.Code != ErrMissingKeyword || !reflect.DeepEqual(perr.Expected, tt.expected) || perr.Pos.Column != tt.column {
+			t.Errorf("%q: got %v, want %q missing at column %d", tt.sql, err, tt.expected, tt.column)
+		}
+	}
+}
+
+func TestMarshalWindowFrameErrors(t *testing.T) {
+	tests := map[string]WindowFrame{
+		"unknown unit":        {Unit: "ROW", Start: FrameBound{Kind: "CURRENT ROW"}},
+		"unknown bound":       {Unit: "ROWS", Start: FrameBound{Kind: "BEFORE"}},
+		"empty offset column": {Unit: "ROWS", Start: FrameBound{Kind: "CURRENT ROW"}, End: FrameBound{Kind: "PRECEDING", Offset: Value{Value: ColumnRef{}}}},
+	}
+	for name, frame := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := marshalWindowFrame(&marshaler{}, frame); err == nil {
+				t.Errorf("marshalWindowFrame = %q, should fail", got)
+			}
+		})
+	}
+}